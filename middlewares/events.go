@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"github.com/lunny/tango"
+	"github.com/tango-contrib/events"
+)
+
+// Events calls Before and After of actions like events.Events, the action
+// is skipped when Before has written a response such as a redirect
+func Events() tango.HandlerFunc {
+	return func(ctx *tango.Context) {
+		action := ctx.Action()
+		if b, ok := action.(events.Before); ok {
+			b.Before()
+		}
+
+		if !ctx.Written() {
+			ctx.Next()
+		}
+
+		if a, ok := action.(events.After); ok {
+			a.After()
+		}
+	}
+}
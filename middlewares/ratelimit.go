@@ -0,0 +1,134 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Unknwon/i18n"
+	"github.com/lunny/log"
+	"github.com/lunny/tango"
+	"github.com/tango-contrib/renders"
+	"github.com/tango-contrib/session"
+
+	"github.com/go-tango/wego/modules/auth"
+	"github.com/go-tango/wego/modules/ratelimit"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/setting"
+)
+
+// RateLimit limits requests with the [ratelimit.NAME] policies of config,
+// the user is the session user, or the token of Authorization header
+func RateLimit(sessions *session.Sessions) tango.HandlerFunc {
+	policies := ratelimit.FromConfig(setting.Cfg)
+	limiter := ratelimit.NewLimiter(setting.Cache)
+
+	return func(ctx *tango.Context) {
+		if !setting.RateLimitEnabled {
+			ctx.Next()
+			return
+		}
+
+		req := ctx.Req()
+		var shown *ratelimit.Result
+		for _, p := range policies {
+			if !p.Match(req.Method, req.URL.Path) {
+				continue
+			}
+
+			res := limiter.Take(p, rateLimitKey(ctx, sessions, p.Key))
+			if !res.Allowed {
+				setRateLimitHeaders(ctx, &res)
+				log.Info("ratelimit:", p.Name, "limits", utils.IP(req), req.Method, req.URL.Path)
+				rateLimited(ctx, &res)
+				return
+			}
+			// headers show the policy closest to its limit
+			if shown == nil || res.Remaining < shown.Remaining {
+				r := res
+				shown = &r
+			}
+		}
+
+		if shown != nil {
+			setRateLimitHeaders(ctx, shown)
+		}
+		ctx.Next()
+	}
+}
+
+// rateLimitKey returns who the request is counted for, a token is the
+// most specific, then the logined user, guests are counted by ip
+func rateLimitKey(ctx *tango.Context, sessions *session.Sessions, key string) string {
+	req := ctx.Req()
+	if key == ratelimit.KEY_IP {
+		return "ip:" + utils.IP(req)
+	}
+
+	if raw := auth.BearerToken(req); raw != "" {
+		sum := sha256.Sum256([]byte(raw))
+		return "token:" + hex.EncodeToString(sum[:8])
+	}
+	if id := auth.GetUserIdFromSession(sessions.Session(req, ctx.ResponseWriter)); id > 0 {
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+	return "ip:" + utils.IP(req)
+}
+
+func setRateLimitHeaders(ctx *tango.Context, res *ratelimit.Result) {
+	h := ctx.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(res.Reset).Unix(), 10))
+}
+
+// rateLimited writes 429, as json for ajax and api requests
+func rateLimited(ctx *tango.Context, res *ratelimit.Result) {
+	retry := int(math.Ceil(res.RetryAfter.Seconds()))
+	ctx.Header().Set("Retry-After", strconv.Itoa(retry))
+
+	req := ctx.Req()
+	if req.Header.Get("X-Requested-With") == "XMLHttpRequest" || auth.BearerToken(req) != "" {
+		data, _ := json.Marshal(map[string]interface{}{
+			"success": false,
+			"error": map[string]string{
+				"code":    "rate_limited",
+				"message": fmt.Sprintf("too many requests, retry after %d seconds", retry),
+			},
+		})
+		ctx.Header().Set("Content-Type", "application/json; charset=utf-8")
+		ctx.WriteHeader(http.StatusTooManyRequests)
+		ctx.Write(data)
+		return
+	}
+
+	// the page is shown before routers set the language
+	lang := auth.GetCookie(req, "lang")
+	if !i18n.IsExist(lang) {
+		lang = ""
+		if al := req.Header.Get("Accept-Language"); len(al) > 4 && i18n.IsExist(al[:5]) {
+			lang = al[:5]
+		}
+	}
+	if lang == "" {
+		lang = "en-US"
+	}
+
+	data, err := Renders.RenderBytes("base/429.html", renders.T{
+		"Lang":       lang,
+		"RetryAfter": retry,
+	})
+	if err != nil {
+		log.Error("ratelimit: ", err)
+		ctx.Abort(http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+		return
+	}
+	ctx.Header().Set("Content-Type", "text/html; charset=utf-8")
+	ctx.WriteHeader(http.StatusTooManyRequests)
+	ctx.Write(data)
+}
@@ -0,0 +1,197 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-xorm/xorm"
+
+	"github.com/go-tango/wego/modules/utils"
+)
+
+// common actions of audit logs, handlers may use others for specific
+// operations like "moderator.add" or "toggle-best"
+const (
+	AUDIT_CREATE = "create"
+	AUDIT_UPDATE = "update"
+	AUDIT_DELETE = "delete"
+)
+
+// values of these fields never go to audit logs
+var auditSecretFields = map[string]bool{
+	"Password": true,
+	"Rands":    true,
+}
+
+const auditSecretValue = "******"
+
+// AuditChange is one changed field of an audited object
+type AuditChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// AuditLog records who did an administrative or moderation action,
+// logs are only inserted, never updated or deleted
+type AuditLog struct {
+	Id          int64
+	ActorId     int64     `xorm:"index"`
+	Action      string    `xorm:"varchar(30) index"`
+	TargetModel string    `xorm:"varchar(30) index"`
+	TargetId    int64     `xorm:"index"`
+	Changes     string    `xorm:"text"`
+	Ip          string    `xorm:"varchar(45)"`
+	Created     time.Time `xorm:"created index"`
+}
+
+func (m *AuditLog) Actor() *User {
+	var user User
+	if err := GetById(m.ActorId, &user); err != nil {
+		return nil
+	}
+	return &user
+}
+
+// ChangeList decodes the changed fields of the log
+func (m *AuditLog) ChangeList() []AuditChange {
+	var changes []AuditChange
+	if m.Changes != "" {
+		json.Unmarshal([]byte(m.Changes), &changes)
+	}
+	return changes
+}
+
+// CSVRows returns the log as csv rows for actor, one row for each changed
+// field
+func (m *AuditLog) CSVRows(actor string) [][]string {
+	row := []string{
+		utils.ToStr(m.Id),
+		m.Created.Format(time.RFC3339),
+		csvCell(actor),
+		csvCell(m.Action),
+		csvCell(m.TargetModel),
+		utils.ToStr(m.TargetId),
+	}
+
+	changes := m.ChangeList()
+	if len(changes) == 0 {
+		return [][]string{append(row, "", "", "", m.Ip)}
+	}
+	rows := make([][]string, 0, len(changes))
+	for _, c := range changes {
+		cells := append([]string{}, row...)
+		rows = append(rows, append(cells, csvCell(c.Field), csvCell(c.Before), csvCell(c.After), m.Ip))
+	}
+	return rows
+}
+
+// csvCell quotes value with a leading single quote if it starts like a
+// formula, spreadsheet programs would run it when the csv is opened
+func csvCell(value string) string {
+	if value != "" && strings.IndexByte("=+-@\t\r", value[0]) >= 0 {
+		return "'" + value
+	}
+	return value
+}
+
+// AuditDiff returns before and after values of fields of two copies of
+// the same model, fields not in the model are skipped
+func AuditDiff(before, after interface{}, fields []string) []AuditChange {
+	b := reflect.Indirect(reflect.ValueOf(before))
+	a := reflect.Indirect(reflect.ValueOf(after))
+
+	changes := make([]AuditChange, 0, len(fields))
+	for _, name := range fields {
+		fb := b.FieldByName(name)
+		fa := a.FieldByName(name)
+		if !fb.IsValid() || !fa.IsValid() {
+			continue
+		}
+
+		change := AuditChange{Field: name}
+		if auditSecretFields[name] {
+			change.Before = auditSecretValue
+			change.After = auditSecretValue
+		} else {
+			change.Before = utils.ToStr(fb.Interface())
+			change.After = utils.ToStr(fa.Interface())
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// InsertAuditLog appends a log with its changed fields
+func InsertAuditLog(m *AuditLog, changes []AuditChange) error {
+	if len(changes) > 0 {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		m.Changes = string(data)
+	}
+	_, err := orm.Insert(m)
+	return err
+}
+
+// AuditLogFilter limits listed logs, zero values match all
+type AuditLogFilter struct {
+	ActorId     int64
+	Action      string
+	TargetModel string
+	TargetId    int64
+}
+
+func (f *AuditLogFilter) session() *xorm.Session {
+	sess := orm.NewSession()
+	if f.ActorId > 0 {
+		sess = sess.And("actor_id = ?", f.ActorId)
+	}
+	if f.Action != "" {
+		sess = sess.And("action = ?", f.Action)
+	}
+	if f.TargetModel != "" {
+		sess = sess.And("target_model = ?", f.TargetModel)
+	}
+	if f.TargetId > 0 {
+		sess = sess.And("target_id = ?", f.TargetId)
+	}
+	return sess
+}
+
+// FindAuditLogs lists logs matching filter, newest first, limit 0
+// lists all of them
+func FindAuditLogs(filter AuditLogFilter, limit, offset int) ([]*AuditLog, error) {
+	var logs = make([]*AuditLog, 0)
+	sess := filter.session()
+	defer sess.Close()
+	sess = sess.Desc("id")
+	if limit > 0 {
+		sess = sess.Limit(limit, offset)
+	}
+	err := sess.Find(&logs)
+	return logs, err
+}
+
+func CountAuditLogs(filter AuditLogFilter) (int64, error) {
+	sess := filter.session()
+	defer sess.Close()
+	return sess.Count(new(AuditLog))
+}
@@ -0,0 +1,56 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLogCSVRows(t *testing.T) {
+	m := AuditLog{
+		Id:          1,
+		Action:      AUDIT_UPDATE,
+		TargetModel: "user",
+		TargetId:    2,
+		Ip:          "127.0.0.1",
+		Changes:     `[{"field":"NickName","before":"bob","after":"=HYPERLINK(\"http://a.com\")"},{"field":"Url","before":"+1","after":"@x"}]`,
+		Created:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	rows := m.CSVRows("-admin")
+	want := []string{
+		"1|2026-01-02T03:04:05Z|'-admin|update|user|2|NickName|bob|'=HYPERLINK(\"http://a.com\")|127.0.0.1",
+		"1|2026-01-02T03:04:05Z|'-admin|update|user|2|Url|'+1|'@x|127.0.0.1",
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, row := range rows {
+		if got := strings.Join(row, "|"); got != want[i] {
+			t.Errorf("row %d = %s, want %s", i, got, want[i])
+		}
+	}
+
+	for _, v := range []string{"\tx", "\rx"} {
+		if got := csvCell(v); got != "'"+v {
+			t.Errorf("csvCell(%q) = %q", v, got)
+		}
+	}
+	if got := csvCell("a=b"); got != "a=b" {
+		t.Errorf("csvCell(a=b) = %q", got)
+	}
+}
@@ -23,6 +23,10 @@ func (m *Category) Link() string {
 	return fmt.Sprintf("%scategory/%s", setting.AppUrl, m.Slug)
 }
 
+func (m *Category) FeedLink() string {
+	return fmt.Sprintf("%scategory/%s/feed", setting.AppUrl, m.Slug)
+}
+
 func GetCategoryBySlug(slug string) (*Category, error) {
 	var cate = Category{Slug: slug}
 	err := GetByExample(&cate)
@@ -1,22 +1,104 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/setting"
 )
 
+const (
+	COMMENT_STATUS_NORMAL = iota
+	COMMENT_STATUS_DELETED
+	// waiting for review by moderators, shown to the author and admins only
+	COMMENT_STATUS_PENDING
+	// hidden by moderators
+	COMMENT_STATUS_HIDDEN
+)
+
 // commnet content for post
+// ParentId: the comment replied to, 0 for top level comments
+// Edited: time of the last edit, zero if never edited
 type Comment struct {
 	Id           int64
 	UserId       int64  `xorm:"index"`
 	PostId       int64  `xorm:"index"`
+	ParentId     int64  `xorm:"index"`
 	Message      string `xorm:"text"`
 	MessageCache string `xorm:"text"`
 	Floor        int
-	Status       int       `xorm:"index"`
-	Created      time.Time `xorm:"created"`
+	Status       int        `xorm:"index"`
+	Edited       time.Time  `xorm:"index"`
+	Created      time.Time  `xorm:"created"`
+	Children     []*Comment `xorm:"-"`
+}
+
+func (c *Comment) IsDeleted() bool {
+	return c.Status == COMMENT_STATUS_DELETED
+}
+
+func (c *Comment) IsPending() bool {
+	return c.Status == COMMENT_STATUS_PENDING
+}
+
+func (c *Comment) IsHidden() bool {
+	return c.Status == COMMENT_STATUS_HIDDEN
+}
+
+// IsVisible reports whether the comment is listed in its thread, deleted
+// comments are listed as placeholders
+func (c *Comment) IsVisible() bool {
+	return c.Status == COMMENT_STATUS_NORMAL || c.Status == COMMENT_STATUS_DELETED
+}
+
+// CanViewBy reports whether user may see the comment, authors and
+// moderators can see comments that are not visible
+func (c *Comment) CanViewBy(user *User) bool {
+	if c.IsVisible() {
+		return true
+	}
+	return user != nil && user.Id != 0 && (user.Id == c.UserId || c.CanModerateBy(user))
+}
+
+// CanModerateBy reports whether user moderates the category of the comment
+func (c *Comment) CanModerateBy(user *User) bool {
+	if user == nil || !user.CanModerate() {
+		return false
+	}
+	if user.Can(PERM_EDIT_COMMENT, 0) {
+		return true
+	}
+	post := c.Post()
+	return post != nil && user.Can(PERM_EDIT_COMMENT, post.CategoryId)
+}
+
+func (c *Comment) IsEdited() bool {
+	return !c.Edited.IsZero()
+}
+
+// CanEditBy reports whether user may edit the comment, moderators always
+// can, authors only within setting.CommentEditMinutes after posting unless
+// they are trusted.
+func (c *Comment) CanEditBy(user *User) bool {
+	if user == nil || user.Id == 0 || c.IsDeleted() {
+		return false
+	}
+	if c.UserId != user.Id {
+		return c.CanModerateBy(user)
+	}
+	if setting.CommentEditMinutes <= 0 || user.IsTrusted() {
+		return true
+	}
+	return time.Since(c.Created) < time.Duration(setting.CommentEditMinutes)*time.Minute
+}
+
+// CanDeleteBy reports whether user may delete the comment
+func (c *Comment) CanDeleteBy(user *User) bool {
+	if user == nil || user.Id == 0 || c.IsDeleted() {
+		return false
+	}
+	return c.UserId == user.Id || c.CanModerateBy(user)
 }
 
 func (m *Comment) GetMessageCache() string {
@@ -49,29 +131,161 @@ func (c *Comment) Post() *Post {
 	return &post
 }
 
+func (c *Comment) Parent() *Comment {
+	if c.ParentId == 0 {
+		return nil
+	}
+	var parent Comment
+	if err := GetById(c.ParentId, &parent); err != nil {
+		return nil
+	}
+	return &parent
+}
+
+func GetCommentById(id int64) (*Comment, error) {
+	var comment Comment
+	if err := GetById(id, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
 func InsertComment(comment *Comment) error {
 	_, err := orm.Insert(comment)
 	return err
 }
 
+// RecentCommentsByUserId returns the newest visible comments of a user,
+// deleted, pending and hidden comments are left out
 func RecentCommentsByUserId(userId int64, limit int) ([]Comment, error) {
+	return FindCommentsByUserId(userId, limit, 0)
+}
+
+// FindCommentsByUserId returns visible comments of a user on visible posts,
+// newest first
+func FindCommentsByUserId(userId int64, limit, start int) ([]Comment, error) {
 	var comments = make([]Comment, 0)
-	err := orm.Where("user_id = ?", userId).Limit(limit).Find(&comments)
+	err := orm.Where("user_id = ? AND status = ?", userId, COMMENT_STATUS_NORMAL).
+		And(visiblePostCond(), POST_STATUS_NORMAL).
+		Desc("id").Limit(limit, start).Find(&comments)
 	return comments, err
 }
 
+// visiblePostCond limits comments to visible posts, it takes the normal
+// status of posts as argument
+func visiblePostCond() string {
+	return fmt.Sprintf("post_id IN (SELECT id FROM %s WHERE status = ?)", orm.Quote(orm.TableInfo(new(Post)).Name))
+}
+
+// GetCommentsByPostId returns visible comments of a post, pending and
+// hidden comments are left out until reviewed
 func GetCommentsByPostId(comments *[]*Comment, postId int64) error {
-	return orm.Find(comments, &Comment{PostId: postId})
+	return orm.In("status", COMMENT_STATUS_NORMAL, COMMENT_STATUS_DELETED).
+		Asc("id").Find(comments, &Comment{PostId: postId})
+}
+
+// FindCommentsAfterId returns visible comments of a post with id greater than afterId, oldest first
+func FindCommentsAfterId(postId, afterId int64, limit int) ([]Comment, error) {
+	var comments = make([]Comment, 0)
+	err := orm.Where("post_id = ? AND id > ?", postId, afterId).
+		In("status", COMMENT_STATUS_NORMAL, COMMENT_STATUS_DELETED).
+		Asc("id").Limit(limit).Find(&comments)
+	return comments, err
+}
+
+// FindPendingComments returns pending comments of a post by user, they are
+// shown to their author until reviewed
+func FindPendingComments(postId, userId int64) ([]*Comment, error) {
+	var comments = make([]*Comment, 0)
+	err := orm.Where("post_id = ? AND user_id = ? AND status = ?", postId, userId, COMMENT_STATUS_PENDING).
+		Asc("id").Find(&comments)
+	return comments, err
+}
+
+// CommentThreads arranges comments into threads by ParentId, returns the top
+// level comments. Comments whose parent is missing are kept at top level.
+func CommentThreads(comments []*Comment) []*Comment {
+	byId := make(map[int64]*Comment, len(comments))
+	for _, c := range comments {
+		c.Children = nil
+		byId[c.Id] = c
+	}
+
+	roots := make([]*Comment, 0, len(comments))
+	for _, c := range comments {
+		if parent, ok := byId[c.ParentId]; ok && c.ParentId != c.Id {
+			parent.Children = append(parent.Children, c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	return roots
+}
+
+const (
+	COMMENT_HISTORY_EDIT = iota + 1
+	COMMENT_HISTORY_DELETE
+)
+
+// CommentHistory keeps the message of a comment before each edit or delete
+// UserId: who made the change
+type CommentHistory struct {
+	Id        int64
+	CommentId int64  `xorm:"index"`
+	UserId    int64  `xorm:"index"`
+	Message   string `xorm:"text"`
+	Action    int
+	Created   time.Time `xorm:"created"`
+}
+
+func (h *CommentHistory) IsDelete() bool {
+	return h.Action == COMMENT_HISTORY_DELETE
+}
+
+func (h *CommentHistory) User() *User {
+	var user User
+	has, err := orm.Id(h.UserId).Get(&user)
+	if err != nil || !has {
+		return nil
+	}
+	return &user
+}
+
+func InsertCommentHistory(history *CommentHistory) error {
+	_, err := orm.Insert(history)
+	return err
+}
+
+// FindCommentHistories returns the history of a comment, oldest first
+func FindCommentHistories(commentId int64) ([]CommentHistory, error) {
+	var histories = make([]CommentHistory, 0)
+	err := orm.Where("comment_id = ?", commentId).Asc("id").Find(&histories)
+	return histories, err
 }
 
 func CountCommentsByPostId(postId int64) (int64, error) {
 	return orm.Count(&Comment{PostId: postId})
 }
 
+// CountCommentsByUserId counts the comments listed by FindCommentsByUserId
 func CountCommentsByUserId(userId int64) (int64, error) {
-	return orm.Count(&Comment{UserId: userId})
+	return orm.Where("status = ?", COMMENT_STATUS_NORMAL).And(visiblePostCond(), POST_STATUS_NORMAL).
+		Count(&Comment{UserId: userId})
 }
 
 func CountCommentsLTEId(id int64) (int64, error) {
 	return orm.Where("id <= ?", id).Count(new(Comment))
 }
+
+// CountCommentsByUserSince counts comments of user created after since
+func CountCommentsByUserSince(userId int64, since time.Time) (int64, error) {
+	return orm.Where("user_id = ? AND created > ?", userId, since).Count(new(Comment))
+}
+
+// HasCommentMessageSince reports whether user posted a comment other than
+// exceptId with the same message after since
+func HasCommentMessageSince(userId, exceptId int64, message string, since time.Time) (bool, error) {
+	cnt, err := orm.Where("user_id = ? AND created > ? AND message = ? AND id <> ?", userId, since, message, exceptId).
+		Count(new(Comment))
+	return cnt > 0, err
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+)
+
+// status of data export
+const (
+	EXPORT_PENDING = iota
+	EXPORT_DONE
+	EXPORT_FAILED
+)
+
+// zip file of the personal data of a user, built in background
+type DataExport struct {
+	Id       int64
+	UserId   int64  `xorm:"index"`
+	FileName string `xorm:"varchar(64)"`
+	Size     int64
+	Status   int       `xorm:"index"`
+	Created  time.Time `xorm:"created index"`
+	Finished time.Time
+}
+
+func (m *DataExport) IsPending() bool {
+	return m.Status == EXPORT_PENDING
+}
+
+func (m *DataExport) IsDone() bool {
+	return m.Status == EXPORT_DONE
+}
+
+func (m *DataExport) IsFailed() bool {
+	return m.Status == EXPORT_FAILED
+}
+
+func GetDataExport(userId, id int64) (*DataExport, error) {
+	var exp = DataExport{Id: id, UserId: userId}
+	has, err := orm.Get(&exp)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrNotExist
+	}
+	return &exp, nil
+}
+
+func FindDataExports(userId int64) ([]DataExport, error) {
+	var exports = make([]DataExport, 0)
+	err := orm.Desc("id").Find(&exports, &DataExport{UserId: userId})
+	return exports, err
+}
+
+// FindDataExportsBefore returns exports created before t, of userId when it is not 0
+func FindDataExportsBefore(userId int64, t time.Time) ([]DataExport, error) {
+	var exports = make([]DataExport, 0)
+	err := orm.Where("created < ?", t).Find(&exports, &DataExport{UserId: userId})
+	return exports, err
+}
+
+func HasPendingDataExport(userId int64) (bool, error) {
+	return orm.Where("status = ?", EXPORT_PENDING).Get(&DataExport{UserId: userId})
+}
+
+// FinishDataExport saves the status, size and finish time
+func FinishDataExport(exp *DataExport) error {
+	exp.Finished = time.Now()
+	_, err := orm.Id(exp.Id).Cols("status", "size", "finished").Update(exp)
+	return err
+}
+
+// FailPendingDataExports marks exports interrupted by a restart failed
+func FailPendingDataExports() error {
+	_, err := orm.Where("status = ?", EXPORT_PENDING).Cols("status").Update(&DataExport{Status: EXPORT_FAILED})
+	return err
+}
+
+func DeleteDataExport(id int64) error {
+	_, err := orm.Id(id).Delete(new(DataExport))
+	return err
+}
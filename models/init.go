@@ -35,10 +35,17 @@ func Init(isProMode bool) {
 
 	err = orm.Sync2(new(Setting), new(Category), new(Post), new(Image),
 		new(User), new(FavoritePost), new(Follow), new(Topic), new(FollowTopic),
-		new(Page), new(Notification), new(Comment), new(Bulletin))
+		new(Page), new(Notification), new(Comment), new(Bulletin), new(AccessToken),
+		new(CommentHistory), new(PostRevision), new(MailPreference), new(MailQueue), new(Report),
+		new(CategoryModerator), new(AuditLog), new(TwoFactor), new(RecoveryCode),
+		new(UserSession), new(DataExport), new(Invite))
 	if err != nil {
 		panic(err)
 	}
 
+	if err := unlockRepliedPosts(); err != nil {
+		panic(err)
+	}
+
 	social.SetORM(orm)
 }
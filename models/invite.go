@@ -0,0 +1,109 @@
+package models
+
+import (
+	"time"
+)
+
+// invite code for registration in invite mode
+// UserId: the admin or trusted member who created the code
+// MaxUses: number of registrations allowed, 0 is no limit
+// Expires: zero time never expires
+type Invite struct {
+	Id      int64
+	Code    string `xorm:"varchar(32) unique"`
+	UserId  int64  `xorm:"index"`
+	MaxUses int
+	Uses    int
+	Expires time.Time
+	Created time.Time `xorm:"created"`
+}
+
+func (m *Invite) User() *User {
+	return getUser(m.UserId)
+}
+
+// Inviter returns the user who invited m, nil when m registered without invite
+func (m *User) Inviter() *User {
+	if m.InvitedBy == 0 {
+		return nil
+	}
+	return getUser(m.InvitedBy)
+}
+
+func (m *Invite) IsExpired() bool {
+	return !m.Expires.IsZero() && time.Now().After(m.Expires)
+}
+
+func (m *Invite) IsUsedUp() bool {
+	return m.MaxUses > 0 && m.Uses >= m.MaxUses
+}
+
+// IsValid tells whether the code can still be used to register
+func (m *Invite) IsValid() bool {
+	return !m.IsExpired() && !m.IsUsedUp()
+}
+
+func GetInviteByCode(code string) (*Invite, error) {
+	var invite = Invite{Code: code}
+	has, err := orm.Get(&invite)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrNotExist
+	}
+	return &invite, nil
+}
+
+// FindInvites returns the invites created by userId, all invites when it is 0
+func FindInvites(userId int64) ([]Invite, error) {
+	var invites = make([]Invite, 0)
+	err := orm.Desc("id").Find(&invites, &Invite{UserId: userId})
+	return invites, err
+}
+
+// UseInvite counts a registration on the code, it fails with ErrNotExist
+// when the code is unknown, expired or used up
+func UseInvite(code string) (*Invite, error) {
+	invite, err := GetInviteByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if invite.IsExpired() {
+		return nil, ErrNotExist
+	}
+
+	// count in sql so that concurrent registrations can not pass the limit
+	res, err := orm.Exec("UPDATE "+orm.Quote(orm.TableInfo(invite).Name)+
+		" SET uses = uses + 1 WHERE id = ? AND (max_uses = 0 OR uses < max_uses)", invite.Id)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrNotExist
+	}
+	invite.Uses++
+	return invite, nil
+}
+
+// ReleaseInvite gives back a use of the code when the registration failed
+func ReleaseInvite(invite *Invite) error {
+	_, err := orm.Exec("UPDATE "+orm.Quote(orm.TableInfo(invite).Name)+
+		" SET uses = uses - 1 WHERE id = ? AND uses > 0", invite.Id)
+	return err
+}
+
+// DeleteInvite deletes the invite of userId, any invite when it is 0
+func DeleteInvite(userId, id int64) error {
+	_, err := orm.Where("id = ?", id).Delete(&Invite{UserId: userId})
+	return err
+}
+
+// FindInvitedUsers returns the users registered with invites of userId
+func FindInvitedUsers(userId int64) ([]User, error) {
+	var users = make([]User, 0)
+	err := orm.Desc("id").Find(&users, &User{InvitedBy: userId})
+	return users, err
+}
@@ -0,0 +1,150 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// status of queued mails, failed mails gave up retrying and wait for resend
+const (
+	MAIL_QUEUE_PENDING = iota
+	MAIL_QUEUE_SENDING
+	MAIL_QUEUE_SENT
+	MAIL_QUEUE_FAILED
+)
+
+// MailQueue is a mail waiting to be sent or already sent
+// Recipients, CcAddrs, BccAddrs: comma separated emails
+type MailQueue struct {
+	Id         int64
+	MessageId  string `xorm:"varchar(255)"`
+	Recipients string `xorm:"text"`
+	CcAddrs    string `xorm:"text"`
+	BccAddrs   string `xorm:"text"`
+	ReplyTo    string `xorm:"varchar(255)"`
+	FromAddr   string `xorm:"varchar(255)"`
+	FromName   string `xorm:"varchar(255)"`
+	Subject    string `xorm:"varchar(255)"`
+	Body       string `xorm:"text"`
+	Text       string `xorm:"text"`
+	Type       string `xorm:"varchar(10)"`
+	Info       string `xorm:"varchar(255)"`
+	Status     int    `xorm:"index"`
+	Attempts   int
+	LastError  string    `xorm:"text"`
+	NextTry    time.Time `xorm:"index"`
+	Sent       time.Time
+	Created    time.Time `xorm:"created"`
+	Updated    time.Time `xorm:"updated"`
+}
+
+func (m *MailQueue) To() []string {
+	return splitEmails(m.Recipients)
+}
+
+func (m *MailQueue) Cc() []string {
+	return splitEmails(m.CcAddrs)
+}
+
+func (m *MailQueue) Bcc() []string {
+	return splitEmails(m.BccAddrs)
+}
+
+func splitEmails(emails string) []string {
+	if emails == "" {
+		return nil
+	}
+	return strings.Split(emails, ",")
+}
+
+func (m *MailQueue) IsPending() bool {
+	return m.Status == MAIL_QUEUE_PENDING
+}
+
+func (m *MailQueue) IsSending() bool {
+	return m.Status == MAIL_QUEUE_SENDING
+}
+
+func (m *MailQueue) IsSent() bool {
+	return m.Status == MAIL_QUEUE_SENT
+}
+
+func (m *MailQueue) IsFailed() bool {
+	return m.Status == MAIL_QUEUE_FAILED
+}
+
+func InsertMailQueue(m *MailQueue) error {
+	_, err := orm.Insert(m)
+	return err
+}
+
+func GetMailQueue(id int64) (*MailQueue, error) {
+	var m MailQueue
+	if err := GetById(id, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// FindDueMails returns pending mails whose next try is not after now
+func FindDueMails(now time.Time, limit int) ([]*MailQueue, error) {
+	var mails = make([]*MailQueue, 0)
+	err := orm.Where("status = ? AND next_try <= ?", MAIL_QUEUE_PENDING, now).
+		Asc("next_try").Limit(limit).Find(&mails)
+	return mails, err
+}
+
+// ClaimMail marks a pending mail as sending, it returns false when the
+// mail was taken by another worker
+func ClaimMail(m *MailQueue) (bool, error) {
+	m.Status = MAIL_QUEUE_SENDING
+	n, err := orm.Where("id = ? AND status = ?", m.Id, MAIL_QUEUE_PENDING).
+		Cols("status").Update(m)
+	return n > 0, err
+}
+
+func UpdateMailQueue(m *MailQueue, cols ...string) error {
+	_, err := orm.Id(m.Id).Cols(cols...).Update(m)
+	return err
+}
+
+// ResetSendingMails puts back mails left sending by a stopped process
+func ResetSendingMails() (int64, error) {
+	return orm.Where("status = ?", MAIL_QUEUE_SENDING).Cols("status").
+		Update(&MailQueue{Status: MAIL_QUEUE_PENDING})
+}
+
+// FindMailQueue lists mails of status for admin, newest first,
+// status -1 lists all
+func FindMailQueue(status, limit, offset int) ([]*MailQueue, error) {
+	var mails = make([]*MailQueue, 0)
+	sess := orm.Desc("id").Limit(limit, offset)
+	if status >= 0 {
+		sess = sess.Where("status = ?", status)
+	}
+	err := sess.Find(&mails)
+	return mails, err
+}
+
+func CountMailQueue(status int) (int64, error) {
+	sess := orm.NewSession()
+	defer sess.Close()
+	if status >= 0 {
+		sess = sess.Where("status = ?", status)
+	}
+	return sess.Count(new(MailQueue))
+}
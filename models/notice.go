@@ -21,14 +21,31 @@ type Notification struct {
 	Content      string    `xorm:"text"`
 	ContentCache string    `xorm:"text"`
 	Status       int       `xorm:"index"`
+	MailStatus   int       `xorm:"index"`
 	Created      time.Time `xorm:"created index"`
 }
 
+// mail status of notification, skipped is zero so that notifications
+// saved before mails were sent are never put in a digest
+const (
+	NOTICE_MAIL_SKIPPED = iota
+	NOTICE_MAIL_PENDING
+	NOTICE_MAIL_SENT
+)
+
 func (m *Notification) String() string {
 	return utils.ToStr(m.Id)
 }
 
 func (m *Notification) Link() string {
+	if m.IsFollow() {
+		if user := m.FromUser(); user != nil {
+			return user.Link()
+		}
+	}
+	if m.Floor == 0 {
+		return fmt.Sprintf("%s%s", setting.AppUrl, m.Uri)
+	}
 	return fmt.Sprintf("%s%s#reply%d", setting.AppUrl, m.Uri, m.Floor)
 }
 
@@ -40,6 +57,26 @@ func (m *Notification) GetContentCache() string {
 	}
 }
 
+func (n *Notification) IsReply() bool {
+	return n.Action == setting.NOTICE_TYPE_REPLY
+}
+
+func (n *Notification) IsFollow() bool {
+	return n.Action == setting.NOTICE_TYPE_FOLLOW
+}
+
+func (n *Notification) IsMention() bool {
+	return n.Action == setting.NOTICE_TYPE_MENTION
+}
+
+func (n *Notification) IsFavorite() bool {
+	return n.Action == setting.NOTICE_TYPE_FAVOURITE
+}
+
+func (n *Notification) IsUnread() bool {
+	return n.Status == setting.NOTICE_UNREAD
+}
+
 func (n *Notification) FromUser() *User {
 	return getUser(n.FromUserId)
 }
@@ -48,9 +85,37 @@ func (n *Notification) ToUser() *User {
 	return getUser(n.ToUserId)
 }
 
+// NotificationListener is told about new notifications and read status
+// changes after they are saved
+type NotificationListener interface {
+	NotificationInserted(notic *Notification)
+	NotificationsRead(userId int64)
+}
+
+var notificationListeners []NotificationListener
+
+func AddNotificationListener(l NotificationListener) {
+	notificationListeners = append(notificationListeners, l)
+}
+
 func InsertNotification(notic *Notification) error {
-	_, err := orm.Insert(notic)
-	return err
+	notic.MailStatus = NOTICE_MAIL_PENDING
+	if _, err := orm.Insert(notic); err != nil {
+		return err
+	}
+	for _, l := range notificationListeners {
+		l.NotificationInserted(notic)
+	}
+	return nil
+}
+
+// FindNotificationsAfterId returns notifications to user with id greater
+// than afterId, oldest first
+func FindNotificationsAfterId(userId, afterId int64, limit int) ([]*Notification, error) {
+	var notifications = make([]*Notification, 0)
+	err := orm.Where("to_user_id = ? AND from_user_id <> ? AND id > ?", userId, userId, afterId).
+		Asc("id").Limit(limit).Find(&notifications)
+	return notifications, err
 }
 
 func CountNotifications(userId int64) (int64, error) {
@@ -65,7 +130,13 @@ func FindNotificationsByUserId(userId int64, limit, start int) ([]*Notification,
 
 func MarkNortificationAsRead(userId int64, postId int64) error {
 	_, err := orm.Exec("UPDATE notification SET status=? WHERE to_user_id=? AND target_id=?", setting.NOTICE_READ, userId, postId)
-	return err
+	if err != nil {
+		return err
+	}
+	for _, l := range notificationListeners {
+		l.NotificationsRead(userId)
+	}
+	return nil
 }
 
 func GetUnreadNotificationCount(userId int64) int64 {
@@ -75,3 +146,20 @@ func GetUnreadNotificationCount(userId int64) int64 {
 	})
 	return count
 }
+
+// FindMailPendingNotifications returns notifications waiting for digest
+// mail created before the time with id greater than afterId, oldest first
+func FindMailPendingNotifications(before time.Time, afterId int64, limit int) ([]*Notification, error) {
+	var notifications = make([]*Notification, 0)
+	err := orm.Where("mail_status = ? AND created < ? AND id > ? AND from_user_id <> to_user_id",
+		NOTICE_MAIL_PENDING, before, afterId).Asc("id").Limit(limit).Find(&notifications)
+	return notifications, err
+}
+
+func UpdateNotificationsMailStatus(ids []int64, status int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := orm.In("id", ids).Cols("mail_status").Update(&Notification{MailStatus: status})
+	return err
+}
@@ -0,0 +1,106 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"time"
+
+	"github.com/go-tango/wego/setting"
+)
+
+// how notifications of an event are mailed
+const (
+	MAIL_OFF = iota
+	MAIL_INSTANT
+	MAIL_DAILY
+	MAIL_WEEKLY
+)
+
+var MailModes = []int{MAIL_INSTANT, MAIL_DAILY, MAIL_WEEKLY, MAIL_OFF}
+
+// events users can choose mail mode for
+const (
+	MAIL_EVENT_COMMENT = iota + 1
+	MAIL_EVENT_MENTION
+	MAIL_EVENT_FOLLOW
+	MAIL_EVENT_FAVORITE
+)
+
+var MailEvents = []int{MAIL_EVENT_COMMENT, MAIL_EVENT_MENTION, MAIL_EVENT_FOLLOW, MAIL_EVENT_FAVORITE}
+
+// mail mode of events without user preference
+var defaultMailModes = map[int]int{
+	MAIL_EVENT_COMMENT:  MAIL_INSTANT,
+	MAIL_EVENT_MENTION:  MAIL_INSTANT,
+	MAIL_EVENT_FOLLOW:   MAIL_DAILY,
+	MAIL_EVENT_FAVORITE: MAIL_WEEKLY,
+}
+
+// MailEventOf returns the preference event of a notification action
+func MailEventOf(action int) int {
+	switch action {
+	case setting.NOTICE_TYPE_COMMENT, setting.NOTICE_TYPE_REPLY:
+		return MAIL_EVENT_COMMENT
+	case setting.NOTICE_TYPE_MENTION:
+		return MAIL_EVENT_MENTION
+	case setting.NOTICE_TYPE_FOLLOW:
+		return MAIL_EVENT_FOLLOW
+	case setting.NOTICE_TYPE_FAVOURITE:
+		return MAIL_EVENT_FAVORITE
+	}
+	return 0
+}
+
+// MailPreference is the mail mode a user chose for an event
+type MailPreference struct {
+	Id      int64
+	UserId  int64 `xorm:"unique(user_event)"`
+	Event   int   `xorm:"unique(user_event)"`
+	Mode    int
+	Updated time.Time `xorm:"updated"`
+}
+
+// GetMailModes returns mail mode of every event for user, defaults are
+// used for events the user never set
+func GetMailModes(userId int64) (map[int]int, error) {
+	modes := make(map[int]int, len(defaultMailModes))
+	for event, mode := range defaultMailModes {
+		modes[event] = mode
+	}
+
+	var prefs []MailPreference
+	if err := orm.Where("user_id = ?", userId).Find(&prefs); err != nil {
+		return modes, err
+	}
+	for _, pref := range prefs {
+		modes[pref.Event] = pref.Mode
+	}
+	return modes, nil
+}
+
+func SetMailMode(userId int64, event, mode int) error {
+	pref := MailPreference{UserId: userId, Event: event}
+	has, err := orm.Get(&pref)
+	if err != nil {
+		return err
+	}
+	pref.Mode = mode
+	if has {
+		_, err = orm.Id(pref.Id).Cols("mode").Update(&pref)
+	} else {
+		_, err = orm.Insert(&pref)
+	}
+	return err
+}
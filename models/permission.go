@@ -0,0 +1,226 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// roles of users, every role has the rights of the roles before it
+// trusted: can edit own posts after replies and comments at any time
+// moderator: moderates all categories
+// admin: has all rights, same as User.IsAdmin
+const (
+	ROLE_MEMBER = iota
+	ROLE_TRUSTED
+	ROLE_MODERATOR
+	ROLE_ADMIN
+)
+
+var Roles = []int{ROLE_MEMBER, ROLE_TRUSTED, ROLE_MODERATOR, ROLE_ADMIN}
+
+var roleNames = map[int]string{
+	ROLE_MEMBER:    "member",
+	ROLE_TRUSTED:   "trusted",
+	ROLE_MODERATOR: "moderator",
+	ROLE_ADMIN:     "admin",
+}
+
+// RoleName returns the name of role used in api and locales
+func RoleName(role int) string {
+	return roleNames[role]
+}
+
+func (m *User) RoleName() string {
+	return RoleName(m.GetRole())
+}
+
+// Permission is a right checked by routers, moderators of a category have
+// every permission but PERM_ADMIN, PERM_BAN_USER and PERM_INVITE on posts
+// and comments of the category
+type Permission int
+
+const (
+	// edit posts of others
+	PERM_EDIT_POST Permission = iota + 1
+	// delete posts of others
+	PERM_DELETE_POST
+	// mark posts as best
+	PERM_MARK_BEST
+	// move posts to topics of another category
+	PERM_MOVE_POST
+	// edit and delete comments of others
+	PERM_EDIT_COMMENT
+	// see pending and hidden content, and handle reports
+	PERM_MODERATE
+	// forbid users to login
+	PERM_BAN_USER
+	// everything in the admin pages
+	PERM_ADMIN
+	// create invite codes for registration
+	PERM_INVITE
+)
+
+// role needed to have the permission on every category
+var permissionRoles = map[Permission]int{
+	PERM_EDIT_POST:    ROLE_MODERATOR,
+	PERM_DELETE_POST:  ROLE_MODERATOR,
+	PERM_MARK_BEST:    ROLE_MODERATOR,
+	PERM_MOVE_POST:    ROLE_MODERATOR,
+	PERM_EDIT_COMMENT: ROLE_MODERATOR,
+	PERM_MODERATE:     ROLE_MODERATOR,
+	PERM_BAN_USER:     ROLE_MODERATOR,
+	PERM_ADMIN:        ROLE_ADMIN,
+	PERM_INVITE:       ROLE_TRUSTED,
+}
+
+// GetRole returns the role of user, admin rights always follow IsAdmin
+// as admins of old databases have no role
+func (m *User) GetRole() int {
+	if m.IsAdmin {
+		return ROLE_ADMIN
+	}
+	if m.Role > ROLE_MODERATOR {
+		return ROLE_MODERATOR
+	}
+	return m.Role
+}
+
+// SetRole changes the role, IsAdmin follows it
+func (m *User) SetRole(role int) {
+	m.Role = role
+	m.IsAdmin = role == ROLE_ADMIN
+}
+
+// RestrictRole drops rights above role for this request, used for access
+// tokens without the admin scope
+func (m *User) RestrictRole(role int) {
+	if role < ROLE_ADMIN {
+		m.IsAdmin = false
+	}
+	if m.Role > role {
+		m.Role = role
+	}
+	if role < ROLE_MODERATOR {
+		m.moderates = map[int64]bool{}
+	}
+}
+
+func (m *User) IsTrusted() bool {
+	return m.GetRole() >= ROLE_TRUSTED
+}
+
+// IsModerator reports whether user moderates all categories
+func (m *User) IsModerator() bool {
+	return m.GetRole() >= ROLE_MODERATOR
+}
+
+// ModeratedCategoryIds returns categories user is assigned to moderate
+func (m *User) ModeratedCategoryIds() []int64 {
+	m.loadModerates()
+	ids := make([]int64, 0, len(m.moderates))
+	for id := range m.moderates {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// CanModerate reports whether user moderates any category
+func (m *User) CanModerate() bool {
+	if m.Id == 0 {
+		return false
+	}
+	if m.IsModerator() {
+		return true
+	}
+	m.loadModerates()
+	return len(m.moderates) > 0
+}
+
+func (m *User) loadModerates() {
+	if m.moderates != nil {
+		return
+	}
+	m.moderates = map[int64]bool{}
+	if m.Id == 0 {
+		return
+	}
+	ids, err := FindModeratedCategoryIds(m.Id)
+	if err != nil {
+		return
+	}
+	for _, id := range ids {
+		m.moderates[id] = true
+	}
+}
+
+// Can reports whether user has the permission on content of the category,
+// categoryId 0 asks for the permission on every category
+func (m *User) Can(perm Permission, categoryId int64) bool {
+	if m == nil || m.Id == 0 {
+		return false
+	}
+	role, ok := permissionRoles[perm]
+	if !ok {
+		return false
+	}
+	if m.GetRole() >= role {
+		return true
+	}
+	if perm == PERM_ADMIN || perm == PERM_BAN_USER || perm == PERM_INVITE || categoryId == 0 {
+		return false
+	}
+	m.loadModerates()
+	return m.moderates[categoryId]
+}
+
+// CategoryModerator assigns a user to moderate a category
+type CategoryModerator struct {
+	Id         int64
+	UserId     int64     `xorm:"unique(user_category)"`
+	CategoryId int64     `xorm:"unique(user_category) index"`
+	Created    time.Time `xorm:"created"`
+}
+
+func (c *CategoryModerator) User() *User {
+	return getUser(c.UserId)
+}
+
+func AddCategoryModerator(userId, categoryId int64) error {
+	has, err := orm.Get(&CategoryModerator{UserId: userId, CategoryId: categoryId})
+	if err != nil || has {
+		return err
+	}
+	_, err = orm.Insert(&CategoryModerator{UserId: userId, CategoryId: categoryId})
+	return err
+}
+
+func RemoveCategoryModerator(userId, categoryId int64) error {
+	_, err := orm.Delete(&CategoryModerator{UserId: userId, CategoryId: categoryId})
+	return err
+}
+
+// FindCategoryModerators returns moderators assigned to the category
+func FindCategoryModerators(categoryId int64) ([]*CategoryModerator, error) {
+	var mods = make([]*CategoryModerator, 0)
+	err := orm.Where("category_id = ?", categoryId).Asc("id").Find(&mods)
+	return mods, err
+}
+
+func FindModeratedCategoryIds(userId int64) ([]int64, error) {
+	var ids []int64
+	err := orm.Table(new(CategoryModerator)).Where("user_id = ?", userId).Cols("category_id").Find(&ids)
+	return ids, err
+}
@@ -10,6 +10,14 @@ import (
 	"github.com/go-tango/wego/setting"
 )
 
+const (
+	POST_STATUS_NORMAL = iota
+	// waiting for review by moderators, shown to the author and admins only
+	POST_STATUS_PENDING
+	// hidden by moderators
+	POST_STATUS_HIDDEN
+)
+
 // post content
 type Post struct {
 	Id           int64
@@ -27,6 +35,7 @@ type Post struct {
 	IsBest       bool      `xorm:"index"`
 	CanEdit      bool      `xorm:"index"`
 	CategoryId   int64     `xorm:"index"`
+	Status       int       `xorm:"index"`
 	Created      time.Time `xorm:"created"`
 	Updated      time.Time `xorm:"updated"`
 	LastReplied  time.Time `xorm:"updated"`
@@ -36,6 +45,62 @@ func (m *Post) String() string {
 	return utils.ToStr(m.Id)
 }
 
+func (m *Post) IsPending() bool {
+	return m.Status == POST_STATUS_PENDING
+}
+
+func (m *Post) IsHidden() bool {
+	return m.Status == POST_STATUS_HIDDEN
+}
+
+// IsVisible reports whether the post is listed, pending and hidden posts are
+// left out until reviewed
+func (m *Post) IsVisible() bool {
+	return m.Status == POST_STATUS_NORMAL
+}
+
+// CanViewBy reports whether user may open the post, authors and moderators
+// can open posts that are not visible
+func (m *Post) CanViewBy(user *User) bool {
+	if m.IsVisible() {
+		return true
+	}
+	return user != nil && user.Id != 0 && (user.Id == m.UserId || user.Can(PERM_MODERATE, m.CategoryId))
+}
+
+// CanEditBy reports whether user may edit the post. Authors can edit their
+// posts unless an admin has locked it by clearing CanEdit, trusted authors
+// even then. Replies do not lock posts, every edit is kept as a revision.
+func (m *Post) CanEditBy(user *User) bool {
+	if user == nil || user.Id == 0 {
+		return false
+	}
+	if user.Id == m.UserId && (m.CanEdit || user.IsTrusted()) {
+		return true
+	}
+	return user.Can(PERM_EDIT_POST, m.CategoryId)
+}
+
+func (m *Post) CanDeleteBy(user *User) bool {
+	if user == nil || user.Id == 0 {
+		return false
+	}
+	return user.Id == m.UserId || user.Can(PERM_DELETE_POST, m.CategoryId)
+}
+
+func (m *Post) CanMarkBestBy(user *User) bool {
+	return user != nil && user.Can(PERM_MARK_BEST, m.CategoryId)
+}
+
+// CanMoveTo reports whether user may change the topic of the post, moving
+// to a topic of another category needs PERM_MOVE_POST on both categories
+func (m *Post) CanMoveTo(user *User, topic *Topic) bool {
+	if topic.CategoryId == m.CategoryId {
+		return m.CanEditBy(user)
+	}
+	return user != nil && user.Can(PERM_MOVE_POST, m.CategoryId) && user.Can(PERM_MOVE_POST, topic.CategoryId)
+}
+
 func (m *Post) Link() string {
 	return fmt.Sprintf("%spost/%d", setting.AppUrl, m.Id)
 }
@@ -109,6 +174,19 @@ func (p *Post) Insert() error {
 	return err
 }
 
+// CountPostsByUserSince counts posts of user created after since
+func CountPostsByUserSince(userId int64, since time.Time) (int64, error) {
+	return orm.Where("user_id = ? AND created > ?", userId, since).Count(new(Post))
+}
+
+// HasPostContentSince reports whether user posted a post other than
+// exceptId with the same content after since
+func HasPostContentSince(userId, exceptId int64, content string, since time.Time) (bool, error) {
+	cnt, err := orm.Where("user_id = ? AND created > ? AND content = ? AND id <> ?", userId, since, content, exceptId).
+		Count(new(Post))
+	return cnt > 0, err
+}
+
 func GetPost(id int64, userId int64, post *Post) error {
 	s := orm.Where("id = ?", id)
 	if userId > 0 {
@@ -138,20 +216,51 @@ func GetPostById(id int64) (*Post, error) {
 
 func FindPosts(limit, start int) ([]Post, error) {
 	var posts = make([]Post, 0)
-	err := orm.Desc("last_replied").Limit(limit, start).Find(&posts)
+	err := orm.Where("status = ?", POST_STATUS_NORMAL).Desc("last_replied").Limit(limit, start).Find(&posts)
+	return posts, err
+}
+
+// CountVisiblePosts counts visible posts matching example, as listed on
+// the home, category and topic pages
+func CountVisiblePosts(example *Post) (int64, error) {
+	return orm.Where("status = ?", POST_STATUS_NORMAL).Count(example)
+}
+
+// FindPostsByUserId returns visible posts of a user, newest first
+func FindPostsByUserId(userId int64, limit, start int) ([]Post, error) {
+	var posts = make([]Post, 0)
+	err := orm.Where("user_id = ? AND status = ?", userId, POST_STATUS_NORMAL).
+		Desc("id").Limit(limit, start).Find(&posts)
+	return posts, err
+}
+
+// CountPostsByUserId counts the posts listed by FindPostsByUserId
+func CountPostsByUserId(userId int64) (int64, error) {
+	return orm.Where("status = ?", POST_STATUS_NORMAL).Count(&Post{UserId: userId})
+}
+
+// FindPostsBeforeId returns posts matching example with id less than beforeId,
+// newest first. beforeId <= 0 means from the newest post.
+func FindPostsBeforeId(example *Post, beforeId int64, limit int) ([]Post, error) {
+	var posts = make([]Post, 0)
+	s := orm.Where("status = ?", POST_STATUS_NORMAL).Desc("id").Limit(limit)
+	if beforeId > 0 {
+		s.And("id < ?", beforeId)
+	}
+	err := s.Find(&posts, example)
 	return posts, err
 }
 
 func RecentPosts(sort string, limit, start int) ([]Post, error) {
 	var posts = make([]Post, 0)
-	s := orm.Limit(limit, start)
+	s := orm.Where("status = ?", POST_STATUS_NORMAL).Limit(limit, start)
 	switch sort {
 	case "recent":
 		s.Desc("created")
 	case "hot":
 		s.Desc("last_replied")
 	case "cold":
-		s.Where("Replys = ?", 0).Desc("created")
+		s.And("Replys = ?", 0).Desc("created")
 	default:
 		return nil, errors.New("unknown sort")
 	}
@@ -160,14 +269,75 @@ func RecentPosts(sort string, limit, start int) ([]Post, error) {
 }
 
 func NewBestPostsByExample(posts *[]Post, example *Post) error {
-	return orm.Where("is_best = ?", true).Desc("created").Limit(10).Find(posts, example)
+	return orm.Where("is_best = ? AND status = ?", true, POST_STATUS_NORMAL).Desc("created").Limit(10).Find(posts, example)
 }
 
 func MostReplysPostsByExample(posts *[]Post, example *Post) error {
-	return orm.Where("replys > 0").Desc("created", "replys").Limit(10).Find(posts, example)
+	return orm.Where("replys > 0 AND status = ?", POST_STATUS_NORMAL).Desc("created", "replys").Limit(10).Find(posts, example)
 }
 
 func UpdatePostBrowsersById(id int64) error {
 	_, err := orm.Id(id).Incr("browsers").Update(new(Post))
 	return err
 }
+
+// posts were locked by their first reply before revisions were recorded
+const settingPostsUnlocked = "migration.posts_unlocked"
+
+// unlockRepliedPosts makes posts locked by replies editable again once, so
+// that all posts follow the rule of CanEditBy. Posts without replies could
+// only be locked by admins and are kept.
+func unlockRepliedPosts() error {
+	if _, err := GetSettingValue(settingPostsUnlocked); err != ErrNotExist {
+		return err
+	}
+	sql := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ? AND %s > 0", orm.Quote(orm.TableInfo(new(Post)).Name),
+		orm.Quote("can_edit"), orm.Quote("can_edit"), orm.Quote("replys"))
+	if _, err := orm.Exec(sql, true, false); err != nil {
+		return err
+	}
+	return SaveSetting(settingPostsUnlocked, "1")
+}
+
+// PostRevision is a version of post title and content
+// UserId: the editor of this version
+type PostRevision struct {
+	Id      int64
+	PostId  int64  `xorm:"index"`
+	UserId  int64  `xorm:"index"`
+	Title   string `xorm:"varchar(60)"`
+	Content string `xorm:"text"`
+	Created time.Time
+}
+
+func (r *PostRevision) User() *User {
+	return getUser(r.UserId)
+}
+
+func InsertPostRevision(rev *PostRevision) error {
+	_, err := orm.Insert(rev)
+	return err
+}
+
+func GetPostRevision(postId, id int64) (*PostRevision, error) {
+	var rev PostRevision
+	has, err := orm.Where("id = ? AND post_id = ?", id, postId).Get(&rev)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrNotExist
+	}
+	return &rev, nil
+}
+
+// FindPostRevisions returns revisions of a post, oldest first
+func FindPostRevisions(postId int64) ([]PostRevision, error) {
+	var revs = make([]PostRevision, 0)
+	err := orm.Where("post_id = ?", postId).Asc("id").Find(&revs)
+	return revs, err
+}
+
+func CountPostRevisions(postId int64) (int64, error) {
+	return orm.Count(&PostRevision{PostId: postId})
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/go-xorm/xorm"
+)
+
+// DeletePost deletes the post with its comments, revisions and favorites,
+// the favorite counters of users are recounted. Open reports of them are
+// resolved as deleted by handlerId, handled reports are kept as history.
+func DeletePost(id, handlerId int64) error {
+	sess := orm.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+	if err := deletePost(sess, id, handlerId); err != nil {
+		sess.Rollback()
+		return err
+	}
+	return sess.Commit()
+}
+
+func deletePost(sess *xorm.Session, id, handlerId int64) error {
+	var comments, favorers []int64
+	if err := sess.Table(new(Comment)).Where("post_id = ?", id).Cols("id").Find(&comments); err != nil {
+		return err
+	}
+	if err := sess.Table(new(FavoritePost)).Where("post_id = ? AND is_fav = ?", id, true).Cols("user_id").Find(&favorers); err != nil {
+		return err
+	}
+
+	report := Report{Status: REPORT_DELETED, HandlerId: handlerId, Handled: time.Now()}
+	if _, err := sess.Where("target_type = ? AND target_id = ? AND status = ?", REPORT_POST, id, REPORT_OPEN).
+		Cols("status", "handler_id", "handled").Update(&report); err != nil {
+		return err
+	}
+	if len(comments) > 0 {
+		if _, err := sess.Where("target_type = ? AND status = ?", REPORT_COMMENT, REPORT_OPEN).In("target_id", comments).
+			Cols("status", "handler_id", "handled").Update(&report); err != nil {
+			return err
+		}
+		if _, err := sess.In("comment_id", comments).Delete(new(CommentHistory)); err != nil {
+			return err
+		}
+	}
+
+	beans := []interface{}{new(Comment), new(PostRevision), new(FavoritePost)}
+	for _, bean := range beans {
+		if _, err := sess.Where("post_id = ?", id).Delete(bean); err != nil {
+			return err
+		}
+	}
+	if _, err := sess.Id(id).Delete(new(Post)); err != nil {
+		return err
+	}
+
+	return recount(sess, new(User), "fav_posts", new(FavoritePost), "user_id", "is_fav = ?", favorers)
+}
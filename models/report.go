@@ -0,0 +1,234 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-xorm/xorm"
+
+	"github.com/go-tango/wego/setting"
+)
+
+// kinds of reported objects
+const (
+	REPORT_POST = iota + 1
+	REPORT_COMMENT
+	REPORT_USER
+)
+
+// reasons of reports
+const (
+	REPORT_REASON_SPAM = iota + 1
+	REPORT_REASON_ABUSE
+	REPORT_REASON_OFFTOPIC
+	REPORT_REASON_OTHER
+)
+
+var ReportReasons = []int{
+	REPORT_REASON_SPAM,
+	REPORT_REASON_ABUSE,
+	REPORT_REASON_OFFTOPIC,
+	REPORT_REASON_OTHER,
+}
+
+// status of reports, an open report waits for a moderator, the others
+// tell which action closed it
+const (
+	REPORT_OPEN = iota
+	REPORT_APPROVED
+	REPORT_HIDDEN
+	REPORT_DELETED
+	REPORT_BANNED
+)
+
+// Report flags a post, comment or user for moderators
+// UserId: the reporter, 0 for reports made by the system
+// CategoryId: category of the reported content, 0 for users
+// HandlerId: the moderator who closed the report
+type Report struct {
+	Id         int64
+	UserId     int64 `xorm:"index"`
+	TargetType int   `xorm:"index(target)"`
+	TargetId   int64 `xorm:"index(target)"`
+	CategoryId int64 `xorm:"index"`
+	Reason     int
+	Note       string `xorm:"varchar(500)"`
+	Status     int    `xorm:"index"`
+	HandlerId  int64
+	Handled    time.Time
+	Created    time.Time `xorm:"created"`
+}
+
+func (r *Report) IsOpen() bool {
+	return r.Status == REPORT_OPEN
+}
+
+func (r *Report) IsPost() bool {
+	return r.TargetType == REPORT_POST
+}
+
+func (r *Report) IsComment() bool {
+	return r.TargetType == REPORT_COMMENT
+}
+
+func (r *Report) IsUser() bool {
+	return r.TargetType == REPORT_USER
+}
+
+func (r *Report) User() *User {
+	return getUser(r.UserId)
+}
+
+func (r *Report) Handler() *User {
+	return getUser(r.HandlerId)
+}
+
+// Post returns the reported post, or the post of the reported comment
+func (r *Report) Post() *Post {
+	switch r.TargetType {
+	case REPORT_POST:
+		post, _ := GetPostById(r.TargetId)
+		return post
+	case REPORT_COMMENT:
+		if comment := r.Comment(); comment != nil {
+			return comment.Post()
+		}
+	}
+	return nil
+}
+
+func (r *Report) Comment() *Comment {
+	if r.TargetType != REPORT_COMMENT {
+		return nil
+	}
+	comment, _ := GetCommentById(r.TargetId)
+	return comment
+}
+
+// TargetUser returns the reported user, or the author of the reported content
+func (r *Report) TargetUser() *User {
+	switch r.TargetType {
+	case REPORT_USER:
+		return getUser(r.TargetId)
+	case REPORT_COMMENT:
+		if comment := r.Comment(); comment != nil {
+			return getUser(comment.UserId)
+		}
+	case REPORT_POST:
+		if post := r.Post(); post != nil {
+			return getUser(post.UserId)
+		}
+	}
+	return nil
+}
+
+// TargetName returns the model name of the reported object
+func (r *Report) TargetName() string {
+	switch r.TargetType {
+	case REPORT_POST:
+		return "post"
+	case REPORT_COMMENT:
+		return "comment"
+	case REPORT_USER:
+		return "user"
+	}
+	return ""
+}
+
+// TargetLink returns the page of the reported object
+func (r *Report) TargetLink() string {
+	switch r.TargetType {
+	case REPORT_POST:
+		return fmt.Sprintf("%spost/%d", setting.AppUrl, r.TargetId)
+	case REPORT_COMMENT:
+		if comment := r.Comment(); comment != nil {
+			return fmt.Sprintf("%spost/%d#reply%d", setting.AppUrl, comment.PostId, comment.Floor)
+		}
+	case REPORT_USER:
+		if user := r.TargetUser(); user != nil {
+			return user.Link()
+		}
+	}
+	return ""
+}
+
+func InsertReport(report *Report) error {
+	_, err := orm.Insert(report)
+	return err
+}
+
+func GetReport(id int64) (*Report, error) {
+	var report Report
+	if err := GetById(id, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// HasOpenReport reports whether user has an open report on the target
+func HasOpenReport(userId int64, targetType int, targetId int64) (bool, error) {
+	return orm.Where("user_id = ? AND target_type = ? AND target_id = ? AND status = ?",
+		userId, targetType, targetId, REPORT_OPEN).Get(new(Report))
+}
+
+// CountOpenReports counts open reports on the target
+func CountOpenReports(targetType int, targetId int64) (int64, error) {
+	return orm.Where("target_type = ? AND target_id = ? AND status = ?",
+		targetType, targetId, REPORT_OPEN).Count(new(Report))
+}
+
+// reportsSession selects open or closed reports, in categoryIds unless it is nil
+func reportsSession(open bool, categoryIds []int64) *xorm.Session {
+	sess := orm.NewSession()
+	if open {
+		sess = sess.Where("status = ?", REPORT_OPEN)
+	} else {
+		sess = sess.Where("status != ?", REPORT_OPEN)
+	}
+	if categoryIds != nil {
+		if len(categoryIds) == 0 {
+			// no category at all
+			return sess.And("1 = 0")
+		}
+		sess = sess.In("category_id", categoryIds)
+	}
+	return sess
+}
+
+// FindReports returns reports newest first, open selects open or closed
+// reports, categoryIds limits them to some categories unless it is nil
+func FindReports(open bool, categoryIds []int64, limit, offset int) ([]*Report, error) {
+	var reports = make([]*Report, 0)
+	sess := reportsSession(open, categoryIds)
+	defer sess.Close()
+	err := sess.Desc("id").Limit(limit, offset).Find(&reports)
+	return reports, err
+}
+
+func CountReports(open bool, categoryIds []int64) (int64, error) {
+	sess := reportsSession(open, categoryIds)
+	defer sess.Close()
+	return sess.Count(new(Report))
+}
+
+// ResolveReports closes all open reports on the target with status
+func ResolveReports(targetType int, targetId int64, status int, handlerId int64) error {
+	report := Report{Status: status, HandlerId: handlerId, Handled: time.Now()}
+	_, err := orm.Where("target_type = ? AND target_id = ? AND status = ?",
+		targetType, targetId, REPORT_OPEN).Cols("status", "handler_id", "handled").Update(&report)
+	return err
+}
@@ -7,3 +7,36 @@ type Setting struct {
 	Value   string `xorm:"text"`
 	Updated string `xorm:"created"`
 }
+
+// GetSettingValue returns value of the setting, ErrNotExist when never saved
+func GetSettingValue(name string) (string, error) {
+	s := Setting{Name: name}
+	has, err := orm.Get(&s)
+	if err != nil {
+		return "", err
+	}
+	if !has {
+		return "", ErrNotExist
+	}
+	return s.Value, nil
+}
+
+func SaveSetting(name, value string) error {
+	s := Setting{Name: name}
+	has, err := orm.Get(&s)
+	if err != nil {
+		return err
+	}
+	s.Value = value
+	if has {
+		_, err = orm.Id(s.Id).Cols("value").Update(&s)
+	} else {
+		_, err = orm.Insert(&s)
+	}
+	return err
+}
+
+func DeleteSetting(name string) error {
+	_, err := orm.Delete(&Setting{Name: name})
+	return err
+}
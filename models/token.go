@@ -0,0 +1,90 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// access token scopes
+const (
+	SCOPE_READ  = "read"
+	SCOPE_WRITE = "write"
+	SCOPE_ADMIN = "admin"
+)
+
+var TokenScopes = []string{SCOPE_READ, SCOPE_WRITE, SCOPE_ADMIN}
+
+// personal access token for scripts, only the sha256 of token is saved
+// Scopes: comma separated scope names
+type AccessToken struct {
+	Id        int64
+	UserId    int64     `xorm:"index"`
+	Name      string    `xorm:"varchar(50)"`
+	TokenHash string    `xorm:"varchar(64) unique"`
+	Scopes    string    `xorm:"varchar(50)"`
+	LastUsed  time.Time `xorm:"index"`
+	Created   time.Time `xorm:"created"`
+}
+
+func (m *AccessToken) ScopeList() []string {
+	if m.Scopes == "" {
+		return nil
+	}
+	return strings.Split(m.Scopes, ",")
+}
+
+func (m *AccessToken) HasScope(scope string) bool {
+	for _, s := range m.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *AccessToken) User() *User {
+	return getUser(m.UserId)
+}
+
+func GetAccessTokenByHash(hash string) (*AccessToken, error) {
+	var token = AccessToken{TokenHash: hash}
+	has, err := orm.Get(&token)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrNotExist
+	}
+	return &token, nil
+}
+
+func FindAccessTokensByUserId(userId int64) ([]AccessToken, error) {
+	var tokens = make([]AccessToken, 0)
+	err := orm.Desc("id").Find(&tokens, &AccessToken{UserId: userId})
+	return tokens, err
+}
+
+func DeleteAccessToken(userId, id int64) error {
+	_, err := orm.Where("id = ?", id).Delete(&AccessToken{UserId: userId})
+	return err
+}
+
+func UpdateAccessTokenLastUsed(token *AccessToken) error {
+	token.LastUsed = time.Now()
+	_, err := orm.Id(token.Id).Cols("last_used").Update(token)
+	return err
+}
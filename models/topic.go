@@ -30,6 +30,10 @@ func (m *Topic) Link() string {
 	return fmt.Sprintf("%stopic/%s", setting.AppUrl, m.Slug)
 }
 
+func (m *Topic) FeedLink() string {
+	return fmt.Sprintf("%stopic/%s/feed", setting.AppUrl, m.Slug)
+}
+
 func (t *Topic) Category() *Category {
 	var category Category
 	has, err := orm.Id(t.CategoryId).Get(&category)
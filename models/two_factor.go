@@ -0,0 +1,113 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// two-factor authentication of a user, only saved after the first code of
+// the secret is verified. LastStep is the time step of the last used code,
+// a code can not be used twice.
+type TwoFactor struct {
+	Id       int64
+	UserId   int64  `xorm:"unique"`
+	Secret   string `xorm:"varchar(64)"`
+	LastStep int64
+	Created  time.Time `xorm:"created"`
+}
+
+// one-time recovery code for users losing their authenticator,
+// only the sha256 of code is saved and a used code is deleted
+type RecoveryCode struct {
+	Id       int64
+	UserId   int64     `xorm:"index"`
+	CodeHash string    `xorm:"varchar(64)"`
+	Created  time.Time `xorm:"created"`
+}
+
+func GetTwoFactor(userId int64) (*TwoFactor, error) {
+	var tf = TwoFactor{UserId: userId}
+	has, err := orm.Get(&tf)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrNotExist
+	}
+	return &tf, nil
+}
+
+func HasTwoFactor(userId int64) bool {
+	has, _ := orm.Get(&TwoFactor{UserId: userId})
+	return has
+}
+
+// SaveTwoFactor enables two-factor authentication with new recovery codes
+func SaveTwoFactor(tf *TwoFactor, codeHashes []string) error {
+	if err := DeleteTwoFactor(tf.UserId); err != nil {
+		return err
+	}
+	if _, err := orm.Insert(tf); err != nil {
+		return err
+	}
+	return ReplaceRecoveryCodes(tf.UserId, codeHashes)
+}
+
+// DeleteTwoFactor disables two-factor authentication of user
+func DeleteTwoFactor(userId int64) error {
+	if _, err := orm.Delete(&TwoFactor{UserId: userId}); err != nil {
+		return err
+	}
+	_, err := orm.Delete(&RecoveryCode{UserId: userId})
+	return err
+}
+
+// UseTwoFactorStep saves step as the last used one, false if a code of
+// the step or a later step is used already
+func UseTwoFactorStep(tf *TwoFactor, step int64) (bool, error) {
+	n, err := orm.Where("user_id = ? AND last_step < ?", tf.UserId, step).
+		Cols("last_step").Update(&TwoFactor{LastStep: step})
+	if err != nil || n == 0 {
+		return false, err
+	}
+	tf.LastStep = step
+	return true, nil
+}
+
+func ReplaceRecoveryCodes(userId int64, codeHashes []string) error {
+	if _, err := orm.Delete(&RecoveryCode{UserId: userId}); err != nil {
+		return err
+	}
+	codes := make([]RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, RecoveryCode{UserId: userId, CodeHash: hash})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	_, err := orm.Insert(&codes)
+	return err
+}
+
+// UseRecoveryCode deletes the code, false if user has no such code
+func UseRecoveryCode(userId int64, codeHash string) (bool, error) {
+	n, err := orm.Delete(&RecoveryCode{UserId: userId, CodeHash: codeHash})
+	return n > 0, err
+}
+
+func CountRecoveryCodes(userId int64) (int64, error) {
+	return orm.Count(&RecoveryCode{UserId: userId})
+}
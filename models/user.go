@@ -10,8 +10,11 @@ import (
 
 // main user table
 // IsAdmin: user is admininstator
+// Role: one of ROLE_*, admins have ROLE_ADMIN
 // IsActive: set active when email is verified
 // IsForbid: forbid user login
+// LoginSource: name of the directory checking the password, empty for local users
+// InvitedBy: id of the user whose invite code was used to register
 type User struct {
 	Id          int64
 	UserName    string `xorm:"varchar(30) unique"`
@@ -37,12 +40,18 @@ type User struct {
 	FavPosts    int
 	FavTopics   int
 	IsAdmin     bool      `xorm:"index"`
+	Role        int       `xorm:"index"`
 	IsActive    bool      `xorm:"index"`
 	IsForbid    bool      `xorm:"index"`
+	LoginSource string    `xorm:"varchar(20) index"`
+	InvitedBy   int64     `xorm:"index"`
 	Lang        int       `xorm:"index"`
 	Rands       string    `xorm:"varchar(10)"`
 	Created     time.Time `xorm:"created"`
 	Updated     time.Time `xorm:"updated"`
+
+	// categories moderated by user, loaded on first permission check
+	moderates map[int64]bool `xorm:"-"`
 }
 
 func (m *User) String() string {
@@ -53,6 +62,10 @@ func (m *User) Link() string {
 	return fmt.Sprintf("%suser/%s", setting.AppUrl, m.UserName)
 }
 
+func (m *User) FeedLink() string {
+	return fmt.Sprintf("%suser/%s/feed", setting.AppUrl, m.UserName)
+}
+
 func (m *User) avatarLink(size int) string {
 	if m.AvatarType == setting.AvatarTypePersonalized {
 		if m.AvatarKey != "" {
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-tango/social-auth"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/setting"
+	"github.com/go-xorm/xorm"
+)
+
+// login source of the ghost user, no source has it so nobody can login
+const LOGIN_SOURCE_GHOST = "ghost"
+
+func (m *User) IsGhost() bool {
+	return m.LoginSource == LOGIN_SOURCE_GHOST
+}
+
+// GetGhostUser returns the user owning the content of deleted users, it is
+// created on first use
+func GetGhostUser() (*User, error) {
+	var user = User{UserName: setting.GhostUserName}
+	has, err := orm.Get(&user)
+	if err != nil {
+		return nil, err
+	}
+	if has {
+		if !user.IsGhost() {
+			return nil, fmt.Errorf("ghost user name %s is used by a member", user.UserName)
+		}
+		return &user, nil
+	}
+
+	user = User{
+		UserName:    setting.GhostUserName,
+		NickName:    setting.GhostUserName,
+		Email:       setting.GhostUserName + "@deleted.invalid",
+		IsForbid:    true,
+		LoginSource: LOGIN_SOURCE_GHOST,
+		AvatarType:  setting.AvatarTypeGravatar,
+	}
+	user.GrEmail = utils.EncodeMd5(user.Email)
+	if _, err := orm.Insert(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// content of users kept after deletion and references to the user, the
+// columns are given to the ghost
+var ghostColumns = []struct {
+	bean    interface{}
+	columns []string
+}{
+	{new(Post), []string{"user_id", "last_author_id"}},
+	{new(Comment), []string{"user_id"}},
+	{new(CommentHistory), []string{"user_id"}},
+	{new(PostRevision), []string{"user_id"}},
+	{new(Page), []string{"user_id", "last_author_id"}},
+	{new(Image), []string{"user_id"}},
+	{new(Report), []string{"user_id", "handler_id"}},
+	{new(User), []string{"invited_by"}},
+}
+
+// DeleteUser gives the posts, comments and other content of user to ghost,
+// then deletes the user with its follows, favorites, notifications,
+// logins and other personal data. Audit logs are kept.
+func DeleteUser(user, ghost *User) error {
+	if user.IsGhost() {
+		return fmt.Errorf("the ghost user can not be deleted")
+	}
+
+	sess := orm.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+	if err := deleteUser(sess, user, ghost); err != nil {
+		sess.Rollback()
+		return err
+	}
+	return sess.Commit()
+}
+
+func deleteUser(sess *xorm.Session, user, ghost *User) error {
+	for _, g := range ghostColumns {
+		for _, col := range g.columns {
+			table := orm.TableInfo(g.bean).Name
+			sql := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", orm.Quote(table), orm.Quote(col), orm.Quote(col))
+			if _, err := sess.Exec(sql, ghost.Id, user.Id); err != nil {
+				return err
+			}
+		}
+	}
+
+	// counters of others which counted rows of user, recounted after deletion
+	var followed, followers, favorites, topics []int64
+	if err := sess.Table(new(Follow)).Where("user_id = ?", user.Id).Cols("follow_user_id").Find(&followed); err != nil {
+		return err
+	}
+	if err := sess.Table(new(Follow)).Where("follow_user_id = ?", user.Id).Cols("user_id").Find(&followers); err != nil {
+		return err
+	}
+	if err := sess.Table(new(FavoritePost)).Where("user_id = ?", user.Id).Cols("post_id").Find(&favorites); err != nil {
+		return err
+	}
+	if err := sess.Table(new(FollowTopic)).Where("user_id = ?", user.Id).Cols("topic_id").Find(&topics); err != nil {
+		return err
+	}
+
+	if err := deleteUserMails(sess, user.Email); err != nil {
+		return err
+	}
+
+	beans := []interface{}{
+		&FavoritePost{UserId: user.Id},
+		&FollowTopic{UserId: user.Id},
+		&MailPreference{UserId: user.Id},
+		&AccessToken{UserId: user.Id},
+		&TwoFactor{UserId: user.Id},
+		&RecoveryCode{UserId: user.Id},
+		&UserSession{UserId: user.Id},
+		&CategoryModerator{UserId: user.Id},
+		&DataExport{UserId: user.Id},
+		&Invite{UserId: user.Id},
+		&social.UserSocial{Uid: int(user.Id)},
+	}
+	for _, bean := range beans {
+		if _, err := sess.Delete(bean); err != nil {
+			return err
+		}
+	}
+
+	if _, err := sess.Where("user_id = ? OR follow_user_id = ?", user.Id, user.Id).Delete(new(Follow)); err != nil {
+		return err
+	}
+	if _, err := sess.Where("to_user_id = ? OR from_user_id = ?", user.Id, user.Id).Delete(new(Notification)); err != nil {
+		return err
+	}
+	if _, err := sess.Id(user.Id).Delete(new(User)); err != nil {
+		return err
+	}
+
+	recounts := []struct {
+		bean        interface{}
+		column      string
+		countBean   interface{}
+		countColumn string
+		cond        string
+		ids         []int64
+	}{
+		{new(User), "followers", new(Follow), "follow_user_id", "", followed},
+		{new(User), "following", new(Follow), "user_id", "", followers},
+		{new(Post), "favorites", new(FavoritePost), "post_id", "is_fav = ?", favorites},
+		{new(Topic), "followers", new(FollowTopic), "topic_id", "", topics},
+	}
+	for _, r := range recounts {
+		if err := recount(sess, r.bean, r.column, r.countBean, r.countColumn, r.cond, r.ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recount sets column of the rows of bean with ids to the number of rows of
+// countBean referring to them by countColumn and matching cond, which takes
+// true as its argument when given
+func recount(sess *xorm.Session, bean interface{}, column string, countBean interface{}, countColumn, cond string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	table := orm.Quote(orm.TableInfo(bean).Name)
+	countTable := orm.Quote(orm.TableInfo(countBean).Name)
+
+	args := make([]interface{}, 0, len(ids)+1)
+	where := fmt.Sprintf("%s.%s = %s.%s", countTable, orm.Quote(countColumn), table, orm.Quote("id"))
+	if cond != "" {
+		where += " AND " + cond
+		args = append(args, true)
+	}
+	marks := make([]string, len(ids))
+	for i, id := range ids {
+		marks[i] = "?"
+		args = append(args, id)
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET %s = (SELECT COUNT(*) FROM %s WHERE %s) WHERE %s IN (%s)",
+		table, orm.Quote(column), countTable, where, orm.Quote("id"), strings.Join(marks, ","))
+	_, err := sess.Exec(sql, args...)
+	return err
+}
+
+// deleteUserMails removes email from queued mails, mails left without any
+// recipient are deleted
+func deleteUserMails(sess *xorm.Session, email string) error {
+	if email == "" {
+		return nil
+	}
+	var mails []*MailQueue
+	like := "%" + email + "%"
+	if err := sess.Where("recipients LIKE ? OR cc_addrs LIKE ? OR bcc_addrs LIKE ?", like, like, like).Find(&mails); err != nil {
+		return err
+	}
+
+	for _, m := range mails {
+		m.Recipients = removeEmail(m.Recipients, email)
+		m.CcAddrs = removeEmail(m.CcAddrs, email)
+		m.BccAddrs = removeEmail(m.BccAddrs, email)
+		if m.Recipients == "" && m.CcAddrs == "" && m.BccAddrs == "" {
+			if _, err := sess.Id(m.Id).Delete(new(MailQueue)); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := sess.Id(m.Id).Cols("recipients", "cc_addrs", "bcc_addrs").Update(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeEmail drops email from a comma separated list
+func removeEmail(list, email string) string {
+	emails := make([]string, 0)
+	for _, e := range splitEmails(list) {
+		if !strings.EqualFold(strings.TrimSpace(e), email) {
+			emails = append(emails, e)
+		}
+	}
+	return strings.Join(emails, ",")
+}
@@ -0,0 +1,84 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// login of a user on a browser, the session and remember cookie keep the
+// token of which only the sha256 is saved. Deleting the record logs the
+// browser out.
+type UserSession struct {
+	Id        int64
+	UserId    int64     `xorm:"index"`
+	TokenHash string    `xorm:"varchar(64) unique"`
+	Device    string    `xorm:"varchar(50)"`
+	Ip        string    `xorm:"varchar(45)"`
+	UserAgent string    `xorm:"varchar(255)"`
+	LastSeen  time.Time `xorm:"index"`
+	Created   time.Time `xorm:"created"`
+	Remember  bool
+}
+
+func GetUserSessionByHash(hash string) (*UserSession, error) {
+	var us = UserSession{TokenHash: hash}
+	has, err := orm.Get(&us)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrNotExist
+	}
+	return &us, nil
+}
+
+func FindUserSessions(userId int64) ([]UserSession, error) {
+	var sessions = make([]UserSession, 0)
+	err := orm.Desc("last_seen").Find(&sessions, &UserSession{UserId: userId})
+	return sessions, err
+}
+
+func UpdateUserSessionSeen(us *UserSession, ip string) error {
+	us.LastSeen = time.Now()
+	us.Ip = ip
+	_, err := orm.Id(us.Id).Cols("last_seen", "ip").Update(us)
+	return err
+}
+
+func DeleteUserSession(userId, id int64) error {
+	_, err := orm.Where("id = ?", id).Delete(&UserSession{UserId: userId})
+	return err
+}
+
+func DeleteUserSessionByHash(hash string) error {
+	_, err := orm.Delete(&UserSession{TokenHash: hash})
+	return err
+}
+
+// DeleteUserSessionsSeenBefore deletes the logins of which the session or
+// remember cookie has expired
+func DeleteUserSessionsSeenBefore(sessionBefore, rememberBefore time.Time) error {
+	_, err := orm.Where("remember = ? AND last_seen < ?", false, sessionBefore).
+		Or("remember = ? AND last_seen < ?", true, rememberBefore).
+		Delete(new(UserSession))
+	return err
+}
+
+// DeleteUserSessions logs user out of all browsers
+func DeleteUserSessions(userId int64) error {
+	_, err := orm.Delete(&UserSession{UserId: userId})
+	return err
+}
@@ -0,0 +1,56 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/export"
+	"github.com/go-tango/wego/modules/search"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/setting"
+)
+
+// DeleteAccount gives the content of user to the ghost user and deletes
+// the user with its personal data and exports
+func DeleteAccount(user *models.User) error {
+	ghost, err := models.GetGhostUser()
+	if err != nil {
+		return err
+	}
+
+	export.RemoveUser(user.Id)
+	if err := models.DeleteUser(user, ghost); err != nil {
+		return err
+	}
+	search.ReassignUser(user.Id, ghost.Id)
+	return nil
+}
+
+func deleteCodeData(user *models.User) string {
+	return "delete" + utils.ToStr(user.Id) + user.Email + user.UserName + user.Password + user.Rands
+}
+
+// create a time limit code confirming deletion of the account
+func CreateUserDeleteCode(user *models.User, startInf interface{}) string {
+	return utils.CreateTimeLimitCode(deleteCodeData(user), setting.ActiveCodeLives, startInf)
+}
+
+// verify the code of deletion mail of the logined user
+func VerifyUserDeleteCode(user *models.User, code string) bool {
+	if len(code) != utils.TimeLimitCodeLength {
+		return false
+	}
+	return utils.VerifyTimeLimitCode(deleteCodeData(user), setting.ActiveCodeLives, code)
+}
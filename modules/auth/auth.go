@@ -26,11 +26,11 @@ import (
 	"strings"
 
 	"github.com/Unknwon/i18n"
-	"github.com/lunny/log"
 	"github.com/lunny/tango"
 	"github.com/tango-contrib/session"
 
 	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/password"
 	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/setting"
 
@@ -39,6 +39,12 @@ import (
 
 // CanRegistered checks if the username or e-mail is available.
 func CanRegistered(userName string, email string) (bool, bool, error) {
+	// kept for the ghost user owning content of deleted users
+	if strings.EqualFold(userName, setting.GhostUserName) {
+		_, e2, err := CanRegistered("", email)
+		return false, e2, err
+	}
+
 	var user models.User
 	has, err := models.ORM().Where("user_name = ?", userName).Or("email = ?", email).Get(&user)
 	if err != nil {
@@ -69,15 +75,14 @@ func HasUser(user *models.User, username string) bool {
 
 // register create user
 func RegisterUser(user *models.User, username, email, password string, locale i18n.Locale) error {
-	// use random salt encode password
-	salt := models.GetUserSalt()
-	pwd := utils.EncodePassword(password, salt)
+	pwd, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
 
 	user.UserName = strings.ToLower(username)
 	user.Email = strings.ToLower(email)
-
-	// save salt and encode password, use $ as split char
-	user.Password = fmt.Sprintf("%s$%s", salt, pwd)
+	user.Password = pwd
 
 	// save md5 email value for gravatar
 	user.GrEmail = utils.EncodeMd5(user.Email)
@@ -99,8 +104,11 @@ func RegisterUser(user *models.User, username, email, password string, locale i1
 
 // set a new password to user
 func SaveNewPassword(user *models.User, password string) error {
-	salt := models.GetUserSalt()
-	user.Password = fmt.Sprintf("%s$%s", salt, utils.EncodePassword(password, salt))
+	pwd, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.Password = pwd
 	return models.UpdateById(user.Id, user, "password", "rands", "updated")
 }
 
@@ -129,17 +137,19 @@ func LoginUser(user *models.User, ctx *tango.Context, session *session.Session,
 	fmt.Println("user:", *user)
 
 	session.Set("auth_user_id", user.Id)
+	token := startUserSession(user, ctx, session, remember)
 
 	if remember {
-		WriteRememberCookie(user, ctx)
+		WriteRememberCookie(user, ctx, token)
 	}
 }
 
-func WriteRememberCookie(user *models.User, ctx *tango.Context) {
+// the remember cookie is "username:token", token of the login record
+func WriteRememberCookie(user *models.User, ctx *tango.Context, token string) {
 	secret := utils.EncodeMd5(user.Rands + user.Password)
 	days := 86400 * setting.LoginRememberDays
 	SetCookie(ctx, setting.CookieUserName, user.UserName, days)
-	SetSecureCookie(ctx, secret, setting.CookieRememberName, user.UserName, days)
+	SetSecureCookie(ctx, secret, setting.CookieRememberName, user.UserName+":"+token, days)
 }
 
 func DeleteRememberCookie(ctx *tango.Context) {
@@ -159,26 +169,38 @@ func LoginUserFromRememberCookie(user *models.User, ctx *tango.Context, session
 		}
 	}()
 
-	var err error
-	if user, err = models.GetUserByName(userName); err != nil {
+	u, err := models.GetUserByName(userName)
+	if err != nil {
 		return false
 	}
 
-	secret := utils.EncodeMd5(user.Rands + user.Password)
+	secret := utils.EncodeMd5(u.Rands + u.Password)
 	value, _ := GetSecureCookie(ctx.Req(), secret, setting.CookieRememberName)
-	if value != userName {
+	name, token := value, ""
+	if i := strings.IndexByte(value, ':'); i >= 0 {
+		name, token = value[:i], value[i+1:]
+	}
+	if name != userName {
 		return false
 	}
 
-	LoginUser(user, ctx, session, true)
+	if token == "" {
+		// cookies written before logins were recorded start a record
+		LoginUser(u, ctx, session, true)
+	} else if !resumeUserSession(u, token, ctx, session) {
+		return false
+	}
 
+	*user = *u
 	return true
 }
 
 // logout user
 func LogoutUser(ctx *tango.Context, sess *session.Session) {
 	DeleteRememberCookie(ctx)
+	endUserSession(sess)
 	sess.Del("auth_user_id")
+	ClearPendingLogin(sess)
 }
 
 func GetUserIdFromSession(sess *session.Session) int64 {
@@ -208,37 +230,24 @@ func VerifyUser(user *models.User, username, password string) (success bool) {
 		return
 	}
 
+	// the source of the user checks its password
+	if user.LoginSource != "" {
+		return
+	}
+
 	if VerifyPassword(password, user.Password) {
 		// success
 		success = true
 
-		// re-save discuz password
-		if len(user.Password) == 39 {
-			if err := SaveNewPassword(user, password); err != nil {
-				log.Error("SaveNewPassword err: ", err.Error())
-			}
-		}
+		// re-save discuz, pbkdf2 and outdated hashes
+		rehashPassword(user, password)
 	}
 	return
 }
 
 // compare raw password and encoded password
 func VerifyPassword(rawPwd, encodedPwd string) bool {
-	// for discuz accounts
-	if len(encodedPwd) == 39 {
-		salt := encodedPwd[:6]
-		encoded := encodedPwd[7:]
-		return encoded == utils.EncodeMd5(utils.EncodeMd5(rawPwd)+salt)
-	}
-
-	// split
-	var salt, encoded string
-	if len(encodedPwd) > 11 {
-		salt = encodedPwd[:10]
-		encoded = encodedPwd[11:]
-	}
-
-	return utils.EncodePassword(rawPwd, salt) == encoded
+	return password.Verify(rawPwd, encodedPwd)
 }
 
 // get user by erify code
@@ -29,8 +29,9 @@ import (
 type RegisterForm struct {
 	UserName   string      `valid:"Required;AlphaDash;MinSize(3);MaxSize(30)"`
 	Email      string      `valid:"Required;Email;MaxSize(80)"`
-	Password   string      `form:"type(password)" valid:"Required;MinSize(4);MaxSize(30)"`
-	PasswordRe string      `form:"type(password)" valid:"Required;MinSize(4);MaxSize(30)"`
+	Password   string      `form:"type(password)" valid:"Required"`
+	PasswordRe string      `form:"type(password)" valid:"Required"`
+	InviteCode string      `valid:"MaxSize(32)"`
 	Captcha    string      `form:"type(captcha)" valid:"Required"`
 	CaptchaId  string      `form:"type(empty)"`
 	Locale     i18n.Locale `form:"-"`
@@ -44,6 +45,8 @@ func (form *RegisterForm) Valid(v *validation.Validation) {
 		return
 	}
 
+	validPasswordPolicy(v, "Password", form.Password)
+
 	e1, e2, _ := CanRegistered(form.UserName, form.Email)
 
 	if !e1 {
@@ -54,6 +57,8 @@ func (form *RegisterForm) Valid(v *validation.Validation) {
 		v.SetError("Email", "auth.email_already_taken")
 	}
 
+	validRegisterMode(v, form.Email, form.InviteCode)
+
 	if !setting.Captcha.Verify(form.CaptchaId, form.Captcha) {
 		v.SetError("Captcha", "auth.captcha_wrong")
 	}
@@ -65,14 +70,16 @@ func (form *RegisterForm) Labels() map[string]string {
 		"Email":      "auth.login_email",
 		"Password":   "auth.login_password",
 		"PasswordRe": "auth.retype_password",
+		"InviteCode": "auth.invite_code",
 		"Captcha":    "auth.captcha",
 	}
 }
 
 func (form *RegisterForm) Helps() map[string]string {
 	return map[string]string{
-		"UserName": form.Locale.Tr("valid.min_length_is", 3) + ", " + form.Locale.Tr("valid.only_contains", "a-z 0-9 - _"),
-		"Captcha":  "auth.captcha_click_refresh",
+		"UserName":   form.Locale.Tr("valid.min_length_is", 3) + ", " + form.Locale.Tr("valid.only_contains", "a-z 0-9 - _"),
+		"InviteCode": "auth.invite_code_help",
+		"Captcha":    "auth.captcha_click_refresh",
 	}
 }
 
@@ -117,8 +124,8 @@ func (form *ForgotForm) Valid(v *validation.Validation) {
 
 // Reset password form
 type ResetPwdForm struct {
-	Password   string `form:"type(password)" valid:"Required;MinSize(4);MaxSize(30)"`
-	PasswordRe string `form:"type(password)" valid:"Required;MinSize(4);MaxSize(30)"`
+	Password   string `form:"type(password)" valid:"Required"`
+	PasswordRe string `form:"type(password)" valid:"Required"`
 }
 
 func (form *ResetPwdForm) Valid(v *validation.Validation) {
@@ -127,6 +134,8 @@ func (form *ResetPwdForm) Valid(v *validation.Validation) {
 		v.SetError("PasswordRe", "auth.repassword_not_match")
 		return
 	}
+
+	validPasswordPolicy(v, "Password", form.Password)
 }
 
 func (form *ResetPwdForm) Labels() map[string]string {
@@ -232,8 +241,8 @@ func (form *ProfileForm) Placeholders() map[string]string {
 // Change password form
 type PasswordForm struct {
 	PasswordOld string       `form:"type(password)" valid:"Required"`
-	Password    string       `form:"type(password)" valid:"Required;MinSize(4);MaxSize(30)"`
-	PasswordRe  string       `form:"type(password)" valid:"Required;MinSize(4);MaxSize(30)"`
+	Password    string       `form:"type(password)" valid:"Required"`
+	PasswordRe  string       `form:"type(password)" valid:"Required"`
 	User        *models.User `form:"-"`
 }
 
@@ -247,6 +256,8 @@ func (form *PasswordForm) Valid(v *validation.Validation) {
 	if VerifyPassword(form.PasswordOld, form.User.Password) == false {
 		v.SetError("PasswordOld", "auth.old_password_wrong")
 	}
+
+	validPasswordPolicy(v, "Password", form.Password)
 }
 
 func (form *PasswordForm) Labels() map[string]string {
@@ -294,6 +305,181 @@ func (form *UserAvatarForm) SetFromUser(user *models.User) {
 	utils.SetFormValues(user, form)
 }
 
+// Personal access token form
+type AccessTokenForm struct {
+	Name  string       `valid:"Required;MaxSize(50)"`
+	Read  bool         `valid:""`
+	Write bool         `valid:""`
+	Admin bool         `valid:""`
+	User  *models.User `form:"-"`
+}
+
+func (form *AccessTokenForm) Valid(v *validation.Validation) {
+	if !form.Read && !form.Write && !form.Admin {
+		v.SetError("Read", "auth.token_scope_required")
+	}
+
+	if form.Admin && !form.User.IsAdmin {
+		v.SetError("Admin", "auth.token_scope_admin_denied")
+	}
+}
+
+// Scopes returns the checked scope names
+func (form *AccessTokenForm) Scopes() []string {
+	var scopes []string
+	if form.Read {
+		scopes = append(scopes, models.SCOPE_READ)
+	}
+	if form.Write {
+		scopes = append(scopes, models.SCOPE_WRITE)
+	}
+	if form.Admin {
+		scopes = append(scopes, models.SCOPE_ADMIN)
+	}
+	return scopes
+}
+
+func (form *AccessTokenForm) Labels() map[string]string {
+	return map[string]string{
+		"Name":  "auth.token_name",
+		"Read":  "auth.token_scope_read",
+		"Write": "auth.token_scope_write",
+		"Admin": "auth.token_scope_admin",
+	}
+}
+
+func (form *AccessTokenForm) Placeholders() map[string]string {
+	return map[string]string{
+		"Name": "auth.plz_enter_token_name",
+	}
+}
+
+// code of the authenticator app or a recovery code
+type TwoFactorForm struct {
+	Code string `form:"attr(autocomplete,off)" valid:"Required;MaxSize(20)"`
+}
+
+func (form *TwoFactorForm) Labels() map[string]string {
+	return map[string]string{
+		"Code": "auth.two_factor_code",
+	}
+}
+
+func (form *TwoFactorForm) Placeholders() map[string]string {
+	return map[string]string{
+		"Code": "auth.plz_enter_two_factor_code",
+	}
+}
+
+// password confirms disabling two-factor authentication or new recovery codes
+type TwoFactorConfirmForm struct {
+	Password string       `form:"type(password)" valid:"Required"`
+	User     *models.User `form:"-"`
+}
+
+func (form *TwoFactorConfirmForm) Valid(v *validation.Validation) {
+	if CheckPassword(form.User, form.Password) == false {
+		v.SetError("Password", "auth.password_wrong")
+	}
+}
+
+func (form *TwoFactorConfirmForm) Labels() map[string]string {
+	return map[string]string{
+		"Password": "auth.login_password",
+	}
+}
+
+// password confirms deleting the account
+type DeleteAccountForm struct {
+	Password string       `form:"type(password)" valid:"Required"`
+	User     *models.User `form:"-"`
+}
+
+func (form *DeleteAccountForm) Valid(v *validation.Validation) {
+	if CheckPassword(form.User, form.Password) == false {
+		v.SetError("Password", "auth.password_wrong")
+	}
+}
+
+func (form *DeleteAccountForm) Labels() map[string]string {
+	return map[string]string{
+		"Password": "auth.login_password",
+	}
+}
+
+// Notification mail preferences form
+type NoticeMailForm struct {
+	Comment  int `form:"type(select);attr(rel,select2)" valid:"Range(0,3)"`
+	Mention  int `form:"type(select);attr(rel,select2)" valid:"Range(0,3)"`
+	Follow   int `form:"type(select);attr(rel,select2)" valid:"Range(0,3)"`
+	Favorite int `form:"type(select);attr(rel,select2)" valid:"Range(0,3)"`
+}
+
+func mailModeSelectData() [][]string {
+	names := map[int]string{
+		models.MAIL_INSTANT: "auth.mail_mode_instant",
+		models.MAIL_DAILY:   "auth.mail_mode_daily",
+		models.MAIL_WEEKLY:  "auth.mail_mode_weekly",
+		models.MAIL_OFF:     "auth.mail_mode_off",
+	}
+	data := make([][]string, 0, len(models.MailModes))
+	for _, mode := range models.MailModes {
+		data = append(data, []string{names[mode], utils.ToStr(mode)})
+	}
+	return data
+}
+
+func (form *NoticeMailForm) CommentSelectData() [][]string {
+	return mailModeSelectData()
+}
+
+func (form *NoticeMailForm) MentionSelectData() [][]string {
+	return mailModeSelectData()
+}
+
+func (form *NoticeMailForm) FollowSelectData() [][]string {
+	return mailModeSelectData()
+}
+
+func (form *NoticeMailForm) FavoriteSelectData() [][]string {
+	return mailModeSelectData()
+}
+
+func (form *NoticeMailForm) Labels() map[string]string {
+	return map[string]string{
+		"Comment":  "auth.mail_event_comment",
+		"Mention":  "auth.mail_event_mention",
+		"Follow":   "auth.mail_event_follow",
+		"Favorite": "auth.mail_event_favorite",
+	}
+}
+
+func (form *NoticeMailForm) fields() map[int]*int {
+	return map[int]*int{
+		models.MAIL_EVENT_COMMENT:  &form.Comment,
+		models.MAIL_EVENT_MENTION:  &form.Mention,
+		models.MAIL_EVENT_FOLLOW:   &form.Follow,
+		models.MAIL_EVENT_FAVORITE: &form.Favorite,
+	}
+}
+
+func (form *NoticeMailForm) SetFromUser(user *models.User) error {
+	modes, err := models.GetMailModes(user.Id)
+	for event, field := range form.fields() {
+		*field = modes[event]
+	}
+	return err
+}
+
+func (form *NoticeMailForm) SaveUserModes(user *models.User) error {
+	for event, field := range form.fields() {
+		if err := models.SetMailMode(user.Id, event, *field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //User admin form
 type UserAdminForm struct {
 	Create      bool   `form:"-"`
@@ -315,12 +501,20 @@ type UserAdminForm struct {
 	Facebook    string `valid:"MaxSize(30)"`
 	Followers   int    ``
 	Following   int    ``
-	IsAdmin     bool   ``
+	Role        int    `form:"type(select);attr(rel,select2)" valid:""`
 	IsActive    bool   ``
 	IsForbid    bool   ``
 	Lang        int    `form:"type(select);attr(rel,select2)" valid:""`
 }
 
+func (form *UserAdminForm) RoleSelectData() [][]string {
+	data := make([][]string, 0, len(models.Roles))
+	for _, role := range models.Roles {
+		data = append(data, []string{"model.role_" + models.RoleName(role), utils.ToStr(role)})
+	}
+	return data
+}
+
 func (form *UserAdminForm) LangSelectData() [][]string {
 	langs := setting.Langs
 	data := make([][]string, 0, len(langs))
@@ -342,18 +536,27 @@ func (form *UserAdminForm) Valid(v *validation.Validation) {
 	if len(i18n.GetLangByIndex(form.Lang)) == 0 {
 		v.SetError("Lang", "Can not be empty")
 	}
+
+	if models.RoleName(form.Role) == "" {
+		v.SetError("Role", "Not Found")
+	}
 }
 
 func (form *UserAdminForm) Helps() map[string]string {
-	return nil
+	return map[string]string{
+		"Role": "model.role_help",
+	}
 }
 
 func (form *UserAdminForm) Labels() map[string]string {
-	return nil
+	return map[string]string{
+		"Role": "model.user_role",
+	}
 }
 
 func (form *UserAdminForm) SetFromUser(user *models.User) {
 	utils.SetFormValues(user, form)
+	form.Role = user.GetRole()
 }
 
 func (form *UserAdminForm) SetToUser(user *models.User) {
@@ -363,4 +566,5 @@ func (form *UserAdminForm) SetToUser(user *models.User) {
 	}
 
 	utils.SetFormValues(form, user)
+	user.SetRole(form.Role)
 }
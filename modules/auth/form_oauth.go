@@ -23,8 +23,9 @@ import (
 type OAuthRegisterForm struct {
 	UserName   string      `valid:"Required;AlphaDash;MinSize(5);MaxSize(30)"`
 	Email      string      `valid:"Required;Email;MaxSize(80)"`
-	Password   string      `form:"type(password)" valid:"Required;MinSize(4);MaxSize(30)"`
-	PasswordRe string      `form:"type(password)" valid:"Required;MinSize(4);MaxSize(30)"`
+	Password   string      `form:"type(password)" valid:"Required"`
+	PasswordRe string      `form:"type(password)" valid:"Required"`
+	InviteCode string      `valid:"MaxSize(32)"`
 	Locale     i18n.Locale `form:"-"`
 }
 
@@ -36,6 +37,8 @@ func (form *OAuthRegisterForm) Valid(v *validation.Validation) {
 		return
 	}
 
+	validPasswordPolicy(v, "Password", form.Password)
+
 	e1, e2, _ := CanRegistered(form.UserName, form.Email)
 
 	if !e1 {
@@ -45,6 +48,8 @@ func (form *OAuthRegisterForm) Valid(v *validation.Validation) {
 	if !e2 {
 		v.SetError("Email", "auth.email_already_taken")
 	}
+
+	validRegisterMode(v, form.Email, form.InviteCode)
 }
 
 func (form *OAuthRegisterForm) Labels() map[string]string {
@@ -53,12 +58,14 @@ func (form *OAuthRegisterForm) Labels() map[string]string {
 		"Email":      "auth.login_email",
 		"Password":   "auth.login_password",
 		"PasswordRe": "auth.retype_password",
+		"InviteCode": "auth.invite_code",
 	}
 }
 
 func (form *OAuthRegisterForm) Helps() map[string]string {
 	return map[string]string{
-		"UserName": form.Locale.Tr("valid.min_length_is", 5) + ", " + form.Locale.Tr("valid.only_contains", "a-z 0-9 - _"),
+		"UserName":   form.Locale.Tr("valid.min_length_is", 5) + ", " + form.Locale.Tr("valid.only_contains", "a-z 0-9 - _"),
+		"InviteCode": "auth.invite_code_help",
 	}
 }
 
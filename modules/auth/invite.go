@@ -0,0 +1,127 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/Unknwon/i18n"
+	"github.com/go-xweb/xweb/validation"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
+)
+
+// RegisterClosed reports whether nobody can sign up, local accounts are
+// also closed when only login sources check passwords
+func RegisterClosed() bool {
+	return setting.RegisterMode == setting.REGISTER_CLOSED || setting.LocalLoginDisabled
+}
+
+// NeedInvite reports whether signing up needs an invite code
+func NeedInvite() bool {
+	return setting.RegisterMode == setting.REGISTER_INVITE
+}
+
+// EmailDomainAllowed checks the domain of email in domain mode, any email
+// is allowed in other modes
+func EmailDomainAllowed(email string) bool {
+	if setting.RegisterMode != setting.REGISTER_DOMAIN {
+		return true
+	}
+	i := strings.LastIndex(email, "@")
+	if i == -1 {
+		return false
+	}
+	domain := strings.ToLower(email[i+1:])
+	for _, d := range setting.RegisterDomains {
+		if domain == d {
+			return true
+		}
+	}
+	return false
+}
+
+// validRegisterMode checks the sign up forms against the registration mode
+func validRegisterMode(v *validation.Validation, email, code string) {
+	if RegisterClosed() {
+		v.SetError("UserName", "auth.register_closed")
+		return
+	}
+
+	if !EmailDomainAllowed(email) {
+		v.SetError("Email", "auth.email_domain_not_allowed")
+	}
+
+	if NeedInvite() {
+		if invite, err := models.GetInviteByCode(strings.TrimSpace(code)); err != nil || !invite.IsValid() {
+			v.SetError("InviteCode", "auth.invite_code_invalid")
+		}
+	}
+}
+
+// CreateInvite creates an invite code of user, 0 uses or days is no limit.
+// Codes of members are limited by the settings.
+func CreateInvite(user *models.User, maxUses, days int) (*models.Invite, error) {
+	if !user.IsAdmin {
+		if maxUses <= 0 || maxUses > setting.InviteMaxUses {
+			maxUses = setting.InviteMaxUses
+		}
+		if days <= 0 || days > setting.InviteLives {
+			days = setting.InviteLives
+		}
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	invite := models.Invite{
+		Code:    hex.EncodeToString(b),
+		UserId:  user.Id,
+		MaxUses: maxUses,
+	}
+	if days > 0 {
+		invite.Expires = time.Now().AddDate(0, 0, days)
+	}
+	if err := models.Insert(&invite); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// RegisterInvitedUser registers user like RegisterUser, the invite code is
+// used and its creator recorded when the registration mode needs one
+func RegisterInvitedUser(user *models.User, username, email, password, code string, locale i18n.Locale) error {
+	if !NeedInvite() {
+		return RegisterUser(user, username, email, password, locale)
+	}
+
+	invite, err := models.UseInvite(strings.TrimSpace(code))
+	if err != nil {
+		return err
+	}
+
+	user.InvitedBy = invite.UserId
+	if err := RegisterUser(user, username, email, password, locale); err != nil {
+		models.ReleaseInvite(invite)
+		return err
+	}
+	return nil
+}
@@ -76,3 +76,20 @@ func SendActiveMail(locale i18n.Locale, user *models.User) {
 	// async send mail
 	mailer.SendAsync(msg)
 }
+
+// Send mail with the link confirming deletion of the account
+func SendDeleteAccountMail(locale i18n.Locale, user *models.User) {
+	code := CreateUserDeleteCode(user, nil)
+
+	subject := locale.Tr("mail.delete_account_subject")
+
+	data := mailer.GetMailTmplData(locale.Lang, user)
+	data["Code"] = code
+	body := utils.RenderTemplate("mail/auth/delete_account.html", data)
+
+	msg := mailer.NewMailMessage([]string{user.Email}, subject, body)
+	msg.Info = fmt.Sprintf("UID: %d, send delete account mail", user.Id)
+
+	// async send mail
+	mailer.SendAsync(msg)
+}
@@ -0,0 +1,115 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"sync"
+
+	"github.com/go-xweb/xweb/validation"
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/password"
+	"github.com/go-tango/wego/setting"
+)
+
+// hash config of [password] in app.ini
+func hashConfig() password.Config {
+	return password.Config{
+		Algorithm:     setting.PasswordAlgorithm,
+		BcryptCost:    setting.PasswordBcryptCost,
+		ScryptN:       setting.PasswordScryptN,
+		ScryptR:       setting.PasswordScryptR,
+		ScryptP:       setting.PasswordScryptP,
+		Argon2Time:    setting.PasswordArgon2Time,
+		Argon2Memory:  setting.PasswordArgon2Memory,
+		Argon2Threads: setting.PasswordArgon2Threads,
+	}
+}
+
+// HashPassword hashes a new password with the configured algorithm
+func HashPassword(rawPwd string) (string, error) {
+	c := hashConfig()
+	return c.Hash(rawPwd)
+}
+
+// NeedsRehash reports whether the hash is of an old format or config
+func NeedsRehash(encodedPwd string) bool {
+	c := hashConfig()
+	return c.NeedsRehash(encodedPwd)
+}
+
+// the breached list is loaded again when the file of config changes
+var breached struct {
+	lock sync.Mutex
+	file string
+	list password.Breached
+}
+
+func breachedPasswords() password.Breached {
+	breached.lock.Lock()
+	defer breached.lock.Unlock()
+
+	file := setting.PasswordBreachedFile
+	if file != breached.file {
+		breached.file = file
+		breached.list = nil
+		if file != "" {
+			list, err := password.LoadBreachedFile(file)
+			if err != nil {
+				log.Error("load breached passwords:", err)
+			}
+			breached.list = list
+		}
+	}
+	return breached.list
+}
+
+func passwordPolicy() *password.Policy {
+	p := &password.Policy{
+		MinLength: setting.PasswordMinLength,
+		MaxLength: setting.PasswordMaxLength,
+		Breached:  breachedPasswords(),
+	}
+	if setting.PasswordAlgorithm == password.BCRYPT {
+		p.MaxBytes = password.BcryptMaxBytes
+	}
+	return p
+}
+
+// validPasswordPolicy sets the error of field if a new password does not
+// meet the policy
+func validPasswordPolicy(v *validation.Validation, field, rawPwd string) {
+	switch passwordPolicy().Check(rawPwd) {
+	case password.ErrTooShort:
+		v.SetError(field, "auth.password_too_short").LimitValue = setting.PasswordMinLength
+	case password.ErrTooLong:
+		v.SetError(field, "auth.password_too_long").LimitValue = setting.PasswordMaxLength
+	case password.ErrTooBig:
+		v.SetError(field, "auth.password_too_big").LimitValue = password.BcryptMaxBytes
+	case password.ErrBreached:
+		v.SetError(field, "auth.password_breached")
+	}
+}
+
+// rehash saves password with the configured algorithm after login
+func rehashPassword(user *models.User, rawPwd string) {
+	if !NeedsRehash(user.Password) {
+		return
+	}
+	if err := SaveNewPassword(user, rawPwd); err != nil {
+		log.Error("rehash password:", err)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/ldap"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/setting"
+)
+
+// ErrUnknownUser is returned by sources not having the login
+var ErrUnknownUser = errors.New("auth: unknown user of source")
+
+// SourceUser is a user authenticated by a source
+type SourceUser struct {
+	UserName string
+	Email    string
+	NickName string
+	IsAdmin  bool
+	// IsAdmin is decided by the source
+	SyncAdmin bool
+}
+
+// Source is a directory checking passwords of its users instead of the
+// local password. Users logined by a source are saved with its name in
+// User.LoginSource, and created on their first login.
+type Source interface {
+	Name() string
+	// returns ErrUnknownUser when login is not in the source
+	Authenticate(login, password string) (*SourceUser, error)
+}
+
+var sources []Source
+
+// RegisterSource adds a source, sources are asked in order
+func RegisterSource(s Source) {
+	sources = append(sources, s)
+}
+
+// InitSources registers the sources of app.ini
+func InitSources() {
+	if setting.LDAP != nil {
+		RegisterSource(ldapSource{setting.LDAP})
+	}
+}
+
+func getSource(name string) Source {
+	for _, s := range sources {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+type ldapSource struct {
+	*ldap.Config
+}
+
+func (s ldapSource) Name() string {
+	return "ldap"
+}
+
+func (s ldapSource) Authenticate(login, password string) (*SourceUser, error) {
+	user, err := s.Config.Authenticate(login, password)
+	if err == ldap.ErrNotFound {
+		return nil, ErrUnknownUser
+	} else if err != nil {
+		return nil, err
+	}
+	return &SourceUser{
+		UserName:  user.UserName,
+		Email:     user.Email,
+		NickName:  user.NickName,
+		IsAdmin:   user.IsAdmin,
+		SyncAdmin: s.AdminGroup != "",
+	}, nil
+}
+
+// Authenticate verifies login and password with the sources, then with
+// the local password unless local login is disabled
+func Authenticate(user *models.User, login, password string) bool {
+	for _, s := range sources {
+		su, err := s.Authenticate(login, password)
+		if err == ErrUnknownUser {
+			continue
+		}
+		if err == ldap.ErrBadPassword {
+			return false
+		}
+		if err != nil {
+			// local users can still login when the directory is down
+			log.Error("auth: source", s.Name(), err)
+			continue
+		}
+
+		if err := loginSourceUser(user, s.Name(), su); err != nil {
+			log.Error("auth: source", s.Name(), err)
+			return false
+		}
+		return true
+	}
+
+	if setting.LocalLoginDisabled {
+		return false
+	}
+	return VerifyUser(user, login, password)
+}
+
+// CheckPassword verifies the password of a logined user, with the source
+// of the user when it has one
+func CheckPassword(user *models.User, password string) bool {
+	if user.LoginSource == "" {
+		return VerifyPassword(password, user.Password)
+	}
+	s := getSource(user.LoginSource)
+	if s == nil {
+		return false
+	}
+	su, err := s.Authenticate(user.UserName, password)
+	return err == nil && strings.ToLower(su.UserName) == user.UserName
+}
+
+// loginSourceUser gets the user of su, created on first login. Local users
+// having the same username or email are not taken over.
+func loginSourceUser(user *models.User, source string, su *SourceUser) error {
+	userName := strings.ToLower(su.UserName)
+	u, err := models.GetUserByName(userName)
+	if err == models.ErrNotExist {
+		return provisionUser(user, source, su)
+	} else if err != nil {
+		return err
+	}
+
+	if u.LoginSource != source {
+		return fmt.Errorf("user %s exists and is not of source %s", userName, source)
+	}
+	*user = *u
+
+	if su.SyncAdmin && su.IsAdmin != user.IsAdmin {
+		if su.IsAdmin {
+			user.SetRole(models.ROLE_ADMIN)
+		} else {
+			user.SetRole(models.ROLE_MEMBER)
+		}
+		log.Info("auth: source", source, "sets admin of", user.UserName, "to", user.IsAdmin)
+		return models.UpdateById(user.Id, user, "is_admin", "role", "updated")
+	}
+	return nil
+}
+
+// provisionUser creates the user of su, active as the source owns the email
+func provisionUser(user *models.User, source string, su *SourceUser) error {
+	if su.Email == "" {
+		return fmt.Errorf("user %s has no email", su.UserName)
+	}
+	email := strings.ToLower(su.Email)
+	if has, err := models.IsUserExistByEmail(email, 0); err != nil {
+		return err
+	} else if has {
+		return fmt.Errorf("email %s of %s is used", email, su.UserName)
+	}
+
+	user.UserName = strings.ToLower(su.UserName)
+	user.Email = email
+	user.GrEmail = utils.EncodeMd5(user.Email)
+	user.LoginSource = source
+	user.IsActive = true
+	user.Rands = models.GetUserSalt()
+
+	user.NickName = user.UserName
+	if nick := su.NickName; nick != "" && len([]rune(nick)) <= 30 {
+		if has, err := models.ORM().Get(&models.User{NickName: nick}); err == nil && !has {
+			user.NickName = nick
+		}
+	}
+
+	user.Lang = setting.LangEnUS
+	user.AvatarType = setting.AvatarTypeGravatar
+	if su.SyncAdmin && su.IsAdmin {
+		user.SetRole(models.ROLE_ADMIN)
+	}
+
+	if err := models.Insert(user); err != nil {
+		return err
+	}
+	log.Info("auth: source", source, "created user", user.UserName)
+	return nil
+}
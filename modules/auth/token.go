@@ -0,0 +1,82 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/utils"
+)
+
+// BearerToken returns the access token in Authorization header
+func BearerToken(req *http.Request) string {
+	value := req.Header.Get("Authorization")
+	if len(value) > 7 && strings.EqualFold(value[:7], "Bearer ") {
+		return strings.TrimSpace(value[7:])
+	}
+	return ""
+}
+
+func hashAccessToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAccessToken saves a new token for user, the raw token is returned
+// and can not be got again.
+func CreateAccessToken(user *models.User, name string, scopes []string) (string, error) {
+	raw := utils.GetRandomString(40)
+	token := models.AccessToken{
+		UserId:    user.Id,
+		Name:      name,
+		TokenHash: hashAccessToken(raw),
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if err := models.Insert(&token); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// get user by the raw access token
+func LoginUserFromAccessToken(user *models.User, raw string) (*models.AccessToken, bool) {
+	token, err := models.GetAccessTokenByHash(hashAccessToken(raw))
+	if err != nil {
+		return nil, false
+	}
+
+	u, err := models.GetUserById(token.UserId)
+	if err != nil || u.IsForbid {
+		return nil, false
+	}
+	*user = *u
+
+	models.UpdateAccessTokenLastUsed(token)
+	return token, true
+}
+
+// CheckTokenScope checks if the token allows the request method,
+// read scope for safe methods and write scope for others.
+func CheckTokenScope(token *models.AccessToken, method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return token.HasScope(models.SCOPE_READ)
+	}
+	return token.HasScope(models.SCOPE_WRITE)
+}
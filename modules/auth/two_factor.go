@@ -0,0 +1,166 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/lunny/log"
+	"github.com/tango-contrib/session"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/totp"
+	"github.com/go-tango/wego/setting"
+)
+
+const (
+	// recovery codes given when two-factor authentication is enabled
+	RecoveryCodeCount = 10
+
+	// minutes to finish the second step of login
+	pendingLoginMinutes = 5
+)
+
+func HasTwoFactor(user *models.User) bool {
+	return models.HasTwoFactor(user.Id)
+}
+
+// TwoFactorRequired reports whether user must enable two-factor
+// authentication, admins can be required by config
+func TwoFactorRequired(user *models.User) bool {
+	return setting.TwoFactorAdmin && user.IsAdmin
+}
+
+// recovery codes are compared lowercased without spaces and dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRecoveryCodes returns raw codes like "3f2a9-b41c0" and their hashes
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err = rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(b)
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return
+}
+
+// EnableTwoFactor saves secret once passcode of it is verified, the raw
+// recovery codes are returned and can not be got again.
+func EnableTwoFactor(user *models.User, secret, passcode string) ([]string, bool, error) {
+	step, ok := totp.Validate(secret, passcode, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, true, err
+	}
+	tf := models.TwoFactor{UserId: user.Id, Secret: secret, LastStep: step}
+	if err := models.SaveTwoFactor(&tf, hashes); err != nil {
+		return nil, true, err
+	}
+	return codes, true, nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of user
+func RegenerateRecoveryCodes(user *models.User) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := models.ReplaceRecoveryCodes(user.Id, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func DisableTwoFactor(user *models.User) error {
+	return models.DeleteTwoFactor(user.Id)
+}
+
+// VerifyTwoFactor checks a code of the authenticator or a recovery code,
+// both can be used only once
+func VerifyTwoFactor(user *models.User, code string) bool {
+	tf, err := models.GetTwoFactor(user.Id)
+	if err != nil {
+		if err != models.ErrNotExist {
+			log.Error("VerifyTwoFactor: get two factor", err)
+		}
+		return false
+	}
+
+	if step, ok := totp.Validate(tf.Secret, code, time.Now()); ok {
+		used, err := models.UseTwoFactorStep(tf, step)
+		if err != nil {
+			log.Error("VerifyTwoFactor: save step", err)
+		}
+		return used
+	}
+
+	if normalizeRecoveryCode(code) == "" {
+		return false
+	}
+	used, err := models.UseRecoveryCode(user.Id, hashRecoveryCode(code))
+	if err != nil {
+		log.Error("VerifyTwoFactor: use recovery code", err)
+	}
+	if used {
+		log.Info("VerifyTwoFactor: user", user.Id, "used a recovery code")
+	}
+	return used
+}
+
+// SetPendingLogin keeps user of which password is verified in session,
+// the login is finished after the second step
+func SetPendingLogin(sess *session.Session, user *models.User, remember bool) {
+	sess.Set("auth_2fa_user_id", user.Id)
+	sess.Set("auth_2fa_remember", remember)
+	sess.Set("auth_2fa_time", time.Now().Unix())
+}
+
+// GetPendingLogin returns the pending user id, 0 if none or expired
+func GetPendingLogin(sess *session.Session) (int64, bool) {
+	id, _ := sess.Get("auth_2fa_user_id").(int64)
+	remember, _ := sess.Get("auth_2fa_remember").(bool)
+	created, _ := sess.Get("auth_2fa_time").(int64)
+	if id <= 0 || time.Now().Unix()-created > pendingLoginMinutes*60 {
+		return 0, false
+	}
+	return id, remember
+}
+
+func ClearPendingLogin(sess *session.Session) {
+	sess.Del("auth_2fa_user_id")
+	sess.Del("auth_2fa_remember")
+	sess.Del("auth_2fa_time")
+}
@@ -0,0 +1,245 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lunny/log"
+	"github.com/lunny/tango"
+	"github.com/tango-contrib/session"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/setting"
+)
+
+// last seen of a login is saved at most once in these minutes
+const sessionSeenMinutes = 5
+
+// expired logins are deleted in this interval
+const sessionCleanupInterval = time.Hour
+
+// startUserSession records a login of user, the token is kept in session
+func startUserSession(user *models.User, ctx *tango.Context, sess *session.Session, remember bool) string {
+	req := ctx.Req()
+	ua := req.UserAgent()
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+
+	token := utils.GetRandomString(40)
+	us := models.UserSession{
+		UserId:    user.Id,
+		TokenHash: hashAccessToken(token),
+		Device:    deviceName(ua),
+		Ip:        utils.IP(req),
+		UserAgent: ua,
+		Remember:  remember,
+		LastSeen:  time.Now(),
+	}
+	if err := models.Insert(&us); err != nil {
+		log.Error("startUserSession:", err)
+	}
+	sess.Set("auth_session", token)
+	return token
+}
+
+// resumeUserSession logs the user in again with the token of a remember
+// cookie, false if the login is revoked
+func resumeUserSession(user *models.User, token string, ctx *tango.Context, sess *session.Session) bool {
+	us, err := models.GetUserSessionByHash(hashAccessToken(token))
+	if err != nil || us.UserId != user.Id {
+		return false
+	}
+	sess.Set("auth_user_id", user.Id)
+	sess.Set("auth_session", token)
+	seeUserSession(us, ctx)
+	return true
+}
+
+func seeUserSession(us *models.UserSession, ctx *tango.Context) {
+	ip := utils.IP(ctx.Req())
+	if time.Since(us.LastSeen) < sessionSeenMinutes*time.Minute && us.Ip == ip {
+		return
+	}
+	if err := models.UpdateUserSessionSeen(us, ip); err != nil {
+		log.Error("seeUserSession:", err)
+	}
+}
+
+// endUserSession deletes the login record of session
+func endUserSession(sess *session.Session) {
+	if token, _ := sess.Get("auth_session").(string); token != "" {
+		if err := models.DeleteUserSessionByHash(hashAccessToken(token)); err != nil {
+			log.Error("endUserSession:", err)
+		}
+	}
+	sess.Del("auth_session")
+}
+
+// CurrentSessionHash returns the token hash of the login of session
+func CurrentSessionHash(sess *session.Session) string {
+	if token, _ := sess.Get("auth_session").(string); token != "" {
+		return hashAccessToken(token)
+	}
+	return ""
+}
+
+// LoginUserFromSession gets the user of session if the login is not
+// revoked on the sessions page
+func LoginUserFromSession(user *models.User, ctx *tango.Context, sess *session.Session) bool {
+	if !GetUserFromSession(user, sess) {
+		return false
+	}
+
+	token, _ := sess.Get("auth_session").(string)
+	if token == "" {
+		// logined before logins were recorded
+		startUserSession(user, ctx, sess, false)
+		return true
+	}
+
+	us, err := models.GetUserSessionByHash(hashAccessToken(token))
+	if err != nil || us.UserId != user.Id {
+		if err != nil && err != models.ErrNotExist {
+			log.Error("LoginUserFromSession:", err)
+		}
+		sess.Del("auth_user_id")
+		sess.Del("auth_session")
+		*user = models.User{}
+		return false
+	}
+	seeUserSession(us, ctx)
+	return true
+}
+
+// LoginRevoked reports whether the login of user by access token, or by
+// session when token is nil, has been revoked or the user is forbidden
+// since the request started. Errors of database count as not revoked.
+func LoginRevoked(user *models.User, token *models.AccessToken, sess *session.Session) bool {
+	u, err := models.GetUserById(user.Id)
+	if err == nil && u.IsForbid {
+		return true
+	}
+	if err == nil {
+		if token != nil {
+			_, err = models.GetAccessTokenByHash(token.TokenHash)
+		} else {
+			hash := CurrentSessionHash(sess)
+			if hash == "" {
+				return true
+			}
+			var us *models.UserSession
+			us, err = models.GetUserSessionByHash(hash)
+			if err == nil && us.UserId != user.Id {
+				return true
+			}
+		}
+	}
+	if err != nil && err != models.ErrNotExist {
+		log.Error("LoginRevoked:", err)
+	}
+	return err == models.ErrNotExist
+}
+
+// cleanupUserSessions deletes the logins not seen in the life time of the
+// server session, or of the remember cookie if the user asked to be
+// remembered, those browsers are logged out already
+func cleanupUserSessions() {
+	life := time.Duration(setting.SessionCookieLifeTime) * time.Second
+	if life <= 0 {
+		life = session.DefaultMaxAge
+	}
+	life += sessionSeenMinutes * time.Minute
+	remember := time.Duration(setting.LoginRememberDays) * 24 * time.Hour
+	now := time.Now()
+	if err := models.DeleteUserSessionsSeenBefore(now.Add(-life), now.Add(-remember)); err != nil {
+		log.Error("cleanupUserSessions:", err)
+	}
+}
+
+// StartSessionCleanup deletes the expired logins every hour
+func StartSessionCleanup() {
+	go func() {
+		for {
+			cleanupUserSessions()
+			time.Sleep(sessionCleanupInterval)
+		}
+	}()
+}
+
+// SignOutEverywhere deletes all logins of user, the new Rands makes all
+// remember cookies invalid
+func SignOutEverywhere(user *models.User, ctx *tango.Context, sess *session.Session) error {
+	user.Rands = models.GetUserSalt()
+	if err := models.UpdateById(user.Id, user, "rands", "updated"); err != nil {
+		return err
+	}
+	if err := models.DeleteUserSessions(user.Id); err != nil {
+		return err
+	}
+	LogoutUser(ctx, sess)
+	return nil
+}
+
+var (
+	browserNames = []string{"Edge", "Edg/", "OPR/", "Opera", "Firefox", "Chrome", "Safari", "MSIE", "Trident/"}
+	osNames      = []string{"Windows", "Android", "iPhone", "iPad", "Mac OS X", "Linux", "CrOS"}
+)
+
+// deviceName returns like "Firefox on Windows" from a user agent
+func deviceName(ua string) string {
+	browser := ""
+	for _, name := range browserNames {
+		if strings.Contains(ua, name) {
+			browser = name
+			break
+		}
+	}
+	switch browser {
+	case "Edg/":
+		browser = "Edge"
+	case "OPR/":
+		browser = "Opera"
+	case "MSIE", "Trident/":
+		browser = "Internet Explorer"
+	}
+
+	system := ""
+	for _, name := range osNames {
+		if strings.Contains(ua, name) {
+			system = name
+			break
+		}
+	}
+	switch system {
+	case "Mac OS X":
+		system = "macOS"
+	case "CrOS":
+		system = "Chrome OS"
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown"
+}
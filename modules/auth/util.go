@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
 )
 
 func GetSecureCookie(req *http.Request, Secret, key string) (string, bool) {
@@ -164,7 +165,7 @@ func GetCookie(req *http.Request, key string) string {
 }
 
 func UserFollow(user *models.User, theUser *models.User) {
-	if err := models.GetById(theUser.Id, theUser); err != nil {
+	if err := models.GetById(theUser.Id, theUser); err == nil {
 		var mutual bool
 		tFollow := models.Follow{UserId: theUser.Id, FollowUserId: user.Id}
 		if err := models.GetByExample(&tFollow); err == nil {
@@ -172,9 +173,21 @@ func UserFollow(user *models.User, theUser *models.User) {
 		}
 
 		follow := models.Follow{UserId: user.Id, FollowUserId: theUser.Id, Mutual: mutual}
-		if err := models.Insert(&follow); err == nil && mutual {
-			tFollow.Mutual = mutual
-			models.UpdateById(tFollow.Id, &tFollow, "mutual")
+		if err := models.Insert(&follow); err == nil {
+			if mutual {
+				tFollow.Mutual = mutual
+				models.UpdateById(tFollow.Id, &tFollow, "mutual")
+			}
+
+			notification := models.Notification{
+				FromUserId: user.Id,
+				ToUserId:   theUser.Id,
+				Action:     setting.NOTICE_TYPE_FOLLOW,
+				Title:      user.NickName,
+				Lang:       setting.DefaultLang,
+				Status:     setting.NOTICE_UNREAD,
+			}
+			models.InsertNotification(&notification)
 		}
 
 		if nums, err := models.Count(&models.Follow{UserId: user.Id}); err == nil {
@@ -0,0 +1,235 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package export builds zip files of the personal data of users.
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/go-tango/wego/models"
+)
+
+type profile struct {
+	Id          int64     `json:"id"`
+	UserName    string    `json:"username"`
+	NickName    string    `json:"nickname"`
+	Email       string    `json:"email"`
+	PublicEmail bool      `json:"public_email"`
+	Url         string    `json:"url"`
+	Company     string    `json:"company"`
+	Location    string    `json:"location"`
+	Info        string    `json:"info"`
+	Github      string    `json:"github"`
+	Twitter     string    `json:"twitter"`
+	Google      string    `json:"google"`
+	Weibo       string    `json:"weibo"`
+	Linkedin    string    `json:"linkedin"`
+	Facebook    string    `json:"facebook"`
+	Role        string    `json:"role"`
+	IsActive    bool      `json:"is_active"`
+	LoginSource string    `json:"login_source,omitempty"`
+	InvitedBy   int64     `json:"invited_by,omitempty"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+// profileOf leaves out the password hash and other secrets
+func profileOf(user *models.User) *profile {
+	return &profile{
+		Id:          user.Id,
+		UserName:    user.UserName,
+		NickName:    user.NickName,
+		Email:       user.Email,
+		PublicEmail: user.PublicEmail,
+		Url:         user.Url,
+		Company:     user.Company,
+		Location:    user.Location,
+		Info:        user.Info,
+		Github:      user.Github,
+		Twitter:     user.Twitter,
+		Google:      user.Google,
+		Weibo:       user.Weibo,
+		Linkedin:    user.Linkedin,
+		Facebook:    user.Facebook,
+		Role:        user.RoleName(),
+		IsActive:    user.IsActive,
+		LoginSource: user.LoginSource,
+		InvitedBy:   user.InvitedBy,
+		Created:     user.Created,
+		Updated:     user.Updated,
+	}
+}
+
+type post struct {
+	Id         int64     `json:"id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	CategoryId int64     `json:"category_id"`
+	TopicId    int64     `json:"topic_id"`
+	Status     int       `json:"status"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+}
+
+type comment struct {
+	Id       int64     `json:"id"`
+	PostId   int64     `json:"post_id"`
+	ParentId int64     `json:"parent_id,omitempty"`
+	Floor    int       `json:"floor"`
+	Message  string    `json:"message"`
+	Status   int       `json:"status"`
+	Created  time.Time `json:"created"`
+	Edited   time.Time `json:"edited"`
+}
+
+type favorite struct {
+	PostId  int64     `json:"post_id"`
+	Title   string    `json:"title"`
+	Created time.Time `json:"created"`
+}
+
+type follows struct {
+	Following []string `json:"following"`
+	Followers []string `json:"followers"`
+	Topics    []string `json:"topics"`
+}
+
+type notification struct {
+	Id       int64     `json:"id"`
+	From     string    `json:"from"`
+	Action   int       `json:"action"`
+	TargetId int64     `json:"target_id"`
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Status   int       `json:"status"`
+	Created  time.Time `json:"created"`
+}
+
+func userName(id int64) string {
+	if user, err := models.GetUserById(id); err == nil {
+		return user.UserName
+	}
+	return ""
+}
+
+// Gather reads the data of user, the file names of the zip are the keys
+func Gather(user *models.User) (map[string]interface{}, error) {
+	orm := models.ORM()
+	files := map[string]interface{}{
+		"profile.json": profileOf(user),
+	}
+
+	var posts []models.Post
+	if err := orm.Asc("id").Find(&posts, &models.Post{UserId: user.Id}); err != nil {
+		return nil, err
+	}
+	postList := make([]*post, 0, len(posts))
+	for _, p := range posts {
+		postList = append(postList, &post{p.Id, p.Title, p.Content, p.CategoryId, p.TopicId, p.Status, p.Created, p.Updated})
+	}
+	files["posts.json"] = postList
+
+	var comments []models.Comment
+	if err := orm.Asc("id").Find(&comments, &models.Comment{UserId: user.Id}); err != nil {
+		return nil, err
+	}
+	commentList := make([]*comment, 0, len(comments))
+	for _, c := range comments {
+		commentList = append(commentList, &comment{c.Id, c.PostId, c.ParentId, c.Floor, c.Message, c.Status, c.Created, c.Edited})
+	}
+	files["comments.json"] = commentList
+
+	favorites, err := models.FindFavoritesByUserId(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	favoriteList := make([]*favorite, 0, len(favorites))
+	for _, f := range favorites {
+		if !f.IsFav {
+			continue
+		}
+		fav := &favorite{PostId: f.PostId, Created: f.Created}
+		if p := f.Post(); p != nil {
+			fav.Title = p.Title
+		}
+		favoriteList = append(favoriteList, fav)
+	}
+	files["favorites.json"] = favoriteList
+
+	var userFollows []models.Follow
+	err = orm.Where("user_id = ? OR follow_user_id = ?", user.Id, user.Id).Find(&userFollows)
+	if err != nil {
+		return nil, err
+	}
+	f := &follows{Following: []string{}, Followers: []string{}, Topics: []string{}}
+	for _, follow := range userFollows {
+		if follow.UserId == user.Id {
+			f.Following = append(f.Following, userName(follow.FollowUserId))
+		} else {
+			f.Followers = append(f.Followers, userName(follow.UserId))
+		}
+	}
+	topics, err := models.FindFollowTopic(user.Id, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, ft := range topics {
+		if topic := ft.Topic(); topic != nil {
+			f.Topics = append(f.Topics, topic.Name)
+		}
+	}
+	files["follows.json"] = f
+
+	var notices []models.Notification
+	if err := orm.Asc("id").Find(&notices, &models.Notification{ToUserId: user.Id}); err != nil {
+		return nil, err
+	}
+	noticeList := make([]*notification, 0, len(notices))
+	for _, n := range notices {
+		noticeList = append(noticeList, &notification{n.Id, userName(n.FromUserId), n.Action, n.TargetId, n.Title, n.Content, n.Status, n.Created})
+	}
+	files["notifications.json"] = noticeList
+
+	return files, nil
+}
+
+// Write writes files as indented json to a zip
+func Write(w io.Writer, files map[string]interface{}) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		data, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return err
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,65 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/go-tango/wego/models"
+)
+
+func TestWrite(t *testing.T) {
+	user := &models.User{Id: 7, UserName: "jdoe", Email: "jdoe@example.com", Password: "$argon2id$secret", Rands: "salt"}
+	files := map[string]interface{}{
+		"profile.json": profileOf(user),
+		"posts.json":   []*post{{Id: 1, Title: "hello"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, files); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "posts.json" || zr.File[1].Name != "profile.json" {
+		t.Fatalf("files %v", zr.File)
+	}
+
+	rc, err := zr.File[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(rc)
+	rc.Close()
+
+	if strings.Contains(string(data), "argon2id") || strings.Contains(string(data), "salt") {
+		t.Errorf("profile has secrets: %s", data)
+	}
+	var p map[string]interface{}
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p["username"] != "jdoe" || p["email"] != "jdoe@example.com" || p["role"] != "member" {
+		t.Errorf("profile %v", p)
+	}
+}
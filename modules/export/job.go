@@ -0,0 +1,165 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package export
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
+)
+
+// expired exports are deleted this often
+const cleanupInterval = time.Hour
+
+// ErrPending is returned when an export of the user is still running
+var ErrPending = errors.New("export: an export is pending")
+
+// FilePath returns the zip file of exp
+func FilePath(exp *models.DataExport) string {
+	return filepath.Join(setting.ExportPath, exp.FileName)
+}
+
+// Start saves a pending export of user and builds it in background
+func Start(user *models.User) (*models.DataExport, error) {
+	if pending, err := models.HasPendingDataExport(user.Id); err != nil {
+		return nil, err
+	} else if pending {
+		return nil, ErrPending
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	exp := &models.DataExport{
+		UserId:   user.Id,
+		FileName: hex.EncodeToString(b) + ".zip",
+		Status:   models.EXPORT_PENDING,
+	}
+	if err := models.Insert(exp); err != nil {
+		return nil, err
+	}
+
+	u := *user
+	go run(&u, exp)
+	return exp, nil
+}
+
+func run(user *models.User, exp *models.DataExport) {
+	size, err := build(user, FilePath(exp))
+
+	// the user is deleted meanwhile
+	if _, gerr := models.GetDataExport(user.Id, exp.Id); gerr == models.ErrNotExist {
+		os.Remove(FilePath(exp))
+		return
+	}
+
+	if err != nil {
+		log.Error("export: user", user.Id, err)
+		exp.Status = models.EXPORT_FAILED
+	} else {
+		exp.Status = models.EXPORT_DONE
+		exp.Size = size
+	}
+	if err := models.FinishDataExport(exp); err != nil {
+		log.Error("export: save", exp.Id, err)
+	}
+}
+
+// build writes to a temporary file renamed when complete
+func build(user *models.User, path string) (int64, error) {
+	files, err := Gather(user)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return 0, err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	if err = Write(f, files); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), os.Rename(tmp, path)
+}
+
+func remove(exports []models.DataExport) {
+	for i := range exports {
+		if err := os.Remove(FilePath(&exports[i])); err != nil && !os.IsNotExist(err) {
+			log.Error("export: remove", err)
+		}
+		if err := models.DeleteDataExport(exports[i].Id); err != nil {
+			log.Error("export: delete", exports[i].Id, err)
+		}
+	}
+}
+
+// RemoveUser deletes all exports of user
+func RemoveUser(userId int64) {
+	exports, err := models.FindDataExports(userId)
+	if err != nil {
+		log.Error("export: find", err)
+		return
+	}
+	remove(exports)
+}
+
+func cleanup() {
+	lives := time.Duration(setting.ExportLives) * time.Hour
+	exports, err := models.FindDataExportsBefore(0, time.Now().Add(-lives))
+	if err != nil {
+		log.Error("export: find expired", err)
+		return
+	}
+	remove(exports)
+}
+
+// Init fails exports interrupted by a restart and deletes expired ones
+// every hour
+func Init() {
+	if err := models.FailPendingDataExports(); err != nil {
+		log.Error("export: fail pending", err)
+	}
+	go func() {
+		for {
+			cleanup()
+			time.Sleep(cleanupInterval)
+		}
+	}()
+}
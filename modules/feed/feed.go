@@ -0,0 +1,229 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package feed writes Atom 1.0 and RSS 2.0 feeds.
+package feed
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	FORMAT_ATOM = "atom"
+	FORMAT_RSS  = "rss"
+)
+
+var contentTypes = map[string]string{
+	FORMAT_ATOM: "application/atom+xml; charset=utf-8",
+	FORMAT_RSS:  "application/rss+xml; charset=utf-8",
+}
+
+// ContentType returns the mime type of format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+type Author struct {
+	Name string
+	Link string
+}
+
+type Item struct {
+	Id        string
+	Title     string
+	Link      string
+	Author    Author
+	Category  string
+	Content   string // html
+	Published time.Time
+	Updated   time.Time
+}
+
+type Feed struct {
+	Title       string
+	Description string
+	Link        string // html page of the feed
+	Self        string // the feed itself
+	Updated     time.Time
+	Items       []*Item
+}
+
+// LastModified returns the newest update time of feed and items
+func (f *Feed) LastModified() time.Time {
+	updated := f.Updated
+	for _, item := range f.Items {
+		if item.Updated.After(updated) {
+			updated = item.Updated
+		}
+	}
+	return updated
+}
+
+// ETag changes when any item changes or the format changes
+func (f *Feed) ETag(format string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n%d\n", format, f.Self, f.Updated.Unix())
+	for _, item := range f.Items {
+		fmt.Fprintf(h, "%s %d\n", item.Id, item.Updated.Unix())
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// Write encodes feed as format to w
+func (f *Feed) Write(w io.Writer, format string) error {
+	var v interface{}
+	if format == FORMAT_RSS {
+		v = f.rss()
+	} else {
+		v = f.atom()
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(v)
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title     string        `xml:"title"`
+	Id        string        `xml:"id"`
+	Links     []atomLink    `xml:"link"`
+	Published string        `xml:"published"`
+	Updated   string        `xml:"updated"`
+	Author    *atomPerson   `xml:"author,omitempty"`
+	Category  *atomCategory `xml:"category,omitempty"`
+	Content   atomText      `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Id       string       `xml:"id"`
+	Links    []atomLink   `xml:"link"`
+	Updated  string       `xml:"updated"`
+	Entries  []*atomEntry `xml:"entry"`
+}
+
+func (f *Feed) atom() *atomFeed {
+	feed := &atomFeed{
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Id:       f.Self,
+		Links: []atomLink{
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+		},
+		Updated: f.LastModified().Format(time.RFC3339),
+	}
+	for _, item := range f.Items {
+		entry := &atomEntry{
+			Title:     item.Title,
+			Id:        item.Id,
+			Links:     []atomLink{{Href: item.Link, Rel: "alternate", Type: "text/html"}},
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   item.Updated.Format(time.RFC3339),
+			Content:   atomText{Type: "html", Body: item.Content},
+		}
+		if item.Author.Name != "" {
+			entry.Author = &atomPerson{Name: item.Author.Name, URI: item.Author.Link}
+		}
+		if item.Category != "" {
+			entry.Category = &atomCategory{Term: item.Category}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Guid        rssGuid `xml:"guid"`
+	Creator     string  `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Category    string  `xml:"category,omitempty"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	AtomLink      atomLink   `xml:"http://www.w3.org/2005/Atom link"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name    `xml:"rss"`
+	Version string      `xml:"version,attr"`
+	Channel *rssChannel `xml:"channel"`
+}
+
+func (f *Feed) rss() *rssFeed {
+	channel := &rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		AtomLink:      atomLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+		LastBuildDate: f.LastModified().Format(time.RFC1123Z),
+	}
+	if channel.Description == "" {
+		channel.Description = f.Title
+	}
+	for _, item := range f.Items {
+		channel.Items = append(channel.Items, &rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Guid:        rssGuid{IsPermaLink: item.Id == item.Link, Value: item.Id},
+			Creator:     item.Author.Name,
+			Category:    item.Category,
+			Description: item.Content,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+		})
+	}
+	return &rssFeed{Version: "2.0", Channel: channel}
+}
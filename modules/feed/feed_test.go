@@ -0,0 +1,90 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	created := time.Date(2014, 10, 1, 8, 0, 0, 0, time.UTC)
+	return &Feed{
+		Title: "WeGo",
+		Link:  "http://example.com/",
+		Self:  "http://example.com/feed",
+		Items: []*Item{{
+			Id:        "http://example.com/post/1",
+			Title:     "Hello <Go>",
+			Link:      "http://example.com/post/1",
+			Author:    Author{Name: "gopher", Link: "http://example.com/user/gopher"},
+			Content:   "<p>hi</p>",
+			Published: created,
+			Updated:   created.Add(time.Hour),
+		}},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testFeed().Write(&buf, FORMAT_ATOM); err != nil {
+		t.Fatal(err)
+	}
+
+	var v atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Updated != "2014-10-01T09:00:00Z" || len(v.Entries) != 1 {
+		t.Fatalf("unexpected feed %+v", v)
+	}
+	entry := v.Entries[0]
+	if entry.Title != "Hello <Go>" || entry.Content.Body != "<p>hi</p>" || entry.Author.Name != "gopher" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testFeed().Write(&buf, FORMAT_RSS); err != nil {
+		t.Fatal(err)
+	}
+
+	var v rssFeed
+	if err := xml.Unmarshal(buf.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != "2.0" || len(v.Channel.Items) != 1 {
+		t.Fatalf("unexpected feed %+v", v)
+	}
+	item := v.Channel.Items[0]
+	if !item.Guid.IsPermaLink || item.Description != "<p>hi</p>" || item.PubDate != "Wed, 01 Oct 2014 08:00:00 +0000" {
+		t.Errorf("unexpected item %+v", item)
+	}
+}
+
+func TestETag(t *testing.T) {
+	f := testFeed()
+	etag := f.ETag(FORMAT_ATOM)
+	if etag == f.ETag(FORMAT_RSS) {
+		t.Error("formats share etag")
+	}
+	f.Items[0].Updated = f.Items[0].Updated.Add(time.Second)
+	if etag == f.ETag(FORMAT_ATOM) {
+		t.Error("etag not changed by item update")
+	}
+}
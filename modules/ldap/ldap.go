@@ -0,0 +1,194 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package ldap authenticates users of an LDAP directory.
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// connection security
+const (
+	SECURITY_NONE     = "none"
+	SECURITY_STARTTLS = "starttls"
+	SECURITY_LDAPS    = "ldaps"
+)
+
+var (
+	// the login is not in the directory, or is not unique
+	ErrNotFound = errors.New("ldap: user not found")
+	// the directory rejects the password
+	ErrBadPassword = errors.New("ldap: wrong password")
+)
+
+// Config is a directory. Users are searched under BaseDN with Filter,
+// where %s is the escaped login, with the BindDN account or anonymously,
+// then bind with their own password.
+type Config struct {
+	Host         string
+	Port         int
+	Security     string
+	SkipVerify   bool
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	Filter       string
+
+	// attributes of new accounts
+	UserNameAttr string
+	EmailAttr    string
+	NickNameAttr string
+
+	// members of the group are admins, by the memberOf attribute
+	AdminGroup string
+	GroupAttr  string
+
+	Timeout time.Duration
+}
+
+// User is an entry of the directory
+type User struct {
+	DN       string
+	UserName string
+	Email    string
+	NickName string
+	IsAdmin  bool
+}
+
+func (c *Config) dial() (*ldap.Conn, error) {
+	port := c.Port
+	if port == 0 {
+		port = 389
+		if c.Security == SECURITY_LDAPS {
+			port = 636
+		}
+	}
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: c.Host, InsecureSkipVerify: c.SkipVerify}
+
+	var conn *ldap.Conn
+	var err error
+	if c.Security == SECURITY_LDAPS {
+		conn, err = ldap.DialTLS("tcp", addr, tlsConfig)
+	} else {
+		conn, err = ldap.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Timeout > 0 {
+		conn.SetTimeout(c.Timeout)
+	}
+	if c.Security == SECURITY_STARTTLS {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// SearchFilter returns the filter of login
+func (c *Config) SearchFilter(login string) string {
+	return strings.Replace(c.Filter, "%s", ldap.EscapeFilter(login), -1)
+}
+
+// Authenticate finds login and checks its password
+func (c *Config) Authenticate(login, password string) (*User, error) {
+	// an empty password is an unauthenticated bind, which succeeds
+	if login == "" || password == "" {
+		return nil, ErrBadPassword
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if c.BindDN != "" {
+		if err = conn.Bind(c.BindDN, c.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: bind %s: %v", c.BindDN, err)
+		}
+	}
+
+	attrs := []string{c.UserNameAttr, c.EmailAttr, c.NickNameAttr, c.groupAttr()}
+	req := ldap.NewSearchRequest(c.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(c.Timeout/time.Second), false, c.SearchFilter(login), attrs, nil)
+	res, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if len(res.Entries) != 1 {
+		return nil, ErrNotFound
+	}
+	entry := res.Entries[0]
+
+	if err = conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrBadPassword
+		}
+		return nil, err
+	}
+
+	user := &User{
+		DN:       entry.DN,
+		UserName: entry.GetAttributeValue(c.UserNameAttr),
+		Email:    entry.GetAttributeValue(c.EmailAttr),
+		NickName: entry.GetAttributeValue(c.NickNameAttr),
+	}
+	if user.UserName == "" {
+		user.UserName = login
+	}
+	user.IsAdmin = c.isAdmin(entry.GetAttributeValues(c.groupAttr()))
+	return user, nil
+}
+
+func (c *Config) groupAttr() string {
+	if c.GroupAttr == "" {
+		return "memberOf"
+	}
+	return c.GroupAttr
+}
+
+// isAdmin reports whether groups has AdminGroup, DNs are compared
+// without case and spaces after commas
+func (c *Config) isAdmin(groups []string) bool {
+	if c.AdminGroup == "" {
+		return false
+	}
+	admin, err := ldap.ParseDN(strings.ToLower(c.AdminGroup))
+	if err != nil {
+		return false
+	}
+	for _, group := range groups {
+		if dn, err := ldap.ParseDN(strings.ToLower(group)); err == nil && admin.Equal(dn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ldap
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+type testEntry struct {
+	password string
+	attrs    map[string][]string
+}
+
+// testServer is a stand-in directory, answering simple binds and
+// searches whose filter is the filter of one of its uids
+type testServer struct {
+	config  *Config
+	entries map[string]*testEntry
+}
+
+func (s *testServer) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func ldapResult(id int64, app ber.Tag, code uint16) *ber.Packet {
+	p := ber.NewSequence("message")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "id"))
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, app, nil, "result")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, uint64(code), "code"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matched"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "message"))
+	p.AppendChild(res)
+	return p
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p, err := ber.ReadPacket(conn)
+		if err != nil || len(p.Children) < 2 {
+			return
+		}
+		id := p.Children[0].Value.(int64)
+		op := p.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Data.String()
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			if e, ok := s.entries[dn]; ok && e.password == op.Children[2].Data.String() {
+				code = ldap.LDAPResultSuccess
+			}
+			conn.Write(ldapResult(id, ldap.ApplicationBindResponse, code).Bytes())
+
+		case ldap.ApplicationSearchRequest:
+			filter, _ := ldap.DecompileFilter(op.Children[6])
+			for dn, e := range s.entries {
+				if filter != s.config.SearchFilter(e.attrs["uid"][0]) {
+					continue
+				}
+				res := ber.NewSequence("message")
+				res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "id"))
+				entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "entry")
+				entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "dn"))
+				attrs := ber.NewSequence("attributes")
+				for name, values := range e.attrs {
+					attr := ber.NewSequence("attribute")
+					attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+					set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+					for _, v := range values {
+						set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+					}
+					attr.AppendChild(set)
+					attrs.AppendChild(attr)
+				}
+				entry.AppendChild(attrs)
+				res.AppendChild(entry)
+				conn.Write(res.Bytes())
+			}
+			conn.Write(ldapResult(id, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess).Bytes())
+
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func startServer(t *testing.T) (*Config, net.Listener) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	config := &Config{
+		Host:         host,
+		Port:         p,
+		Security:     SECURITY_NONE,
+		BindDN:       "cn=reader,dc=corp",
+		BindPassword: "reader",
+		BaseDN:       "ou=people,dc=corp",
+		Filter:       "(&(objectClass=person)(uid=%s))",
+		UserNameAttr: "uid",
+		EmailAttr:    "mail",
+		NickNameAttr: "cn",
+		AdminGroup:   "cn=Admins, ou=groups,dc=corp",
+	}
+	s := &testServer{config: config, entries: map[string]*testEntry{
+		"cn=reader,dc=corp": {"reader", map[string][]string{"uid": {"reader"}}},
+		"uid=jdoe,ou=people,dc=corp": {"jdoe-pw", map[string][]string{
+			"uid":      {"jdoe"},
+			"mail":     {"jdoe@corp.example"},
+			"cn":       {"Jane Doe"},
+			"memberOf": {"cn=staff,ou=groups,dc=corp", "CN=admins,OU=groups,DC=corp"},
+		}},
+		"uid=bob,ou=people,dc=corp": {"bob-pw", map[string][]string{
+			"uid": {"bob"},
+		}},
+	}}
+	go s.serve(l)
+	return config, l
+}
+
+func TestAuthenticate(t *testing.T) {
+	config, l := startServer(t)
+	defer l.Close()
+
+	user, err := config.Authenticate("jdoe", "jdoe-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := User{"uid=jdoe,ou=people,dc=corp", "jdoe", "jdoe@corp.example", "Jane Doe", true}
+	if *user != want {
+		t.Errorf("user %+v, want %+v", *user, want)
+	}
+
+	if user, err = config.Authenticate("bob", "bob-pw"); err != nil || user.IsAdmin || user.Email != "" {
+		t.Errorf("bob: %+v, %v", user, err)
+	}
+
+	cases := []struct {
+		login, password string
+		err             error
+	}{
+		{"jdoe", "wrong", ErrBadPassword},
+		{"jdoe", "", ErrBadPassword},
+		{"nobody", "pw", ErrNotFound},
+		{"jdoe)(uid=*", "pw", ErrNotFound},
+	}
+	for _, c := range cases {
+		if _, err := config.Authenticate(c.login, c.password); err != c.err {
+			t.Errorf("Authenticate(%q, %q) = %v, want %v", c.login, c.password, err, c.err)
+		}
+	}
+
+	config.BindPassword = "wrong"
+	if _, err := config.Authenticate("jdoe", "jdoe-pw"); err == nil || err == ErrBadPassword {
+		t.Errorf("bad bind account: %v", err)
+	}
+}
+
+func TestSearchFilter(t *testing.T) {
+	c := &Config{Filter: "(|(uid=%s)(mail=%s))"}
+	if f := c.SearchFilter("a*)(b"); f != `(|(uid=a\2a\29\28b)(mail=a\2a\29\28b))` {
+		t.Errorf("filter %s", f)
+	}
+}
@@ -16,95 +16,100 @@ package mailer
 
 import (
 	"fmt"
-	"net/smtp"
 	"strings"
-	
-	"github.com/lunny/log"
 
-	"github.com/go-tango/wego/setting"
+	"github.com/lunny/log"
 )
 
 type Message struct {
 	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
 	From    string
 	Subject string
-	Body    string
+	// html or plain text by Type
+	Body string
+	// plain text alternative of html Body, created from Body when empty
+	Text    string
 	User    string
 	Type    string
 	Massive bool
 	Info    string
+	// created when the message is sent or queued, retries keep it
+	MessageId string
 }
 
-// create mail content
-func (m Message) Content() string {
-	// set mail type
-	contentType := "text/plain; charset=UTF-8"
-	if m.Type == "html" {
-		contentType = "text/html; charset=UTF-8"
-	}
-
-	// create mail content
-	content := "From: " + m.User + "<" + m.From +
-		">\r\nSubject: " + m.Subject + "\r\nContent-Type: " + contentType + "\r\n\r\n" + m.Body
-	return content
+// Recipients returns all envelope recipients, Bcc included
+func (m Message) Recipients() []string {
+	rcpts := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	rcpts = append(rcpts, m.To...)
+	rcpts = append(rcpts, m.Cc...)
+	return append(rcpts, m.Bcc...)
 }
 
-// Direct Send mail message
-func Send(msg Message) (int, error) {
-	host := strings.Split(setting.MailHost, ":")
-
-	// get message body
-	content := msg.Content()
-
-	auth := smtp.PlainAuth("", setting.MailAuthUser, setting.MailAuthPass, host[0])
+// split returns the messages to send, massive messages are split into one
+// message for every To, Cc and Bcc go with the first one only
+func (m Message) split() []Message {
+	msgs := []Message{m}
+	if m.Massive && len(m.To) > 1 {
+		msgs = msgs[:0]
+		for i, to := range m.To {
+			single := m
+			single.To = []string{to}
+			if i > 0 {
+				single.Cc, single.Bcc = nil, nil
+			}
+			msgs = append(msgs, single)
+		}
+	}
+	for i := range msgs {
+		msgs[i].Massive = false
+		if msgs[i].MessageId == "" || len(msgs) > 1 {
+			msgs[i].MessageId = newMessageId(m.From)
+		}
+	}
+	return msgs
+}
 
-	if len(msg.To) == 0 {
-		return 0, fmt.Errorf("empty receive emails")
+func (m Message) validate() error {
+	if len(m.To) == 0 {
+		return fmt.Errorf("empty receive emails")
 	}
 
-	if len(msg.Body) == 0 {
-		return 0, fmt.Errorf("empty email body")
+	if len(m.Body) == 0 {
+		return fmt.Errorf("empty email body")
 	}
+	return nil
+}
 
-	if msg.Massive {
-		// send mail to multiple emails one by one
-		num := 0
-		for _, to := range msg.To {
-			body := []byte("To: " + to + "\r\n" + content)
-			err := smtp.SendMail(setting.MailHost, auth, msg.From, []string{to}, body)
-			if err != nil {
-				return num, err
-			}
-			num++
-		}
-		return num, nil
-	} else {
-		body := []byte("To: " + strings.Join(msg.To, ";") + "\r\n" + content)
+// Direct Send mail message with DefaultTransport
+func Send(msg Message) (int, error) {
+	if err := msg.validate(); err != nil {
+		return 0, err
+	}
 
-		// send to multiple emails in one message
-		err := smtp.SendMail(setting.MailHost, auth, msg.From, msg.To, body)
-		if err != nil {
-			return 0, err
-		} else {
-			return 1, nil
+	// massive mails are sent to multiple emails one by one
+	num := 0
+	for _, single := range msg.split() {
+		if err := DefaultTransport.Send(&single); err != nil {
+			return num, err
 		}
+		num++
 	}
+	return num, nil
 }
 
-// Async Send mail message
+// Async Send mail message, the message is saved in mail queue and
+// sent by queue workers
 func SendAsync(msg Message) {
-	// TODO may be need pools limit concurrent nums
-	go func() {
-		if num, err := Send(msg); err != nil {
-			tos := strings.Join(msg.To, "; ")
-			info := ""
-			if len(msg.Info) > 0 {
-				info = ", info: " + msg.Info
-			}
-			// log failed
-			log.Error(fmt.Sprintf("Async send email %d succeed, not send emails: %s%s err: %s", num, tos, info, err))
+	if err := Enqueue(msg); err != nil {
+		info := ""
+		if len(msg.Info) > 0 {
+			info = ", info: " + msg.Info
 		}
-	}()
+		log.Error(fmt.Sprintf("Enqueue email to %s failed%s err: %s", strings.Join(msg.To, "; "), info, err))
+	}
 }
 
 // Create html mail message
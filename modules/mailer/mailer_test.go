@@ -0,0 +1,199 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-tango/wego/setting"
+)
+
+func TestSendMassive(t *testing.T) {
+	transport := &MemoryTransport{}
+	DefaultTransport = transport
+	defer func() { DefaultTransport = SMTPTransport{} }()
+
+	msg := NewHtmlMessage([]string{"a@example.com", "b@example.com"}, "from@example.com", "subject", "body")
+	msg.Massive = true
+	num, err := Send(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if num != 2 {
+		t.Fatalf("sent %d messages, want 2", num)
+	}
+
+	sent := transport.Messages()
+	if len(sent) != 2 || sent[0].To[0] != "a@example.com" || sent[1].To[0] != "b@example.com" {
+		t.Fatalf("unexpected messages %v", sent)
+	}
+
+	transport.Reset()
+	transport.Err = errors.New("down")
+	if num, err := Send(msg); err == nil || num != 0 {
+		t.Fatalf("send with failing transport returned %d, %v", num, err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	setting.MailQueueRetryBase = 60
+	cases := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{20, maxRetryDelay},
+	}
+	for _, c := range cases {
+		if d := RetryDelay(c.attempts); d != c.delay {
+			t.Errorf("RetryDelay(%d) = %v, want %v", c.attempts, d, c.delay)
+		}
+	}
+}
+
+func TestMessageContent(t *testing.T) {
+	msg := NewHtmlMessage([]string{"a@example.com"}, "from@example.com", "你好 WeGo", "<p>Hello &amp; welcome</p>")
+	msg.User = "社区"
+	msg.Cc = []string{"c@example.com"}
+	msg.Bcc = []string{"hidden@example.com"}
+	msg.ReplyTo = "reply@example.com"
+
+	m, err := mail.ReadMessage(bytes.NewReader(msg.Content()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dec := new(mime.WordDecoder)
+	if subject, _ := dec.DecodeHeader(m.Header.Get("Subject")); subject != "你好 WeGo" {
+		t.Errorf("subject = %q", subject)
+	}
+	if from, err := m.Header.AddressList("From"); err != nil || from[0].Name != "社区" || from[0].Address != "from@example.com" {
+		t.Errorf("from = %v, %v", from, err)
+	}
+	if cc := m.Header.Get("Cc"); cc != "<c@example.com>" {
+		t.Errorf("cc = %q", cc)
+	}
+	if m.Header.Get("Bcc") != "" || bytes.Contains(msg.Content(), []byte("hidden@example.com")) {
+		t.Error("bcc leaked into headers")
+	}
+	if id := m.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("message id = %q", id)
+	}
+	if _, err := m.Header.Date(); err != nil {
+		t.Error(err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q, %v", mediaType, err)
+	}
+	r := multipart.NewReader(m.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := ioutil.ReadAll(quotedprintable.NewReader(p))
+		parts = append(parts, p.Header.Get("Content-Type")+": "+string(body))
+	}
+	if len(parts) != 2 ||
+		parts[0] != "text/plain; charset=UTF-8: Hello & welcome" ||
+		parts[1] != "text/html; charset=UTF-8: <p>Hello &amp; welcome</p>" {
+		t.Errorf("unexpected parts %q", parts)
+	}
+}
+
+func TestSMTPTransport(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		received <- fakeSMTPSession(conn)
+	}()
+
+	msg := NewMailMessage([]string{"a@example.com"}, "subject", "body")
+	msg.Type = ""
+	msg.Bcc = []string{"b@example.com"}
+	transport := SMTPTransport{Host: l.Addr().String(), TLS: TLS_NONE}
+	if err := transport.Send(&msg); err != nil {
+		t.Fatal(err)
+	}
+
+	cmds := <-received
+	want := []string{"MAIL FROM:<" + msg.From + ">", "RCPT TO:<a@example.com>", "RCPT TO:<b@example.com>", "DATA"}
+	got := strings.Join(cmds, "\n")
+	for _, cmd := range want {
+		if !strings.Contains(got, cmd) {
+			t.Errorf("missing %q in %q", cmd, got)
+		}
+	}
+}
+
+// fakeSMTPSession accepts one mail and returns the received commands
+func fakeSMTPSession(conn net.Conn) []string {
+	var cmds []string
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return cmds
+		}
+		line = strings.TrimSpace(line)
+		cmds = append(cmds, line)
+		switch {
+		case strings.HasPrefix(line, "EHLO"):
+			reply("250 localhost")
+		case line == "DATA":
+			reply("354 go ahead")
+			for {
+				data, err := r.ReadString('\n')
+				if err != nil || data == ".\r\n" {
+					break
+				}
+			}
+			reply("250 ok")
+		case line == "QUIT":
+			reply("221 bye")
+			return cmds
+		default:
+			reply("250 ok")
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/go-tango/wego/modules/utils"
+)
+
+// create mail content, headers are RFC 2047 encoded and html mails carry
+// a plain text alternative
+func (m Message) Content() []byte {
+	var buf bytes.Buffer
+
+	header := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+		}
+	}
+
+	header("From", formatAddress(m.User, m.From))
+	header("To", formatAddressList(m.To))
+	header("Cc", formatAddressList(m.Cc))
+	header("Reply-To", formatAddress("", m.ReplyTo))
+	header("Subject", mime.BEncoding.Encode("UTF-8", sanitizeHeader(m.Subject)))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	messageId := m.MessageId
+	if messageId == "" {
+		messageId = newMessageId(m.From)
+	}
+	header("Message-ID", messageId)
+	header("MIME-Version", "1.0")
+
+	if m.Type != "html" {
+		writePart(&buf, "text/plain; charset=UTF-8", m.Body)
+		return buf.Bytes()
+	}
+
+	text := m.Text
+	if text == "" {
+		text = html.UnescapeString(utils.Html2str(m.Body))
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	header("Content-Type", "multipart/alternative; boundary="+w.Boundary())
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", m.Body},
+	} {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", part.contentType)
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, _ := w.CreatePart(h)
+		qp := quotedprintable.NewWriter(pw)
+		qp.Write([]byte(part.content))
+		qp.Close()
+	}
+	w.Close()
+
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// writePart writes the content headers and body of a single part mail
+func writePart(buf *bytes.Buffer, contentType, content string) {
+	fmt.Fprintf(buf, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(buf, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(buf)
+	qp.Write([]byte(content))
+	qp.Close()
+}
+
+// formatAddress returns "name" <email> with name encoded when needed
+func formatAddress(name, email string) string {
+	if email == "" {
+		return ""
+	}
+	addr := mail.Address{Name: sanitizeHeader(name), Address: sanitizeHeader(email)}
+	return addr.String()
+}
+
+func formatAddressList(emails []string) string {
+	addrs := make([]string, 0, len(emails))
+	for _, email := range emails {
+		addrs = append(addrs, formatAddress("", email))
+	}
+	return strings.Join(addrs, ", ")
+}
+
+// new lines in header values would start new headers
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// newMessageId returns a unique id in the domain of from address
+func newMessageId(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		domain = sanitizeHeader(from[i+1:])
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
@@ -0,0 +1,221 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package mailer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
+)
+
+const (
+	// due mails loaded at once by the dispatcher
+	queueBatchSize = 100
+	// the dispatcher checks for due retries this often
+	queuePollInterval = 10 * time.Second
+	// retry delay never grows above this
+	maxRetryDelay = 6 * time.Hour
+)
+
+// wakes the dispatcher when new mails are queued
+var queueWakeup = make(chan struct{}, 1)
+
+// Enqueue saves msg in the mail queue, massive messages are saved once
+// for every To recipient
+func Enqueue(msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, single := range msg.split() {
+		m := &models.MailQueue{
+			MessageId:  single.MessageId,
+			Recipients: strings.Join(single.To, ","),
+			CcAddrs:    strings.Join(single.Cc, ","),
+			BccAddrs:   strings.Join(single.Bcc, ","),
+			ReplyTo:    single.ReplyTo,
+			FromAddr:   single.From,
+			FromName:   single.User,
+			Subject:    single.Subject,
+			Body:       single.Body,
+			Text:       single.Text,
+			Type:       single.Type,
+			Info:       single.Info,
+			Status:     models.MAIL_QUEUE_PENDING,
+			NextTry:    now,
+		}
+		if err := models.InsertMailQueue(m); err != nil {
+			return err
+		}
+	}
+
+	wakeQueue()
+	return nil
+}
+
+// Resend queues a sent or failed mail again with fresh attempts
+func Resend(id int64) error {
+	m, err := models.GetMailQueue(id)
+	if err != nil {
+		return err
+	}
+	if m.IsSending() {
+		return fmt.Errorf("mail %d is being sent", id)
+	}
+
+	m.Status = models.MAIL_QUEUE_PENDING
+	m.Attempts = 0
+	m.LastError = ""
+	m.NextTry = time.Now()
+	if err := models.UpdateMailQueue(m, "status", "attempts", "last_error", "next_try"); err != nil {
+		return err
+	}
+
+	wakeQueue()
+	return nil
+}
+
+func wakeQueue() {
+	select {
+	case queueWakeup <- struct{}{}:
+	default:
+	}
+}
+
+// StartQueue starts the dispatcher and setting.MailQueueWorkers workers
+func StartQueue() {
+	// mails left sending by last run are sent again
+	if n, err := models.ResetSendingMails(); err != nil {
+		log.Error("mailer: reset sending mails ", err)
+	} else if n > 0 {
+		log.Info("mailer:", n, "interrupted mails queued again")
+	}
+
+	jobs := make(chan *models.MailQueue)
+	for i := 0; i < setting.MailQueueWorkers; i++ {
+		go queueWorker(jobs)
+	}
+	go dispatchQueue(jobs)
+}
+
+func dispatchQueue(jobs chan<- *models.MailQueue) {
+	ticker := time.NewTicker(queuePollInterval)
+	defer ticker.Stop()
+	for {
+		dispatchDueMails(jobs)
+		select {
+		case <-ticker.C:
+		case <-queueWakeup:
+		}
+	}
+}
+
+// dispatchDueMails hands due mails to workers, it blocks while all
+// workers are busy
+func dispatchDueMails(jobs chan<- *models.MailQueue) {
+	for {
+		mails, err := models.FindDueMails(time.Now(), queueBatchSize)
+		if err != nil {
+			log.Error("mailer: find due mails ", err)
+			return
+		}
+		for _, m := range mails {
+			ok, err := models.ClaimMail(m)
+			if err != nil {
+				log.Error("mailer: claim mail ", err)
+				return
+			}
+			if ok {
+				jobs <- m
+			}
+		}
+		if len(mails) < queueBatchSize {
+			return
+		}
+	}
+}
+
+func queueWorker(jobs <-chan *models.MailQueue) {
+	for m := range jobs {
+		deliver(m)
+	}
+}
+
+// deliver sends a claimed mail and records the result, failed mails are
+// retried with backoff until setting.MailQueueMaxAttempts
+func deliver(m *models.MailQueue) {
+	msg := Message{
+		To:        m.To(),
+		Cc:        m.Cc(),
+		Bcc:       m.Bcc(),
+		ReplyTo:   m.ReplyTo,
+		From:      m.FromAddr,
+		User:      m.FromName,
+		Subject:   m.Subject,
+		Body:      m.Body,
+		Text:      m.Text,
+		Type:      m.Type,
+		Info:      m.Info,
+		MessageId: m.MessageId,
+	}
+
+	now := time.Now()
+	m.Attempts++
+	if err := DefaultTransport.Send(&msg); err != nil {
+		m.LastError = err.Error()
+		if m.Attempts >= setting.MailQueueMaxAttempts {
+			m.Status = models.MAIL_QUEUE_FAILED
+			info := ""
+			if len(m.Info) > 0 {
+				info = ", info: " + m.Info
+			}
+			log.Error(fmt.Sprintf("mailer: give up mail %d to %s after %d attempts%s err: %s",
+				m.Id, m.Recipients, m.Attempts, info, err))
+		} else {
+			m.Status = models.MAIL_QUEUE_PENDING
+			m.NextTry = now.Add(RetryDelay(m.Attempts))
+		}
+	} else {
+		m.Status = models.MAIL_QUEUE_SENT
+		m.LastError = ""
+		m.Sent = now
+	}
+
+	if err := models.UpdateMailQueue(m, "status", "attempts", "last_error", "next_try", "sent"); err != nil {
+		log.Error("mailer: update mail ", err)
+	}
+}
+
+// RetryDelay is the wait after the attempts-th failed send, it doubles
+// setting.MailQueueRetryBase seconds each attempt up to maxRetryDelay
+func RetryDelay(attempts int) time.Duration {
+	delay := time.Duration(setting.MailQueueRetryBase) * time.Second
+	if delay <= 0 {
+		delay = time.Second
+	}
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,177 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package mailer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sync"
+	"time"
+
+	"github.com/go-tango/wego/setting"
+)
+
+// Transport delivers a message to all of its recipients at once
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// transport used by Send and the mail queue
+var DefaultTransport Transport = SMTPTransport{}
+
+// TLS modes of SMTPTransport
+const (
+	// STARTTLS when the server offers it
+	TLS_AUTO = "auto"
+	// fail when the server does not offer STARTTLS
+	TLS_STARTTLS = "starttls"
+	// connect with TLS, usually port 465
+	TLS_IMPLICIT = "tls"
+	// plain connection
+	TLS_NONE = "none"
+)
+
+// timeout of a whole SMTP session
+const smtpTimeout = time.Minute
+
+// SMTPTransport sends mails with a SMTP server, empty fields are read
+// from the mailer settings
+type SMTPTransport struct {
+	Host       string
+	User       string
+	Pass       string
+	TLS        string
+	SkipVerify bool
+}
+
+func (t SMTPTransport) config() SMTPTransport {
+	if t.Host == "" {
+		t = SMTPTransport{
+			Host:       setting.MailHost,
+			User:       setting.MailAuthUser,
+			Pass:       setting.MailAuthPass,
+			TLS:        setting.MailTLS,
+			SkipVerify: setting.MailTLSSkipVerify,
+		}
+	}
+	if t.TLS == "" {
+		t.TLS = TLS_AUTO
+	}
+	return t
+}
+
+func (t SMTPTransport) Send(msg *Message) error {
+	t = t.config()
+
+	addr := t.Host
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+		addr = net.JoinHostPort(addr, "25")
+	}
+	tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: t.SkipVerify}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	if t.TLS == TLS_IMPLICIT {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if t.TLS == TLS_AUTO || t.TLS == TLS_STARTTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		} else if t.TLS == TLS_STARTTLS {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", host)
+		}
+	}
+
+	if t.User != "" {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(smtp.PlainAuth("", t.User, t.Pass, host)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.Mail(msg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Content()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// MemoryTransport keeps messages in memory instead of sending them,
+// sending fails with Err when it is set
+type MemoryTransport struct {
+	lock     sync.Mutex
+	messages []Message
+	Err      error
+}
+
+func (t *MemoryTransport) Send(msg *Message) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.Err != nil {
+		return t.Err
+	}
+	t.messages = append(t.messages, *msg)
+	return nil
+}
+
+// Messages returns a copy of the sent messages
+func (t *MemoryTransport) Messages() []Message {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]Message(nil), t.messages...)
+}
+
+func (t *MemoryTransport) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.messages = nil
+	t.Err = nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package notice pushes new notifications and unread counts to the
+// browsers of logged-in users.
+package notice
+
+import (
+	"sync"
+)
+
+// size of subscriber buffer, events are dropped for slow subscribers
+const bufferSize = 16
+
+// Event is a message sent to subscribers, Id is empty for events which
+// are not replayed on reconnect
+type Event struct {
+	Id   string
+	Name string
+	Data []byte
+}
+
+// Subscriber receives events of a user
+type Subscriber struct {
+	UserId int64
+	C      chan *Event
+}
+
+// Hub is an in-process pub/sub of events by user id
+type Hub struct {
+	lock sync.RWMutex
+	subs map[int64]map[*Subscriber]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[int64]map[*Subscriber]bool),
+	}
+}
+
+func (h *Hub) Subscribe(userId int64) *Subscriber {
+	sub := &Subscriber{
+		UserId: userId,
+		C:      make(chan *Event, bufferSize),
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.subs[userId] == nil {
+		h.subs[userId] = make(map[*Subscriber]bool)
+	}
+	h.subs[userId][sub] = true
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if subs, ok := h.subs[sub.UserId]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subs, sub.UserId)
+		}
+	}
+}
+
+// HasSubscribers reports whether user has any open stream
+func (h *Hub) HasSubscribers(userId int64) bool {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return len(h.subs[userId]) > 0
+}
+
+// Publish sends event to all subscribers of user without blocking
+func (h *Hub) Publish(userId int64, event *Event) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	for sub := range h.subs[userId] {
+		select {
+		case sub.C <- event:
+		default:
+		}
+	}
+}
+
+// Len returns the number of subscribers
+func (h *Hub) Len() int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	var n int
+	for _, subs := range h.subs {
+		n += len(subs)
+	}
+	return n
+}
@@ -0,0 +1,46 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package notice
+
+import (
+	"testing"
+
+	. "github.com/go-tango/wego/modules/utils"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	a1 := hub.Subscribe(1)
+	a2 := hub.Subscribe(1)
+	b := hub.Subscribe(2)
+	ThrowFail(t, AssertIs(hub.Len(), 3))
+	ThrowFail(t, AssertIs(hub.HasSubscribers(3), false))
+
+	hub.Publish(1, &Event{Id: "1", Name: EVENT_NOTIFICATION})
+	ThrowFail(t, AssertIs((<-a1.C).Id, "1"))
+	ThrowFail(t, AssertIs((<-a2.C).Id, "1"))
+	ThrowFail(t, AssertIs(len(b.C), 0))
+
+	hub.Unsubscribe(a1)
+	hub.Unsubscribe(a2)
+	ThrowFail(t, AssertIs(hub.HasSubscribers(1), false))
+	ThrowFail(t, AssertIs(hub.HasSubscribers(2), true))
+
+	// a slow subscriber does not block publishers
+	for i := 0; i < bufferSize+5; i++ {
+		hub.Publish(2, &Event{Name: EVENT_UNREAD})
+	}
+	ThrowFail(t, AssertIs(len(b.C), bufferSize))
+}
@@ -0,0 +1,219 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package notice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Unknwon/i18n"
+	"github.com/lunny/log"
+	"github.com/tango-contrib/renders"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/mailer"
+	"github.com/go-tango/wego/setting"
+)
+
+// notifications loaded at once when building digests
+const digestBatchSize = 500
+
+// renders of mail templates, nil disables notification mails
+var mailRenders *renders.Renders
+
+// mailListener mails new notifications to users who chose instant mail,
+// others wait for the digest
+type mailListener struct{}
+
+func (mailListener) NotificationInserted(n *models.Notification) {
+	if mailRenders == nil || n.FromUserId == n.ToUserId {
+		return
+	}
+
+	user, err := models.GetUserById(n.ToUserId)
+	if err != nil {
+		return
+	}
+
+	status := models.NOTICE_MAIL_SKIPPED
+	switch mailMode(user, n) {
+	case models.MAIL_INSTANT:
+		if sendNotificationMail(user, n) {
+			status = models.NOTICE_MAIL_SENT
+		}
+	case models.MAIL_DAILY, models.MAIL_WEEKLY:
+		return
+	}
+	if err := models.UpdateNotificationsMailStatus([]int64{n.Id}, status); err != nil {
+		log.Error("notice: update mail status ", err)
+	}
+}
+
+func (mailListener) NotificationsRead(userId int64) {}
+
+// mail mode of the notification for user, users without verified email
+// get no mails
+func mailMode(user *models.User, n *models.Notification) int {
+	event := models.MailEventOf(n.Action)
+	if event == 0 || !user.IsActive || user.IsForbid || user.Email == "" {
+		return models.MAIL_OFF
+	}
+	modes, err := models.GetMailModes(user.Id)
+	if err != nil {
+		log.Error("notice: get mail modes ", err)
+	}
+	return modes[event]
+}
+
+func userLang(user *models.User) string {
+	if lang := i18n.GetLangByIndex(user.Lang); lang != "" {
+		return lang
+	}
+	return i18n.GetLangByIndex(setting.DefaultLang)
+}
+
+// Text describes the notification in lang, like "xx replied to your comment in yy"
+func Text(lang string, n *models.Notification) string {
+	var from string
+	if user := n.FromUser(); user != nil {
+		from = user.NickName
+	}
+
+	switch n.Action {
+	case setting.NOTICE_TYPE_REPLY:
+		return i18n.Tr(lang, "mail.notice_reply", from, n.Title)
+	case setting.NOTICE_TYPE_MENTION:
+		return i18n.Tr(lang, "mail.notice_mention", from, n.Title)
+	case setting.NOTICE_TYPE_FOLLOW:
+		return i18n.Tr(lang, "mail.notice_follow", from)
+	case setting.NOTICE_TYPE_FAVOURITE:
+		return i18n.Tr(lang, "mail.notice_favorite", from, n.Title)
+	}
+	return i18n.Tr(lang, "mail.notice_comment", from, n.Title)
+}
+
+// a notification in mail templates
+type mailItem struct {
+	*models.Notification
+	Text string
+}
+
+func sendNotificationMail(user *models.User, n *models.Notification) bool {
+	lang := userLang(user)
+	item := &mailItem{n, Text(lang, n)}
+
+	data := mailer.GetMailTmplData(lang, user)
+	data["Item"] = item
+	body, err := mailRenders.RenderBytes("mail/notice/instant.html", data)
+	if err != nil {
+		log.Error("notice: render mail ", err)
+		return false
+	}
+
+	msg := mailer.NewMailMessage([]string{user.Email}, item.Text, string(body))
+	msg.Info = fmt.Sprintf("UID: %d, send notification %d mail", user.Id, n.Id)
+	mailer.SendAsync(msg)
+	return true
+}
+
+// runDigests sends digests once a day at setting.DigestHour
+func runDigests() {
+	var lastDay string
+	for now := range time.Tick(time.Minute) {
+		day := now.Format("2006-01-02")
+		if now.Hour() != setting.DigestHour || day == lastDay {
+			continue
+		}
+		lastDay = day
+		SendDigests(now, now.Weekday() == time.Weekday(setting.DigestWeekday))
+	}
+}
+
+// SendDigests mails every user one digest of pending notifications created
+// before now, notifications of weekly events wait unless weekly is true.
+func SendDigests(now time.Time, weekly bool) {
+	pending := make(map[int64][]*models.Notification)
+	var afterId int64
+	for {
+		notifications, err := models.FindMailPendingNotifications(now, afterId, digestBatchSize)
+		if err != nil {
+			log.Error("notice: find pending notifications ", err)
+			return
+		}
+		for _, n := range notifications {
+			pending[n.ToUserId] = append(pending[n.ToUserId], n)
+			afterId = n.Id
+		}
+		if len(notifications) < digestBatchSize {
+			break
+		}
+	}
+
+	for userId, notifications := range pending {
+		sendDigest(userId, notifications, weekly)
+	}
+	log.Info("notice: digests checked for", len(pending), "users")
+}
+
+func sendDigest(userId int64, notifications []*models.Notification, weekly bool) {
+	var items []*mailItem
+	var sent, skipped []int64
+
+	user, err := models.GetUserById(userId)
+	lang := ""
+	if err == nil {
+		lang = userLang(user)
+	}
+
+	for _, n := range notifications {
+		if err != nil {
+			skipped = append(skipped, n.Id)
+			continue
+		}
+
+		mode := mailMode(user, n)
+		switch {
+		case mode == models.MAIL_OFF || !n.IsUnread():
+			// read notifications need no mail
+			skipped = append(skipped, n.Id)
+		case mode == models.MAIL_WEEKLY && !weekly:
+		default:
+			items = append(items, &mailItem{n, Text(lang, n)})
+			sent = append(sent, n.Id)
+		}
+	}
+
+	if len(items) > 0 {
+		data := mailer.GetMailTmplData(lang, user)
+		data["Items"] = items
+		body, err := mailRenders.RenderBytes("mail/notice/digest.html", data)
+		if err != nil {
+			log.Error("notice: render digest ", err)
+			return
+		}
+
+		subject := i18n.Tr(lang, "mail.digest_subject", len(items))
+		msg := mailer.NewMailMessage([]string{user.Email}, subject, string(body))
+		msg.Info = fmt.Sprintf("UID: %d, send digest of %d notifications", user.Id, len(items))
+		mailer.SendAsync(msg)
+	}
+
+	if err := models.UpdateNotificationsMailStatus(sent, models.NOTICE_MAIL_SENT); err != nil {
+		log.Error("notice: update mail status ", err)
+	}
+	if err := models.UpdateNotificationsMailStatus(skipped, models.NOTICE_MAIL_SKIPPED); err != nil {
+		log.Error("notice: update mail status ", err)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package notice
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lunny/log"
+	"github.com/tango-contrib/renders"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/utils"
+)
+
+const (
+	EVENT_NOTIFICATION = "notification"
+	EVENT_UNREAD       = "unread"
+)
+
+// the site hub, fed by models.InsertNotification
+var DefaultHub = NewHub()
+
+// Init feeds DefaultHub with new notifications and read status changes,
+// and starts mailing notifications rendered with r
+func Init(r *renders.Renders) {
+	models.AddNotificationListener(hubListener{DefaultHub})
+
+	mailRenders = r
+	models.AddNotificationListener(mailListener{})
+	go runDigests()
+}
+
+type hubListener struct {
+	hub *Hub
+}
+
+func (l hubListener) NotificationInserted(n *models.Notification) {
+	if n.FromUserId == n.ToUserId || !l.hub.HasSubscribers(n.ToUserId) {
+		return
+	}
+	if event := NotificationEvent(n); event != nil {
+		l.hub.Publish(n.ToUserId, event)
+	}
+	l.hub.Publish(n.ToUserId, UnreadEvent(n.ToUserId))
+}
+
+func (l hubListener) NotificationsRead(userId int64) {
+	if !l.hub.HasSubscribers(userId) {
+		return
+	}
+	l.hub.Publish(userId, UnreadEvent(userId))
+}
+
+type fromUser struct {
+	UserName string `json:"user_name"`
+	NickName string `json:"nick_name"`
+	Avatar   string `json:"avatar"`
+	Link     string `json:"link"`
+}
+
+type notificationPayload struct {
+	Id          int64     `json:"id"`
+	Action      int       `json:"action"`
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Floor       int       `json:"floor"`
+	ContentHtml string    `json:"content_html"`
+	From        *fromUser `json:"from"`
+	Created     time.Time `json:"created"`
+}
+
+// NotificationEvent is the event of a new notification, its id is the
+// notification id so that browsers can resume from it
+func NotificationEvent(n *models.Notification) *Event {
+	payload := notificationPayload{
+		Id:          n.Id,
+		Action:      n.Action,
+		Title:       n.Title,
+		Link:        n.Link(),
+		Floor:       n.Floor,
+		ContentHtml: n.GetContentCache(),
+		Created:     n.Created,
+	}
+	if user := n.FromUser(); user != nil {
+		payload.From = &fromUser{
+			UserName: user.UserName,
+			NickName: user.NickName,
+			Avatar:   user.AvatarLink48(),
+			Link:     user.Link(),
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Error("notice: marshal notification ", err)
+		return nil
+	}
+	return &Event{Id: utils.ToStr(n.Id), Name: EVENT_NOTIFICATION, Data: data}
+}
+
+// UnreadEvent carries the unread notification count of user
+func UnreadEvent(userId int64) *Event {
+	data, _ := json.Marshal(map[string]int64{
+		"count": models.GetUnreadNotificationCount(userId),
+	})
+	return &Event{Name: EVENT_UNREAD, Data: data}
+}
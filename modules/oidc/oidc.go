@@ -0,0 +1,210 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package oidc is a social-auth provider of any OpenID Connect identity
+// provider, configured with its discovery document.
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-tango/social-auth"
+	"github.com/go-tango/social-auth/apps"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// SocialOIDC is the social type of the provider. social-auth only accepts
+// its own fixed types, so it takes the type of Dropbox which is never
+// registered by wego.
+const SocialOIDC = social.SocialDropbox
+
+// Discovery is the part of the discovery document used for login
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// Claims names the user info claims of new accounts
+type Claims struct {
+	UserName string
+	NickName string
+	Email    string
+}
+
+// Profile is the user of the identity provider
+type Profile struct {
+	Subject       string
+	UserName      string
+	NickName      string
+	Email         string
+	EmailVerified bool
+}
+
+// Provider logs users in with the identity provider, the endpoints are
+// discovered on first use and again after a failure.
+type Provider struct {
+	apps.BaseProvider
+	Name         string
+	Path         string
+	DiscoveryURL string
+	Claims       Claims
+
+	lock      sync.Mutex
+	discovery *Discovery
+}
+
+var _ social.Provider = new(Provider)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// New returns the provider served at /login/PATH, scopes are separated
+// by spaces and always have openid
+func New(name, path, discoveryURL, clientId, secret, scopes string, claims Claims) *Provider {
+	p := &Provider{
+		Name:         name,
+		Path:         path,
+		DiscoveryURL: discoveryURL,
+		Claims:       claims,
+	}
+	p.App = p
+	p.ClientId = clientId
+	p.ClientSecret = secret
+	p.Scope = scopes
+	if !strings.Contains(" "+scopes+" ", " openid ") {
+		p.Scope = strings.TrimSpace("openid " + scopes)
+	}
+	p.RedirectURL = social.DefaultAppUrl + "login/" + path + "/access"
+	return p
+}
+
+func (p *Provider) GetType() social.SocialType {
+	return SocialOIDC
+}
+
+func (p *Provider) GetName() string {
+	return p.Name
+}
+
+func (p *Provider) GetPath() string {
+	return p.Path
+}
+
+// GetConfig returns the oauth2 config with discovered endpoints
+func (p *Provider) GetConfig() *social.Config {
+	config := p.BaseProvider.GetConfig()
+	if d, err := p.Discover(); err == nil {
+		config.AuthURL = d.AuthorizationEndpoint
+		config.TokenURL = d.TokenEndpoint
+	}
+	return config
+}
+
+// Discover gets the discovery document, the issuer must be the url
+// without /.well-known/openid-configuration
+func (p *Provider) Discover() (*Discovery, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d Discovery
+	if err := getJSON(p.DiscoveryURL, "", &d); err != nil {
+		return nil, err
+	}
+	issuer := strings.TrimSuffix(strings.TrimSuffix(p.DiscoveryURL, discoveryPath), "/")
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc: issuer %q of discovery does not match %q", d.Issuer, issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("oidc: discovery of %s misses endpoints", issuer)
+	}
+	p.discovery = &d
+	return &d, nil
+}
+
+// UserInfo gets the claims of the user of token
+func (p *Provider) UserInfo(tok *social.Token) (map[string]interface{}, error) {
+	d, err := p.Discover()
+	if err != nil {
+		return nil, err
+	}
+	claims := make(map[string]interface{})
+	if err := getJSON(d.UserinfoEndpoint, tok.AccessToken, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Profile maps the user info claims with p.Claims
+func (p *Provider) Profile(tok *social.Token) (*Profile, error) {
+	claims, err := p.UserInfo(tok)
+	if err != nil {
+		return nil, err
+	}
+	profile := &Profile{
+		Subject:  claimString(claims, "sub"),
+		UserName: claimString(claims, p.Claims.UserName),
+		NickName: claimString(claims, p.Claims.NickName),
+		Email:    claimString(claims, p.Claims.Email),
+	}
+	// some providers send the boolean as string
+	profile.EmailVerified = claimString(claims, "email_verified") == "true"
+	return profile, nil
+}
+
+// GetIndentify returns the subject, unique in the identity provider
+func (p *Provider) GetIndentify(tok *social.Token) (string, error) {
+	profile, err := p.Profile(tok)
+	if err != nil {
+		return "", err
+	}
+	return profile.Subject, nil
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	if name == "" || claims[name] == nil {
+		return ""
+	}
+	return fmt.Sprint(claims[name])
+}
+
+func getJSON(url, accessToken string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
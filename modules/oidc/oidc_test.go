@@ -0,0 +1,130 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package oidc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-tango/social-auth"
+)
+
+// newIdP starts a stand-in identity provider, issuer overrides the issuer
+// of its discovery document when not empty
+func newIdP(issuer string) *httptest.Server {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		iss := issuer
+		if iss == "" {
+			iss = srv.URL
+		}
+		writeJSON(w, map[string]string{
+			"issuer":                 iss,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"userinfo_endpoint":      srv.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "good-code" || r.PostFormValue("client_secret") != "secret" {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"access_token": "access-1",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     "header.payload.signature",
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-1" {
+			http.Error(w, "invalid_token", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"sub":            "u-42",
+			"login":          "jdoe",
+			"name":           "Jane Doe",
+			"mail":           "jane@corp.example",
+			"email_verified": true,
+		})
+	})
+	return srv
+}
+
+func TestLogin(t *testing.T) {
+	srv := newIdP("")
+	defer srv.Close()
+
+	p := New("Corp", "corp", srv.URL+discoveryPath, "wego", "secret", "profile email",
+		Claims{UserName: "login", NickName: "name", Email: "mail"})
+	if p.Scope != "openid profile email" {
+		t.Errorf("scope = %q", p.Scope)
+	}
+
+	config := p.GetConfig()
+	if config.AuthURL != srv.URL+"/authorize" || config.TokenURL != srv.URL+"/token" {
+		t.Fatalf("config %+v", config)
+	}
+
+	trans := &social.Transport{Config: config, Transport: http.DefaultTransport}
+	if _, err := trans.Exchange("bad-code"); err == nil {
+		t.Error("bad code is exchanged")
+	}
+	trans.Token = nil
+	tok, err := trans.Exchange("good-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := p.Profile(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Profile{"u-42", "jdoe", "Jane Doe", "jane@corp.example", true}
+	if *profile != want {
+		t.Errorf("profile %+v, want %+v", *profile, want)
+	}
+
+	if id, err := p.GetIndentify(tok); err != nil || id != "u-42" {
+		t.Errorf("GetIndentify = %q, %v", id, err)
+	}
+	if _, err := p.GetIndentify(&social.Token{AccessToken: "stolen"}); err == nil {
+		t.Error("bad access token is accepted")
+	}
+}
+
+func TestIssuerMismatch(t *testing.T) {
+	srv := newIdP("https://evil.example")
+	defer srv.Close()
+
+	p := New("Corp", "corp", srv.URL+discoveryPath, "wego", "secret", "openid", Claims{})
+	if _, err := p.Discover(); err == nil {
+		t.Error("discovery of another issuer is accepted")
+	}
+	if config := p.GetConfig(); config.AuthURL != "" {
+		t.Errorf("auth url %q of bad discovery", config.AuthURL)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package password hashes passwords as $algo$params$salt$hash with bcrypt,
+// scrypt or argon2id, and still verifies the old pbkdf2 and discuz hashes.
+package password
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// hash algorithms
+const (
+	BCRYPT   = "bcrypt"
+	SCRYPT   = "scrypt"
+	ARGON2ID = "argon2id"
+)
+
+const (
+	saltSize = 16
+	keySize  = 32
+
+	// bcrypt refuses longer passwords
+	BcryptMaxBytes = 72
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("password: unknown algorithm")
+	ErrBadParams        = errors.New("password: bad params")
+)
+
+var b64 = base64.RawStdEncoding
+
+// Config is the algorithm and cost of new hashes
+type Config struct {
+	Algorithm     string
+	BcryptCost    int
+	ScryptN       int
+	ScryptR       int
+	ScryptP       int
+	Argon2Time    int
+	Argon2Memory  int // KiB
+	Argon2Threads int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Algorithm:     ARGON2ID,
+		BcryptCost:    bcrypt.DefaultCost,
+		ScryptN:       32768,
+		ScryptR:       8,
+		ScryptP:       1,
+		Argon2Time:    3,
+		Argon2Memory:  64 * 1024,
+		Argon2Threads: 2,
+	}
+}
+
+// params of the algorithm as saved in hashes
+func (c *Config) params() (string, error) {
+	switch c.Algorithm {
+	case BCRYPT:
+		return fmt.Sprintf("c=%d", c.BcryptCost), nil
+	case SCRYPT:
+		return fmt.Sprintf("n=%d,r=%d,p=%d", c.ScryptN, c.ScryptR, c.ScryptP), nil
+	case ARGON2ID:
+		return fmt.Sprintf("m=%d,t=%d,p=%d", c.Argon2Memory, c.Argon2Time, c.Argon2Threads), nil
+	}
+	return "", ErrUnknownAlgorithm
+}
+
+// Hash encodes password with a random salt
+func (c *Config) Hash(password string) (string, error) {
+	params, err := c.params()
+	if err != nil {
+		return "", err
+	}
+
+	if c.Algorithm == BCRYPT {
+		// bcrypt makes its own salt, "$2a$10$" + 22 chars salt + 31 chars hash
+		out, err := bcrypt.GenerateFromPassword([]byte(password), c.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("$%s$%s$%s$%s", BCRYPT, params, out[7:29], out[29:]), nil
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := derive(c.Algorithm, parseParams(params), password, salt)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("$%s$%s$%s$%s", c.Algorithm, params, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// NeedsRehash reports whether encoded is not made with the config,
+// old formats always need to be rehashed
+func (c *Config) NeedsRehash(encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[0] != "" {
+		return true
+	}
+	params, err := c.params()
+	return err != nil || parts[1] != c.Algorithm || parts[2] != params
+}
+
+// parseParams parses "n=32768,r=8,p=1", bad values are left out
+func parseParams(s string) map[string]int {
+	params := make(map[string]int)
+	for _, kv := range strings.Split(s, ",") {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			continue
+		}
+		if v, err := strconv.Atoi(kv[i+1:]); err == nil && v > 0 {
+			params[kv[:i]] = v
+		}
+	}
+	return params
+}
+
+func derive(algo string, params map[string]int, password string, salt []byte) ([]byte, error) {
+	switch algo {
+	case SCRYPT:
+		n, r, p := params["n"], params["r"], params["p"]
+		if n == 0 || r == 0 || p == 0 {
+			return nil, ErrBadParams
+		}
+		return scrypt.Key([]byte(password), salt, n, r, p, keySize)
+	case ARGON2ID:
+		m, t, p := params["m"], params["t"], params["p"]
+		if m == 0 || t == 0 || p == 0 || p > 255 {
+			return nil, ErrBadParams
+		}
+		return argon2.IDKey([]byte(password), salt, uint32(t), uint32(m), uint8(p), keySize), nil
+	}
+	return nil, ErrUnknownAlgorithm
+}
+
+// Verify checks password against encoded of any supported format
+func Verify(password, encoded string) bool {
+	if !strings.HasPrefix(encoded, "$") {
+		return verifyLegacy(password, encoded)
+	}
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return false
+	}
+	algo, params, salt, hash := parts[1], parseParams(parts[2]), parts[3], parts[4]
+
+	if algo == BCRYPT {
+		cost := params["c"]
+		if cost == 0 {
+			return false
+		}
+		out := fmt.Sprintf("$2a$%02d$%s%s", cost, salt, hash)
+		return bcrypt.CompareHashAndPassword([]byte(out), []byte(password)) == nil
+	}
+
+	rawSalt, err := b64.DecodeString(salt)
+	if err != nil {
+		return false
+	}
+	want, err := b64.DecodeString(hash)
+	if err != nil {
+		return false
+	}
+	key, err := derive(algo, params, password, rawSalt)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, want) == 1
+}
+
+// verifyLegacy checks the hashes before versioned format, 39 chars
+// "salt$md5(md5(password)+salt)" of discuz accounts and "salt$pbkdf2"
+func verifyLegacy(password, encoded string) bool {
+	if len(encoded) == 39 {
+		salt := encoded[:6]
+		want := encoded[7:]
+		return subtle.ConstantTimeCompare([]byte(md5Hex(md5Hex(password)+salt)), []byte(want)) == 1
+	}
+
+	if len(encoded) <= 11 {
+		return false
+	}
+	salt := encoded[:10]
+	want := encoded[11:]
+	key := pbkdf2.Key([]byte(password), []byte(salt), 10000, 50, sha256.New)
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(key)), []byte(want)) == 1
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
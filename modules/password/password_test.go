@@ -0,0 +1,125 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+// cheap costs keep tests fast
+func testConfig(algo string) Config {
+	return Config{
+		Algorithm:     algo,
+		BcryptCost:    4,
+		ScryptN:       1024,
+		ScryptR:       8,
+		ScryptP:       1,
+		Argon2Time:    1,
+		Argon2Memory:  1024,
+		Argon2Threads: 1,
+	}
+}
+
+func TestHashVerify(t *testing.T) {
+	for _, algo := range []string{BCRYPT, SCRYPT, ARGON2ID} {
+		c := testConfig(algo)
+		encoded, err := c.Hash("secret pass")
+		if err != nil {
+			t.Fatal(algo, err)
+		}
+		if !strings.HasPrefix(encoded, "$"+algo+"$") || strings.Count(encoded, "$") != 4 || len(encoded) > 128 {
+			t.Errorf("%s: hash %s", algo, encoded)
+		}
+		if !Verify("secret pass", encoded) {
+			t.Errorf("%s: password not verified", algo)
+		}
+		if Verify("secret pas", encoded) {
+			t.Errorf("%s: wrong password verified", algo)
+		}
+		if c.NeedsRehash(encoded) {
+			t.Errorf("%s: same config needs rehash", algo)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	c := testConfig(SCRYPT)
+	encoded, _ := c.Hash("pass")
+
+	cheaper := c
+	cheaper.ScryptN = 512
+	if !cheaper.NeedsRehash(encoded) {
+		t.Error("other params do not need rehash")
+	}
+	other := testConfig(ARGON2ID)
+	if !other.NeedsRehash(encoded) {
+		t.Error("other algorithm does not need rehash")
+	}
+	if !c.NeedsRehash("0123456789$abcdef") {
+		t.Error("legacy hash does not need rehash")
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	// utils.EncodePassword("pass", "abcdefghij")
+	pbkdf2 := "abcdefghij$e09ae6cb53182f9d72e7f6a3fb8ea41a4a380cc0d7a6cb556da5927935f31b19641e8e8faa7557e93f8b09119d2fa8c88be6"
+	if !Verify("pass", pbkdf2) || Verify("pas", pbkdf2) {
+		t.Error("pbkdf2 hash")
+	}
+
+	discuz := "a1b2c3$" + md5Hex(md5Hex("pass")+"a1b2c3")
+	if !Verify("pass", discuz) || Verify("pas", discuz) {
+		t.Error("discuz hash")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	breached, err := LoadBreached(strings.NewReader("123456\n" +
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Policy{MinLength: 6, MaxLength: 10, Breached: breached}
+	cases := []struct {
+		password string
+		err      error
+	}{
+		{"abc", ErrTooShort},
+		{"密码密码密码", nil},
+		{"abcdefghijk", ErrTooLong},
+		{"123456", ErrBreached},
+		{"password", ErrBreached},
+		{"goodpass", nil},
+	}
+	for _, c := range cases {
+		if err := p.Check(c.password); err != c.err {
+			t.Errorf("Check(%q) = %v, want %v", c.password, err, c.err)
+		}
+	}
+
+	p = &Policy{MaxLength: 72, MaxBytes: BcryptMaxBytes}
+	if err := p.Check(strings.Repeat("密", 30)); err != ErrTooBig {
+		t.Errorf("Check of 90 bytes = %v, want %v", err, ErrTooBig)
+	}
+	if err := p.Check(strings.Repeat("密", 24)); err != nil {
+		t.Errorf("Check of 72 bytes = %v", err)
+	}
+
+	var none Breached
+	if none.Contains("123456") {
+		t.Error("nil list contains password")
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrTooShort = errors.New("password: too short")
+	ErrTooLong  = errors.New("password: too long")
+	ErrTooBig   = errors.New("password: too many bytes")
+	ErrBreached = errors.New("password: in breached list")
+)
+
+// Policy is what new passwords must meet, lengths count characters and
+// zero is no limit. MaxBytes limits the UTF-8 length for hashes like bcrypt
+// which use at most 72 bytes.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	MaxBytes  int
+	Breached  Breached
+}
+
+// Check returns nil if password meets the policy
+func (p *Policy) Check(password string) error {
+	n := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && n < p.MinLength {
+		return ErrTooShort
+	}
+	if p.MaxLength > 0 && n > p.MaxLength {
+		return ErrTooLong
+	}
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		return ErrTooBig
+	}
+	if p.Breached.Contains(password) {
+		return ErrBreached
+	}
+	return nil
+}
+
+// Breached is the set of sha1 of leaked passwords
+type Breached map[string]struct{}
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func isSha1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// LoadBreached reads a password per line, or the hex sha1 of one as in
+// "SHA1:count" lines of haveibeenpwned downloads
+func LoadBreached(r io.Reader) (Breached, error) {
+	b := make(Breached)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i == 40 {
+			line = line[:i]
+		}
+		if isSha1Hex(line) {
+			b[strings.ToLower(line)] = struct{}{}
+		} else {
+			b[sha1Hex(line)] = struct{}{}
+		}
+	}
+	return b, scanner.Err()
+}
+
+func LoadBreachedFile(path string) (Breached, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadBreached(f)
+}
+
+func (b Breached) Contains(password string) bool {
+	if len(b) == 0 {
+		return false
+	}
+	_, ok := b[sha1Hex(password)]
+	return ok
+}
@@ -15,10 +15,14 @@
 package post
 
 import (
+	"strings"
+
 	"github.com/Unknwon/i18n"
 	"github.com/go-xweb/xweb/validation"
 
 	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/search"
+	"github.com/go-tango/wego/modules/spam"
 	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/setting"
 )
@@ -68,6 +72,16 @@ func (form *PostForm) Valid(v *validation.Validation) {
 }
 
 func (form *PostForm) SavePost(post *models.Post, user *models.User) error {
+	decision := spam.Check(&spam.Content{
+		Kind:  spam.KIND_POST,
+		User:  user,
+		Title: form.Title,
+		Text:  form.Content,
+	})
+	if decision.Action == spam.REJECT {
+		return spam.ErrRejected
+	}
+
 	utils.SetFormValues(form, post)
 	post.CategoryId = form.Category
 	post.TopicId = form.Topic
@@ -76,11 +90,25 @@ func (form *PostForm) SavePost(post *models.Post, user *models.User) error {
 	post.LastAuthorId = user.Id
 	post.CanEdit = true
 	post.ContentCache = utils.RenderMarkdown(form.Content)
+	if decision.Action == spam.HOLD {
+		post.Status = models.POST_STATUS_PENDING
+	}
+
+	if err := post.Insert(); err != nil {
+		return err
+	}
+
+	// held posts wait for moderators, they are not searchable yet
+	if post.IsPending() {
+		holdForReview(models.REPORT_POST, post.Id, post.CategoryId, decision.Reason)
+		return nil
+	}
 
-	// mentioned follow users
-	FilterMentions(user, post.ContentCache)
+	// notify mentioned users
+	FilterMentions(user, post)
 
-	return post.Insert()
+	search.IndexPost(post)
+	return nil
 }
 
 func (form *PostForm) SetFromPost(post *models.Post) {
@@ -91,9 +119,36 @@ func (form *PostForm) SetFromPost(post *models.Post) {
 
 func (form *PostForm) UpdatePost(post *models.Post, user *models.User) error {
 	changes := utils.FormChanges(post, form)
+	// posts keep the category of their topic
+	if form.Topic != post.TopicId {
+		if topic, err := models.GetTopicById(form.Topic); err == nil {
+			form.Category = topic.CategoryId
+			changes = append(changes, "TopicId", "CategoryId")
+		}
+	}
 	if len(changes) == 0 {
 		return nil
 	}
+
+	// edited text is checked like new posts
+	var decision spam.Decision
+	for _, c := range changes {
+		if c == "Title" || c == "Content" {
+			decision = spam.Check(&spam.Content{
+				Kind:  spam.KIND_POST,
+				Id:    post.Id,
+				User:  user,
+				Title: form.Title,
+				Text:  form.Content,
+			})
+			break
+		}
+	}
+	if decision.Action == spam.REJECT {
+		return spam.ErrRejected
+	}
+
+	old := *post
 	utils.SetFormValues(form, post)
 	post.CategoryId = form.Category
 	post.TopicId = form.Topic
@@ -104,6 +159,13 @@ func (form *PostForm) UpdatePost(post *models.Post, user *models.User) error {
 		}
 	}
 
+	// visible posts go back to moderators, hidden ones stay hidden
+	held := decision.Action == spam.HOLD && post.IsVisible()
+	if held {
+		post.Status = models.POST_STATUS_PENDING
+		changes = append(changes, "Status")
+	}
+
 	// update last edit author
 	if post.LastAuthorId != user.Id {
 		post.LastAuthorId = user.Id
@@ -112,7 +174,15 @@ func (form *PostForm) UpdatePost(post *models.Post, user *models.User) error {
 
 	changes = append(changes, "Updated")
 
-	return models.UpdateById(post.Id, post, models.Obj2Table(changes)...)
+	if err := models.UpdateById(post.Id, post, models.Obj2Table(changes)...); err != nil {
+		return err
+	}
+
+	if held {
+		holdForReview(models.REPORT_POST, post.Id, post.CategoryId, decision.Reason)
+	}
+	search.IndexPost(post)
+	return RecordPostRevision(&old, post, user)
 }
 
 func (form *PostForm) Placeholders() map[string]string {
@@ -138,6 +208,7 @@ type PostAdminForm struct {
 	Topic      int64  `form:"type(select);attr(rel,select2)" valid:"Required"`
 	Lang       int    `form:"type(select);attr(rel,select2)"`
 	IsBest     bool   ``
+	CanEdit    bool   ``
 }
 
 func (form *PostAdminForm) Valid(v *validation.Validation) {
@@ -188,25 +259,111 @@ func (form *PostAdminForm) SetToPost(post *models.Post) {
 
 type CommentForm struct {
 	Message string `form:"type(textarea,markdown)" valid:"Required;MinSize(5)"`
+	Parent  int64  `form:"type(hidden)" valid:""`
+}
+
+// SetQuote pre-fills the form to reply the comment with its message quoted
+func (form *CommentForm) SetQuote(comment *models.Comment) {
+	// the message of deleted comments is only shown in its history
+	if comment.Status != models.COMMENT_STATUS_NORMAL {
+		return
+	}
+
+	var lines []string
+	if user := comment.User(); user != nil {
+		lines = append(lines, "#"+utils.ToStr(comment.Floor)+" @"+user.UserName)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(comment.Message), "\n") {
+		lines = append(lines, "> "+line)
+	}
+	form.Message = strings.Join(lines, "\n") + "\n\n"
+	form.Parent = comment.Id
 }
 
 func (form *CommentForm) SaveComment(comment *models.Comment, user *models.User, post *models.Post) error {
+	// only reply to comments of the same post
+	if form.Parent > 0 {
+		if parent, err := models.GetCommentById(form.Parent); err == nil && parent.PostId == post.Id {
+			comment.ParentId = parent.Id
+		}
+	}
+	decision := spam.Check(&spam.Content{
+		Kind: spam.KIND_COMMENT,
+		User: user,
+		Text: form.Message,
+	})
+	if decision.Action == spam.REJECT {
+		return spam.ErrRejected
+	}
+
 	comment.Message = form.Message
 	comment.MessageCache = utils.RenderMarkdown(form.Message)
 	comment.UserId = user.Id
 	comment.PostId = post.Id
+	if decision.Action == spam.HOLD {
+		comment.Status = models.COMMENT_STATUS_PENDING
+	}
 	if err := models.InsertComment(comment); err == nil {
-		post.LastReplyId = user.Id
-		models.UpdateById(post.Id, post, "last_reply_id", "last_replied")
+		if !comment.IsPending() {
+			post.LastReplyId = user.Id
+			models.UpdateById(post.Id, post, "last_reply_id", "last_replied")
+		}
 
 		cnt, _ := models.CountCommentsLTEId(comment.Id)
 		comment.Floor = int(cnt)
-		return models.UpdateById(comment.Id, comment, "floor")
+		if err := models.UpdateById(comment.Id, comment, "floor"); err != nil {
+			return err
+		}
+
+		if comment.IsPending() {
+			holdForReview(models.REPORT_COMMENT, comment.Id, post.CategoryId, decision.Reason)
+			return nil
+		}
+		search.IndexComment(comment, post)
+		return nil
 	} else {
 		return err
 	}
 }
 
+// SetFromComment fills the form to edit the comment
+func (form *CommentForm) SetFromComment(comment *models.Comment) {
+	form.Message = comment.Message
+	form.Parent = comment.ParentId
+}
+
+// UpdateComment saves the edited message, the old one is kept in history
+func (form *CommentForm) UpdateComment(comment *models.Comment, user *models.User) error {
+	if form.Message == comment.Message {
+		return nil
+	}
+
+	// edited text is checked like new comments
+	decision := spam.Check(&spam.Content{
+		Kind: spam.KIND_COMMENT,
+		Id:   comment.Id,
+		User: user,
+		Text: form.Message,
+	})
+	if decision.Action == spam.REJECT {
+		return spam.ErrRejected
+	}
+	if err := UpdateComment(comment, user, form.Message); err != nil {
+		return err
+	}
+
+	// visible comments go back to moderators, hidden ones stay hidden
+	if decision.Action == spam.HOLD && comment.Status == models.COMMENT_STATUS_NORMAL {
+		if err := SetCommentStatus(comment, models.COMMENT_STATUS_PENDING); err != nil {
+			return err
+		}
+		if post := comment.Post(); post != nil {
+			holdForReview(models.REPORT_COMMENT, comment.Id, post.CategoryId, decision.Reason)
+		}
+	}
+	return nil
+}
+
 type CommentAdminForm struct {
 	Create  bool   `form:"-"`
 	User    int    `form:"attr(rel,select2-admin-model);attr(data-model,User)" valid:"Required"`
@@ -241,3 +398,51 @@ func (form *CommentAdminForm) SetToComment(comment *models.Comment) {
 	comment.PostId = int64(form.Post)
 	comment.MessageCache = utils.RenderMarkdown(comment.Message)
 }
+
+type ReportForm struct {
+	Reason int    `form:"type(select);attr(rel,select2)" valid:"Required"`
+	Note   string `form:"type(textarea)" valid:"MaxSize(500)"`
+}
+
+func (form *ReportForm) ReasonSelectData() [][]string {
+	names := map[int]string{
+		models.REPORT_REASON_SPAM:     "post.report_reason_spam",
+		models.REPORT_REASON_ABUSE:    "post.report_reason_abuse",
+		models.REPORT_REASON_OFFTOPIC: "post.report_reason_offtopic",
+		models.REPORT_REASON_OTHER:    "post.report_reason_other",
+	}
+	data := make([][]string, 0, len(models.ReportReasons))
+	for _, reason := range models.ReportReasons {
+		data = append(data, []string{names[reason], utils.ToStr(reason)})
+	}
+	return data
+}
+
+func (form *ReportForm) Valid(v *validation.Validation) {
+	for _, reason := range models.ReportReasons {
+		if reason == form.Reason {
+			return
+		}
+	}
+	v.SetError("Reason", "Not Found")
+}
+
+func (form *ReportForm) Labels() map[string]string {
+	return map[string]string{
+		"Reason": "post.report_reason",
+		"Note":   "post.report_note",
+	}
+}
+
+func (form *ReportForm) Placeholders() map[string]string {
+	return map[string]string{
+		"Note": "post.report_note_placeholder",
+	}
+}
+
+// SaveReport reports the target by user
+func (form *ReportForm) SaveReport(report *models.Report, user *models.User) error {
+	report.Reason = form.Reason
+	report.Note = strings.TrimSpace(form.Note)
+	return Report(report, user)
+}
@@ -0,0 +1,32 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package post
+
+import (
+	"testing"
+
+	"github.com/go-tango/wego/models"
+	. "github.com/go-tango/wego/modules/utils"
+)
+
+func TestSetQuoteNotVisible(t *testing.T) {
+	for _, status := range []int{models.COMMENT_STATUS_DELETED, models.COMMENT_STATUS_PENDING, models.COMMENT_STATUS_HIDDEN} {
+		comment := &models.Comment{Id: 2, Floor: 2, Message: "deleted text", Status: status}
+		form := CommentForm{}
+		form.SetQuote(comment)
+		ThrowFail(t, AssertIs(form.Message, ""))
+		ThrowFail(t, AssertIs(form.Parent, int64(0)))
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package post
+
+import (
+	"errors"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/search"
+	"github.com/go-tango/wego/setting"
+)
+
+// actions of moderators on reports
+const (
+	MODERATE_APPROVE = "approve"
+	MODERATE_HIDE    = "hide"
+	MODERATE_DELETE  = "delete"
+	MODERATE_BAN     = "ban"
+)
+
+var (
+	ErrReportSelf      = errors.New("cannot report yourself")
+	ErrReportDuplicate = errors.New("already reported")
+	ErrReportClosed    = errors.New("report is closed")
+	ErrUnknownAction   = errors.New("unknown moderation action")
+	ErrBanAdmin        = errors.New("cannot ban admins")
+	ErrModerateDenied  = errors.New("not a moderator of the category")
+)
+
+// ReportTarget returns the type of reported objects for names used in urls
+func ReportTarget(name string) int {
+	switch name {
+	case "post":
+		return models.REPORT_POST
+	case "comment":
+		return models.REPORT_COMMENT
+	case "user":
+		return models.REPORT_USER
+	}
+	return 0
+}
+
+// reportedAuthor returns the author of the reported object and the
+// category of reported content
+func reportedAuthor(targetType int, targetId int64) (int64, int64, error) {
+	switch targetType {
+	case models.REPORT_POST:
+		post, err := models.GetPostById(targetId)
+		if err != nil {
+			return 0, 0, err
+		}
+		return post.UserId, post.CategoryId, nil
+	case models.REPORT_COMMENT:
+		comment, err := models.GetCommentById(targetId)
+		if err != nil {
+			return 0, 0, err
+		}
+		post := comment.Post()
+		if comment.IsDeleted() || post == nil {
+			return 0, 0, models.ErrNotExist
+		}
+		return comment.UserId, post.CategoryId, nil
+	case models.REPORT_USER:
+		user, err := models.GetUserById(targetId)
+		if err != nil {
+			return 0, 0, err
+		}
+		return user.Id, 0, nil
+	}
+	return 0, 0, models.ErrNotExist
+}
+
+// Report saves a report of user on the target, reported posts and comments
+// become pending once they have setting.ModerationPendingReports open reports
+func Report(report *models.Report, user *models.User) error {
+	author, categoryId, err := reportedAuthor(report.TargetType, report.TargetId)
+	if err != nil {
+		return err
+	}
+	if author == user.Id {
+		return ErrReportSelf
+	}
+	if has, err := models.HasOpenReport(user.Id, report.TargetType, report.TargetId); err != nil {
+		return err
+	} else if has {
+		return ErrReportDuplicate
+	}
+
+	report.UserId = user.Id
+	report.CategoryId = categoryId
+	report.Status = models.REPORT_OPEN
+	if err := models.InsertReport(report); err != nil {
+		return err
+	}
+
+	if setting.ModerationPendingReports <= 0 || report.TargetType == models.REPORT_USER {
+		return nil
+	}
+	cnt, err := models.CountOpenReports(report.TargetType, report.TargetId)
+	if err != nil {
+		return err
+	}
+	if cnt >= int64(setting.ModerationPendingReports) {
+		return setPending(report.TargetType, report.TargetId)
+	}
+	return nil
+}
+
+// setPending hides visible content until moderators review it
+func setPending(targetType int, targetId int64) error {
+	switch targetType {
+	case models.REPORT_POST:
+		post, err := models.GetPostById(targetId)
+		if err != nil || !post.IsVisible() {
+			return err
+		}
+		return SetPostStatus(post, models.POST_STATUS_PENDING)
+	case models.REPORT_COMMENT:
+		comment, err := models.GetCommentById(targetId)
+		if err != nil || !comment.IsVisible() || comment.IsDeleted() {
+			return err
+		}
+		return SetCommentStatus(comment, models.COMMENT_STATUS_PENDING)
+	}
+	return nil
+}
+
+// holdForReview opens a report from system for content held by spam
+// checks, reason is the note of the report. The moderation queue lists
+// reports, so held content always gets one.
+func holdForReview(targetType int, targetId, categoryId int64, reason string) {
+	if runes := []rune(reason); len(runes) > 500 {
+		reason = string(runes[:500])
+	}
+	report := models.Report{
+		TargetType: targetType,
+		TargetId:   targetId,
+		CategoryId: categoryId,
+		Reason:     models.REPORT_REASON_SPAM,
+		Note:       reason,
+		Status:     models.REPORT_OPEN,
+	}
+	if err := models.InsertReport(&report); err != nil {
+		log.Error("moderation: hold ", err)
+	}
+}
+
+// SetPostStatus changes the status of post and keeps the search index in
+// step, only visible posts are searchable
+func SetPostStatus(post *models.Post, status int) error {
+	post.Status = status
+	if err := models.UpdateById(post.Id, post, "status"); err != nil {
+		return err
+	}
+
+	if post.IsVisible() {
+		search.IndexPost(post)
+		var comments []*models.Comment
+		if err := models.GetCommentsByPostId(&comments, post.Id); err == nil {
+			for _, comment := range comments {
+				if !comment.IsDeleted() {
+					search.IndexComment(comment, post)
+				}
+			}
+		}
+	} else {
+		search.RemovePost(post.Id)
+	}
+	return nil
+}
+
+// SetCommentStatus changes the status of comment and keeps the search index
+// in step
+func SetCommentStatus(comment *models.Comment, status int) error {
+	comment.Status = status
+	if err := models.UpdateById(comment.Id, comment, "status"); err != nil {
+		return err
+	}
+
+	if comment.Status == models.COMMENT_STATUS_NORMAL {
+		if post := comment.Post(); post != nil && post.IsVisible() {
+			search.IndexComment(comment, post)
+		}
+	} else {
+		search.RemoveComment(comment.Id)
+	}
+	return nil
+}
+
+// Moderate applies the action of admin to the target of report, all open
+// reports on the same target are closed with it. Moderators of the category
+// can handle reports on content, banning needs PERM_BAN_USER.
+func Moderate(report *models.Report, action string, admin *models.User) error {
+	if !report.IsOpen() {
+		return ErrReportClosed
+	}
+	if !admin.Can(models.PERM_MODERATE, report.CategoryId) ||
+		action == MODERATE_BAN && !admin.Can(models.PERM_BAN_USER, 0) {
+		return ErrModerateDenied
+	}
+
+	var status int
+	var err error
+	switch action {
+	case MODERATE_APPROVE:
+		status = models.REPORT_APPROVED
+		// content held by spam checks has a report from system
+		held, _ := models.HasOpenReport(0, report.TargetType, report.TargetId)
+		err = setTargetStatus(report, models.POST_STATUS_NORMAL, models.COMMENT_STATUS_NORMAL)
+		if err == nil && held {
+			err = publishHeld(report)
+		}
+	case MODERATE_HIDE:
+		status = models.REPORT_HIDDEN
+		err = setTargetStatus(report, models.POST_STATUS_HIDDEN, models.COMMENT_STATUS_HIDDEN)
+	case MODERATE_DELETE:
+		status = models.REPORT_DELETED
+		err = deleteTarget(report, admin)
+	case MODERATE_BAN:
+		status = models.REPORT_BANNED
+		err = banTarget(report)
+	default:
+		return ErrUnknownAction
+	}
+	if err != nil {
+		return err
+	}
+
+	return models.ResolveReports(report.TargetType, report.TargetId, status, admin.Id)
+}
+
+func setTargetStatus(report *models.Report, postStatus, commentStatus int) error {
+	switch report.TargetType {
+	case models.REPORT_POST:
+		post, err := models.GetPostById(report.TargetId)
+		if err != nil {
+			return err
+		}
+		if post.Status == postStatus {
+			return nil
+		}
+		return SetPostStatus(post, postStatus)
+	case models.REPORT_COMMENT:
+		comment, err := models.GetCommentById(report.TargetId)
+		if err != nil {
+			return err
+		}
+		if comment.IsDeleted() || comment.Status == commentStatus {
+			return nil
+		}
+		return SetCommentStatus(comment, commentStatus)
+	}
+	return nil
+}
+
+// publishHeld notifies mentioned users and authors and counts replies of
+// approved content held by spam checks, saving it skipped them
+func publishHeld(report *models.Report) error {
+	switch report.TargetType {
+	case models.REPORT_POST:
+		post, err := models.GetPostById(report.TargetId)
+		if err != nil {
+			return err
+		}
+		user, err := models.GetUserById(post.UserId)
+		if err != nil {
+			return err
+		}
+		FilterMentions(user, post)
+	case models.REPORT_COMMENT:
+		comment, err := models.GetCommentById(report.TargetId)
+		if err != nil {
+			return err
+		}
+		post := comment.Post()
+		user := comment.User()
+		if post == nil || user == nil || comment.IsDeleted() {
+			return nil
+		}
+		post.LastReplyId = user.Id
+		if err := models.UpdateById(post.Id, post, "last_reply_id", "last_replied"); err != nil {
+			return err
+		}
+		FilterCommentMentions(user, post, comment)
+		PostReplysCount(post)
+	}
+	return nil
+}
+
+func deleteTarget(report *models.Report, admin *models.User) error {
+	switch report.TargetType {
+	case models.REPORT_POST:
+		post, err := models.GetPostById(report.TargetId)
+		if err != nil {
+			return err
+		}
+		return DeletePost(post, admin)
+	case models.REPORT_COMMENT:
+		comment, err := models.GetCommentById(report.TargetId)
+		if err != nil {
+			return err
+		}
+		if !comment.IsDeleted() {
+			return DeleteComment(comment, admin)
+		}
+	case models.REPORT_USER:
+		// users are never deleted from the queue, they can be banned
+		return ErrUnknownAction
+	}
+	return nil
+}
+
+// banTarget forbids the reported user, or the author of reported content
+// whose content is hidden as well. Admins are never banned.
+func banTarget(report *models.Report) error {
+	user := report.TargetUser()
+	if user == nil {
+		return models.ErrNotExist
+	}
+	if user.IsAdmin {
+		return ErrBanAdmin
+	}
+
+	user.IsForbid = true
+	if err := models.UpdateById(user.Id, user, "is_forbid"); err != nil {
+		return err
+	}
+	log.Info("moderation: banned user", user.UserName)
+
+	if report.TargetType == models.REPORT_USER {
+		return nil
+	}
+	return setTargetStatus(report, models.POST_STATUS_HIDDEN, models.COMMENT_STATUS_HIDDEN)
+}
@@ -18,10 +18,12 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/lunny/log"
 
 	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/search"
 	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/setting"
 )
@@ -37,19 +39,57 @@ func ListPostsOfTopic(topic *models.Topic, posts *[]models.Post) (int64, error)
 
 var mentionRegexp = regexp.MustCompile(`\B@([\d\w-_]*)`)
 
-func FilterMentions(user *models.User, content string) {
-	matches := mentionRegexp.FindAllStringSubmatch(content, -1)
-	mentions := make([]string, 0, len(matches))
-	for _, m := range matches {
-		if len(m) > 1 {
-			mentions = append(mentions, m[1])
+// FilterMentions notifies users @mentioned in a new post
+func FilterMentions(user *models.User, post *models.Post) {
+	notified := map[int64]bool{user.Id: true}
+	for _, m := range mentionRegexp.FindAllStringSubmatch(post.Content, -1) {
+		if len(m) < 2 || m[1] == "" {
+			continue
 		}
+
+		mentioned, err := models.GetUserByName(m[1])
+		if err != nil || notified[mentioned.Id] {
+			continue
+		}
+		notified[mentioned.Id] = true
+
+		var notification = models.Notification{
+			FromUserId:   user.Id,
+			ToUserId:     mentioned.Id,
+			Action:       setting.NOTICE_TYPE_MENTION,
+			Title:        post.Title,
+			TargetId:     post.Id,
+			Uri:          fmt.Sprintf("post/%d", post.Id),
+			Lang:         setting.DefaultLang,
+			Content:      post.Content,
+			ContentCache: post.ContentCache,
+			Status:       setting.NOTICE_UNREAD,
+		}
+		if err := models.InsertNotification(&notification); err != nil {
+			log.Error("FilterMentions ", err)
+		}
+	}
+}
+
+// NotifyFavorite tells the author that user favorited the post
+func NotifyFavorite(user *models.User, post *models.Post) {
+	if user.Id == post.UserId {
+		return
+	}
+
+	var notification = models.Notification{
+		FromUserId: user.Id,
+		ToUserId:   post.UserId,
+		Action:     setting.NOTICE_TYPE_FAVOURITE,
+		Title:      post.Title,
+		TargetId:   post.Id,
+		Uri:        fmt.Sprintf("post/%d", post.Id),
+		Lang:       setting.DefaultLang,
+		Status:     setting.NOTICE_UNREAD,
+	}
+	if err := models.InsertNotification(&notification); err != nil {
+		log.Error("NotifyFavorite ", err)
 	}
-	// var users []*User
-	// num, err := Users().Filter("UserName__in", mentions).Filter("Follow__User", user.Id).All(&users)
-	// if err == nil && num > 0 {
-	// TODO mention email to user
-	// }
 }
 
 func PostBrowsersAdd(uid int64, ip string, post *models.Post) {
@@ -74,23 +114,145 @@ func PostReplysCount(post *models.Post) {
 	cnt, err := models.CountCommentsByPostId(post.Id)
 	if err == nil {
 		post.Replys = int(cnt)
-		//disable post editable
-		post.CanEdit = false
-		err = models.UpdateById(post.Id, post, "replys", "can_edit")
+		err = models.UpdateById(post.Id, post, "replys")
 	}
 	if err != nil {
 		log.Error("PostReplysCount ", err)
 	}
 }
 
+// RecordPostRevision stores the title and content of post edited by user as
+// a new revision. old is the post before edit, it is stored first as the
+// original version for posts without revisions.
+func RecordPostRevision(old, post *models.Post, user *models.User) error {
+	if old.Title == post.Title && old.Content == post.Content {
+		return nil
+	}
+
+	if cnt, err := models.CountPostRevisions(post.Id); err != nil {
+		return err
+	} else if cnt == 0 {
+		editor := old.LastAuthorId
+		if editor == 0 {
+			editor = old.UserId
+		}
+		created := old.Updated
+		if created.IsZero() {
+			created = old.Created
+		}
+		original := models.PostRevision{
+			PostId:  old.Id,
+			UserId:  editor,
+			Title:   old.Title,
+			Content: old.Content,
+			Created: created,
+		}
+		if err := models.InsertPostRevision(&original); err != nil {
+			return err
+		}
+	}
+
+	rev := models.PostRevision{
+		PostId:  post.Id,
+		UserId:  user.Id,
+		Title:   post.Title,
+		Content: post.Content,
+		Created: time.Now(),
+	}
+	return models.InsertPostRevision(&rev)
+}
+
+// RollbackPost restores title and content of post to the revision, the
+// rollback itself is recorded as a new revision by user.
+func RollbackPost(post *models.Post, rev *models.PostRevision, user *models.User) error {
+	old := *post
+
+	post.Title = rev.Title
+	post.Content = rev.Content
+	post.ContentCache = utils.RenderMarkdown(rev.Content)
+	post.LastAuthorId = user.Id
+	if err := models.UpdateById(post.Id, post, "title", "content", "content_cache", "last_author_id", "updated"); err != nil {
+		return err
+	}
+
+	search.IndexPost(post)
+	return RecordPostRevision(&old, post, user)
+}
+
+// UpdateComment changes the message of comment by user, the old message is
+// kept in comment history.
+func UpdateComment(comment *models.Comment, user *models.User, message string) error {
+	history := models.CommentHistory{
+		CommentId: comment.Id,
+		UserId:    user.Id,
+		Message:   comment.Message,
+		Action:    models.COMMENT_HISTORY_EDIT,
+	}
+	if err := models.InsertCommentHistory(&history); err != nil {
+		return err
+	}
+
+	comment.Message = message
+	comment.MessageCache = utils.RenderMarkdown(message)
+	comment.Edited = time.Now()
+	if err := models.UpdateById(comment.Id, comment, "message", "message_cache", "edited"); err != nil {
+		return err
+	}
+
+	if post := comment.Post(); post != nil {
+		search.IndexComment(comment, post)
+	}
+	return nil
+}
+
+// DeleteComment soft deletes comment by user, the comment row is kept so
+// that replies stay in thread, the message is kept in comment history.
+func DeleteComment(comment *models.Comment, user *models.User) error {
+	history := models.CommentHistory{
+		CommentId: comment.Id,
+		UserId:    user.Id,
+		Message:   comment.Message,
+		Action:    models.COMMENT_HISTORY_DELETE,
+	}
+	if err := models.InsertCommentHistory(&history); err != nil {
+		return err
+	}
+
+	comment.Status = models.COMMENT_STATUS_DELETED
+	if err := models.UpdateById(comment.Id, comment, "status"); err != nil {
+		return err
+	}
+
+	search.RemoveComment(comment.Id)
+	return nil
+}
+
+// DeletePost deletes post with its comments and revisions by user, and
+// removes them from the search index.
+func DeletePost(post *models.Post, user *models.User) error {
+	if err := models.DeletePost(post.Id, user.Id); err != nil {
+		return err
+	}
+	search.RemovePost(post.Id)
+	return nil
+}
+
 func FilterCommentMentions(fromUser *models.User, post *models.Post, comment *models.Comment) {
 	var uri = fmt.Sprintf("post/%d", post.Id)
 	var lang = setting.DefaultLang
-	if fromUser.Id != post.UserId {
+
+	// every user is notified once for a comment
+	notified := map[int64]bool{fromUser.Id: true}
+	notify := func(toUserId int64, action int) {
+		if toUserId == 0 || notified[toUserId] {
+			return
+		}
+		notified[toUserId] = true
+
 		var notification = models.Notification{
 			FromUserId:   fromUser.Id,
-			ToUserId:     post.UserId,
-			Action:       setting.NOTICE_TYPE_COMMENT,
+			ToUserId:     toUserId,
+			Action:       action,
 			Title:        post.Title,
 			TargetId:     post.Id,
 			Uri:          uri,
@@ -100,11 +262,18 @@ func FilterCommentMentions(fromUser *models.User, post *models.Post, comment *mo
 			ContentCache: comment.MessageCache,
 			Status:       setting.NOTICE_UNREAD,
 		}
-		if err := models.InsertNotification(&notification); err == nil {
-			//pass
+		if err := models.InsertNotification(&notification); err != nil {
+			log.Error("FilterCommentMentions ", err)
 		}
 	}
 
+	notify(post.UserId, setting.NOTICE_TYPE_COMMENT)
+
+	// notify the author of replied comment
+	if parent := comment.Parent(); parent != nil {
+		notify(parent.UserId, setting.NOTICE_TYPE_REPLY)
+	}
+
 	//check comment @
 	var pattern = "[ ]*@[a-zA-Z0-9]+[ ]*"
 	r := regexp.MustCompile(pattern)
@@ -113,24 +282,7 @@ func FilterCommentMentions(fromUser *models.User, post *models.Post, comment *mo
 		bUserName := strings.TrimPrefix(strings.TrimSpace(userName), "@")
 
 		if user, err := models.GetUserByName(bUserName); err == nil {
-			if user.Id != 0 && user.Id != post.UserId {
-				notification := models.Notification{
-					FromUserId:   fromUser.Id,
-					ToUserId:     user.Id,
-					Action:       setting.NOTICE_TYPE_COMMENT,
-					Title:        post.Title,
-					TargetId:     post.Id,
-					Uri:          uri,
-					Lang:         lang,
-					Floor:        comment.Floor,
-					Content:      comment.Message,
-					ContentCache: comment.MessageCache,
-					Status:       setting.NOTICE_UNREAD,
-				}
-				if err := models.InsertNotification(&notification); err == nil {
-					//pass
-				}
-			}
+			notify(user.Id, setting.NOTICE_TYPE_MENTION)
 		}
 	}
 }
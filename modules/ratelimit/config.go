@@ -0,0 +1,66 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ratelimit
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/lunny/log"
+)
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// FromConfig builds policies of [ratelimit.NAME] sections, sections
+// without paths or with a zero rate are skipped
+func FromConfig(cfg *goconfig.ConfigFile) []*Policy {
+	var policies []*Policy
+	for _, section := range cfg.GetSectionList() {
+		if !strings.HasPrefix(section, "ratelimit.") {
+			continue
+		}
+
+		p := &Policy{
+			Name:    strings.TrimPrefix(section, "ratelimit."),
+			Paths:   splitList(cfg.MustValue(section, "paths")),
+			Methods: splitList(strings.ToUpper(cfg.MustValue(section, "methods"))),
+			Key:     cfg.MustValue(section, "key", KEY_IP),
+			Rate:    cfg.MustInt(section, "rate", 0),
+			Period:  time.Duration(cfg.MustInt(section, "period", 60)) * time.Second,
+		}
+		p.Burst = cfg.MustInt(section, "burst", p.Rate)
+
+		switch p.Key {
+		case KEY_IP, KEY_USER, KEY_TOKEN:
+		default:
+			log.Error("ratelimit: unknown key", p.Key, "of", section)
+			continue
+		}
+		if !p.valid() {
+			continue
+		}
+		policies = append(policies, p)
+	}
+	return policies
+}
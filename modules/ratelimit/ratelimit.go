@@ -0,0 +1,149 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package ratelimit limits requests with token buckets kept in a cache.
+package ratelimit
+
+import (
+	"math"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// what requests of a policy are counted by
+const (
+	KEY_IP    = "ip"
+	KEY_USER  = "user"
+	KEY_TOKEN = "token"
+)
+
+// Policy limits requests of paths and methods. Every key has a bucket of
+// Burst tokens refilled with Rate tokens each Period, a request takes one.
+type Policy struct {
+	Name    string
+	Paths   []string
+	Methods []string
+	Key     string
+	Rate    int
+	Period  time.Duration
+	Burst   int
+}
+
+// Match reports whether the policy limits the request. A path pattern
+// ending with /** matches the path and everything under it, others are
+// matched with path.Match where * is one path segment. No methods match
+// every method.
+func (p *Policy) Match(method, urlPath string) bool {
+	if len(p.Methods) > 0 {
+		found := false
+		for _, m := range p.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, pattern := range p.Paths {
+		if strings.HasSuffix(pattern, "/**") {
+			prefix := strings.TrimSuffix(pattern, "/**")
+			if urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/") {
+				return true
+			}
+		} else if ok, _ := path.Match(pattern, urlPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tokens refilled per second
+func (p *Policy) perSecond() float64 {
+	return float64(p.Rate) / p.Period.Seconds()
+}
+
+func (p *Policy) valid() bool {
+	return p.Rate > 0 && p.Period > 0 && p.Burst > 0 && len(p.Paths) > 0
+}
+
+// Store keeps buckets, setting.Cache is one
+type Store interface {
+	Get(key string) interface{}
+	Put(key string, val interface{}, timeout int64) error
+}
+
+type bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Result tells whether a request is allowed and the state of its bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter takes tokens from buckets in store
+type Limiter struct {
+	lock  sync.Mutex
+	store Store
+	now   func() time.Time
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store, now: time.Now}
+}
+
+// Take takes a token of the bucket of key under policy
+func (l *Limiter) Take(p *Policy, key string) Result {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	cacheKey := "ratelimit:" + p.Name + ":" + key
+	rate := p.perSecond()
+
+	b := bucket{Tokens: float64(p.Burst), Updated: now}
+	if v, ok := l.store.Get(cacheKey).(bucket); ok {
+		b = v
+		b.Tokens = math.Min(float64(p.Burst), b.Tokens+now.Sub(b.Updated).Seconds()*rate)
+		b.Updated = now
+	}
+
+	res := Result{Limit: p.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsDuration((1 - b.Tokens) / rate)
+	}
+	res.Remaining = int(b.Tokens)
+	res.Reset = secondsDuration((float64(p.Burst) - b.Tokens) / rate)
+
+	// a bucket full again is the same as no bucket
+	l.store.Put(cacheKey, b, int64(math.Ceil(res.Reset.Seconds()))+1)
+	return res
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,126 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Unknwon/goconfig"
+)
+
+type mapStore map[string]interface{}
+
+func (s mapStore) Get(key string) interface{} {
+	return s[key]
+}
+
+func (s mapStore) Put(key string, val interface{}, timeout int64) error {
+	s[key] = val
+	return nil
+}
+
+func TestPolicyMatch(t *testing.T) {
+	p := &Policy{
+		Paths:   []string{"/post/*", "/api/v1/**"},
+		Methods: []string{"POST"},
+	}
+	cases := []struct {
+		method, path string
+		match        bool
+	}{
+		{"POST", "/post/12", true},
+		{"post", "/post/12", true},
+		{"GET", "/post/12", false},
+		{"POST", "/post/12/edit", false},
+		{"POST", "/api/v1", true},
+		{"POST", "/api/v1/posts/3/comments", true},
+		{"POST", "/api/v10", false},
+	}
+	for _, c := range cases {
+		if got := p.Match(c.method, c.path); got != c.match {
+			t.Errorf("Match(%s, %s) = %v, want %v", c.method, c.path, got, c.match)
+		}
+	}
+}
+
+func TestLimiterTake(t *testing.T) {
+	now := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(mapStore{})
+	l.now = func() time.Time { return now }
+	p := &Policy{Name: "test", Rate: 1, Period: 10 * time.Second, Burst: 2}
+
+	for i := 1; i >= 0; i-- {
+		res := l.Take(p, "a")
+		if !res.Allowed || res.Remaining != i || res.Limit != 2 {
+			t.Fatalf("take %d: %+v", i, res)
+		}
+	}
+
+	res := l.Take(p, "a")
+	if res.Allowed || res.RetryAfter != 10*time.Second {
+		t.Fatalf("empty bucket: %+v", res)
+	}
+
+	// other keys have their own buckets
+	if res := l.Take(p, "b"); !res.Allowed {
+		t.Fatalf("other key: %+v", res)
+	}
+
+	now = now.Add(5 * time.Second)
+	res = l.Take(p, "a")
+	if res.Allowed || res.RetryAfter != 5*time.Second {
+		t.Fatalf("half refilled: %+v", res)
+	}
+
+	now = now.Add(5 * time.Second)
+	res = l.Take(p, "a")
+	if !res.Allowed || res.Remaining != 0 || res.Reset != 20*time.Second {
+		t.Fatalf("refilled: %+v", res)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg, err := goconfig.LoadFromData([]byte(`
+[ratelimit.comment]
+paths = /post/* | /api/v1/posts/*/comments
+methods = post
+key = user
+rate = 20
+period = 300
+
+[ratelimit.off]
+paths = /search
+rate = 0
+
+[ratelimit.bad]
+paths = /search
+key = cookie
+rate = 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policies := FromConfig(cfg)
+	if len(policies) != 1 {
+		t.Fatalf("got %d policies", len(policies))
+	}
+	p := policies[0]
+	if p.Name != "comment" || p.Key != KEY_USER || p.Burst != 20 || p.Period != 300*time.Second ||
+		len(p.Paths) != 2 || p.Methods[0] != "POST" {
+		t.Errorf("policy %+v", p)
+	}
+}
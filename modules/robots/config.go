@@ -0,0 +1,156 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package robots
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Unknwon/goconfig"
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
+)
+
+// name of the setting keeping rules saved by admin
+const settingName = "robots.txt"
+
+var (
+	lock    sync.RWMutex
+	current *Rules
+	custom  bool
+)
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// FromConfig builds rules from [robot.NAME] sections, the [robot] uas
+// list of old configs is used when there is no such section
+func FromConfig(cfg *goconfig.ConfigFile) *Rules {
+	rules := &Rules{}
+	for _, section := range cfg.GetSectionList() {
+		if !strings.HasPrefix(section, "robot.") {
+			continue
+		}
+		agents := splitList(cfg.MustValue(section, "user_agents"))
+		if len(agents) == 0 {
+			continue
+		}
+		rules.Groups = append(rules.Groups, &Group{
+			Agents:     agents,
+			Allow:      splitList(cfg.MustValue(section, "allow")),
+			Disallow:   splitList(cfg.MustValue(section, "disallow")),
+			CrawlDelay: cfg.MustInt(section, "crawl_delay", 0),
+		})
+	}
+
+	if len(rules.Groups) == 0 {
+		if uas := splitList(cfg.MustValue("robot", "uas")); len(uas) > 0 {
+			rules.Groups = append(rules.Groups,
+				&Group{Agents: uas, Disallow: splitList(cfg.MustValue("robot", "disallow"))},
+				&Group{Agents: []string{"*"}, Disallow: []string{"/"}})
+		}
+	}
+
+	for _, url := range splitList(cfg.MustValue("robot", "sitemaps")) {
+		rules.AddSitemap(url)
+	}
+	return rules
+}
+
+// Reload reads rules saved by admin, or rules in config when there are none
+func Reload() error {
+	rules, isCustom, err := load()
+
+	lock.Lock()
+	defer lock.Unlock()
+	current, custom = rules, isCustom
+	return err
+}
+
+func load() (*Rules, bool, error) {
+	text, err := models.GetSettingValue(settingName)
+	if err == nil {
+		rules, err := Parse(text)
+		if err == nil {
+			rules.AddSitemap(setting.AppUrl + "sitemap.xml")
+			return rules, true, nil
+		}
+		log.Error("robots: saved rules ", err)
+	} else if err != models.ErrNotExist {
+		log.Error("robots: load saved rules ", err)
+	}
+
+	if err == models.ErrNotExist {
+		err = nil
+	}
+	rules := FromConfig(setting.Cfg)
+	rules.AddSitemap(setting.AppUrl + "sitemap.xml")
+	return rules, false, err
+}
+
+// Current returns the rules served as robots.txt
+func Current() (*Rules, bool) {
+	lock.RLock()
+	rules, isCustom := current, custom
+	lock.RUnlock()
+	if rules != nil {
+		return rules, isCustom
+	}
+
+	Reload()
+	lock.RLock()
+	defer lock.RUnlock()
+	return current, custom
+}
+
+// Save validates and saves rules edited by admin
+func Save(text string) error {
+	if _, err := Parse(text); err != nil {
+		return err
+	}
+	if err := models.SaveSetting(settingName, text); err != nil {
+		return err
+	}
+	return Reload()
+}
+
+// Reset drops rules saved by admin and uses rules in config again
+func Reset() error {
+	if err := models.DeleteSetting(settingName); err != nil {
+		return err
+	}
+	return Reload()
+}
+
+// NoIndex reports whether pages under path must not be indexed, the
+// prefixes are setting.RobotNoIndex
+func NoIndex(path string) bool {
+	for _, prefix := range setting.RobotNoIndex {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package robots builds robots.txt from rules in config or saved by admin,
+// and tells which paths must not be indexed.
+package robots
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Group is the rules of some user agents
+type Group struct {
+	Agents     []string
+	Allow      []string
+	Disallow   []string
+	CrawlDelay int
+}
+
+type Rules struct {
+	Groups   []*Group
+	Sitemaps []string
+}
+
+// AddSitemap adds url unless it is listed
+func (r *Rules) AddSitemap(url string) {
+	for _, s := range r.Sitemaps {
+		if s == url {
+			return
+		}
+	}
+	r.Sitemaps = append(r.Sitemaps, url)
+}
+
+// String returns rules in robots.txt format
+func (r *Rules) String() string {
+	var buf bytes.Buffer
+	for i, g := range r.Groups {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		for _, agent := range g.Agents {
+			fmt.Fprintf(&buf, "User-agent: %s\n", agent)
+		}
+		for _, path := range g.Allow {
+			fmt.Fprintf(&buf, "Allow: %s\n", path)
+		}
+		for _, path := range g.Disallow {
+			fmt.Fprintf(&buf, "Disallow: %s\n", path)
+		}
+		// an empty disallow allows everything
+		if len(g.Allow) == 0 && len(g.Disallow) == 0 {
+			buf.WriteString("Disallow:\n")
+		}
+		if g.CrawlDelay > 0 {
+			fmt.Fprintf(&buf, "Crawl-delay: %d\n", g.CrawlDelay)
+		}
+	}
+	if len(r.Sitemaps) > 0 {
+		buf.WriteString("\n")
+	}
+	for _, url := range r.Sitemaps {
+		fmt.Fprintf(&buf, "Sitemap: %s\n", url)
+	}
+	return buf.String()
+}
+
+// Parse reads rules in robots.txt format, unknown directives and rules
+// without user agent are errors so that admin typos are reported
+func Parse(text string) (*Rules, error) {
+	rules := &Rules{}
+	var group *Group
+	// consecutive user agent lines start one group
+	var inAgents bool
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for num := 1; scanner.Scan(); num++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: missing colon", num)
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		if key == "user-agent" {
+			if value == "" {
+				return nil, fmt.Errorf("line %d: empty user agent", num)
+			}
+			if !inAgents {
+				group = &Group{}
+				rules.Groups = append(rules.Groups, group)
+			}
+			group.Agents = append(group.Agents, value)
+			inAgents = true
+			continue
+		}
+		inAgents = false
+
+		if key == "sitemap" {
+			rules.AddSitemap(value)
+			continue
+		}
+
+		if group == nil {
+			return nil, fmt.Errorf("line %d: %s before any user agent", num, key)
+		}
+		switch key {
+		case "allow":
+			if value != "" {
+				group.Allow = append(group.Allow, value)
+			}
+		case "disallow":
+			if value != "" {
+				group.Disallow = append(group.Disallow, value)
+			}
+		case "crawl-delay":
+			delay, err := strconv.Atoi(value)
+			if err != nil || delay < 0 {
+				return nil, fmt.Errorf("line %d: invalid crawl delay %q", num, value)
+			}
+			group.CrawlDelay = delay
+		default:
+			return nil, fmt.Errorf("line %d: unknown directive %q", num, key)
+		}
+	}
+	return rules, scanner.Err()
+}
@@ -0,0 +1,104 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package robots
+
+import (
+	"testing"
+
+	"github.com/Unknwon/goconfig"
+
+	"github.com/go-tango/wego/setting"
+)
+
+const testRobots = `User-agent: Googlebot
+User-agent: Bingbot
+Allow: /post
+Disallow: /admin
+Crawl-delay: 5
+
+# everyone else
+User-agent: *
+Disallow: /
+
+Sitemap: http://example.com/sitemap.xml
+`
+
+func TestParse(t *testing.T) {
+	rules, err := Parse(testRobots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules.Groups) != 2 || len(rules.Groups[0].Agents) != 2 || rules.Groups[0].CrawlDelay != 5 {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+
+	want := "User-agent: Googlebot\nUser-agent: Bingbot\nAllow: /post\nDisallow: /admin\nCrawl-delay: 5\n\n" +
+		"User-agent: *\nDisallow: /\n\nSitemap: http://example.com/sitemap.xml\n"
+	if s := rules.String(); s != want {
+		t.Errorf("String() = %q, want %q", s, want)
+	}
+
+	for _, bad := range []string{"Disallow: /", "User-agent: *\nNoindex: /", "User-agent: *\nCrawl-delay: soon"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) accepted", bad)
+		}
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg, err := goconfig.LoadFromData([]byte(`[robot]
+sitemaps = http://example.com/extra.xml
+
+[robot.search]
+user_agents = Googlebot|Bingbot
+disallow = /settings|/admin
+crawl_delay = 2
+
+[robot.others]
+user_agents = *
+disallow = /
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := FromConfig(cfg)
+	if len(rules.Groups) != 2 || rules.Groups[0].Disallow[1] != "/admin" || rules.Groups[0].CrawlDelay != 2 ||
+		rules.Groups[1].Agents[0] != "*" || len(rules.Sitemaps) != 1 {
+		t.Fatalf("unexpected rules %s", rules)
+	}
+
+	legacy, _ := goconfig.LoadFromData([]byte("[robot]\nuas = Googlebot|YodaoBot\ndisallow =\n"))
+	want := "User-agent: Googlebot\nUser-agent: YodaoBot\nDisallow:\n\nUser-agent: *\nDisallow: /\n"
+	if s := FromConfig(legacy).String(); s != want {
+		t.Errorf("legacy rules %q, want %q", s, want)
+	}
+}
+
+func TestNoIndex(t *testing.T) {
+	setting.RobotNoIndex = []string{"/settings", "/admin/"}
+	for path, want := range map[string]bool{
+		"/settings":         true,
+		"/settings/profile": true,
+		"/admin":            true,
+		"/admin/user":       true,
+		"/administrator":    false,
+		"/post/1":           false,
+	} {
+		if NoIndex(path) != want {
+			t.Errorf("NoIndex(%q) = %v", path, !want)
+		}
+	}
+}
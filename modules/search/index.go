@@ -0,0 +1,221 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package search implemented an in-process full-text index for posts and comments.
+package search
+
+import (
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	KIND_POST = iota + 1
+	KIND_COMMENT
+)
+
+// weight of a token found in the title compare to the body
+const titleBoost = 3
+
+// Document is one indexed post or comment
+type Document struct {
+	Kind       int
+	Id         int64
+	PostId     int64
+	UserId     int64
+	CategoryId int64
+	TopicId    int64
+	Floor      int
+	Title      string
+	Content    string
+	Created    time.Time
+
+	terms []string
+}
+
+type docKey struct {
+	Kind int
+	Id   int64
+}
+
+// term frequency of a token in one document
+type posting struct {
+	Title int
+	Body  int
+}
+
+// Index is an inverted index of tokens to documents, safe for concurrent use
+type Index struct {
+	lock     sync.RWMutex
+	docs     map[docKey]*Document
+	postings map[string]map[docKey]*posting
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[docKey]*Document),
+		postings: make(map[string]map[docKey]*posting),
+	}
+}
+
+// Add puts the document to index, replace the old one if exist
+func (idx *Index) Add(doc *Document) {
+	key := docKey{doc.Kind, doc.Id}
+
+	counts := make(map[string]*posting)
+	for _, t := range Tokenize(doc.Title) {
+		if counts[t] == nil {
+			counts[t] = new(posting)
+		}
+		counts[t].Title++
+	}
+	for _, t := range Tokenize(doc.Content) {
+		if counts[t] == nil {
+			counts[t] = new(posting)
+		}
+		counts[t].Body++
+	}
+
+	doc.terms = make([]string, 0, len(counts))
+	for t := range counts {
+		doc.terms = append(doc.terms, t)
+	}
+
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	idx.remove(key)
+	idx.docs[key] = doc
+	for t, p := range counts {
+		if idx.postings[t] == nil {
+			idx.postings[t] = make(map[docKey]*posting)
+		}
+		idx.postings[t][key] = p
+	}
+}
+
+// Remove deletes the document from index
+func (idx *Index) Remove(kind int, id int64) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.remove(docKey{kind, id})
+}
+
+// RemovePost deletes the post and all comments belong to it
+func (idx *Index) RemovePost(postId int64) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	for key, doc := range idx.docs {
+		if doc.PostId == postId {
+			idx.remove(key)
+		}
+	}
+}
+
+// ReassignUser gives the documents of a user to another
+func (idx *Index) ReassignUser(from, to int64) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	for _, doc := range idx.docs {
+		if doc.UserId == from {
+			doc.UserId = to
+		}
+	}
+}
+
+func (idx *Index) remove(key docKey) {
+	doc, ok := idx.docs[key]
+	if !ok {
+		return
+	}
+	for _, t := range doc.terms {
+		if ps, ok := idx.postings[t]; ok {
+			delete(ps, key)
+			if len(ps) == 0 {
+				delete(idx.postings, t)
+			}
+		}
+	}
+	delete(idx.docs, key)
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return len(idx.docs)
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// Tokenize splits text to lower case words, CJK text has no word
+// separator so every rune and every pair of adjacent runes is a token.
+func Tokenize(text string) []string {
+	return tokenize(text, true)
+}
+
+// tokens for querying, CJK runs use bigrams only unless a single rune
+func queryTokens(text string) []string {
+	return tokenize(text, false)
+}
+
+func tokenize(text string, unigrams bool) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 1:
+			tokens = append(tokens, string(cjk))
+		case len(cjk) > 1:
+			for i := range cjk {
+				if unigrams {
+					tokens = append(tokens, string(cjk[i]))
+				}
+				if i+1 < len(cjk) {
+					tokens = append(tokens, string(cjk[i:i+2]))
+				}
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
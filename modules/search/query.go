@@ -0,0 +1,233 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package search
+
+import (
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query describe the words and filters of a search
+type Query struct {
+	Text       string
+	CategoryId int64
+	TopicId    int64
+	UserId     int64
+	From       time.Time
+	To         time.Time
+}
+
+// Result is one matched document with its score
+type Result struct {
+	*Document
+	Score float64
+	terms []string
+}
+
+// Highlight returns a html snippet of content around the matched tokens
+func (r *Result) Highlight() template.HTML {
+	return Highlight(r.Content, r.terms, snippetSize)
+}
+
+// HighlightTitle returns the html escaped title with matched tokens marked
+func (r *Result) HighlightTitle() template.HTML {
+	return Highlight(r.Title, r.terms, 0)
+}
+
+func (q *Query) match(doc *Document) bool {
+	if q.CategoryId > 0 && doc.CategoryId != q.CategoryId {
+		return false
+	}
+	if q.TopicId > 0 && doc.TopicId != q.TopicId {
+		return false
+	}
+	if q.UserId > 0 && doc.UserId != q.UserId {
+		return false
+	}
+	if !q.From.IsZero() && doc.Created.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !doc.Created.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// Search returns the documents contain all query tokens, ranked by tf-idf.
+// Returns the total number of matched documents and the results in range.
+func (idx *Index) Search(q *Query, limit, start int) (int, []*Result) {
+	terms := uniqueTerms(queryTokens(q.Text))
+	if len(terms) == 0 {
+		return 0, nil
+	}
+
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	// begin with the rarest token to keep candidates small
+	sort.Slice(terms, func(i, j int) bool {
+		return len(idx.postings[terms[i]]) < len(idx.postings[terms[j]])
+	})
+
+	total := float64(len(idx.docs))
+	var results []*Result
+	for key, first := range idx.postings[terms[0]] {
+		doc := idx.docs[key]
+		if !q.match(doc) {
+			continue
+		}
+
+		var score float64
+		matched := true
+		for i, t := range terms {
+			p := first
+			if i > 0 {
+				if p = idx.postings[t][key]; p == nil {
+					matched = false
+					break
+				}
+			}
+			idf := math.Log(1 + total/float64(len(idx.postings[t])))
+			tf := float64(p.Title*titleBoost + p.Body)
+			score += (1 + math.Log(tf)) * idf
+		}
+		if !matched {
+			continue
+		}
+		results = append(results, &Result{Document: doc, Score: score, terms: terms})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Created.After(results[j].Created)
+	})
+
+	count := len(results)
+	if start >= count {
+		return count, nil
+	}
+	end := start + limit
+	if limit <= 0 || end > count {
+		end = count
+	}
+	return count, results[start:end]
+}
+
+func uniqueTerms(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	terms := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// runes around the first match in a snippet
+const snippetSize = 160
+
+// Highlight escapes text and wraps every occurrence of terms with <mark>.
+// If size > 0 only a window of size runes around the first match is returned.
+func Highlight(text string, terms []string, size int) template.HTML {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marks := make([]bool, len(runes))
+	first := -1
+	for _, t := range terms {
+		tr := []rune(t)
+		if len(tr) == 0 {
+			continue
+		}
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if !equalRunes(lower[i:i+len(tr)], tr) {
+				continue
+			}
+			// latin tokens must match whole words
+			if !isCJK(tr[0]) && (!isBoundary(lower, i-1) || !isBoundary(lower, i+len(tr))) {
+				continue
+			}
+			for j := i; j < i+len(tr); j++ {
+				marks[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+
+	begin, end := 0, len(runes)
+	if size > 0 && len(runes) > size {
+		if first > size/4 {
+			begin = first - size/4
+		}
+		end = begin + size
+		if end > len(runes) {
+			end = len(runes)
+			begin = end - size
+		}
+	}
+
+	var buf strings.Builder
+	if begin > 0 {
+		buf.WriteString("&hellip;")
+	}
+	open := false
+	for i := begin; i < end; i++ {
+		if marks[i] && !open {
+			buf.WriteString("<mark>")
+			open = true
+		} else if !marks[i] && open {
+			buf.WriteString("</mark>")
+			open = false
+		}
+		buf.WriteString(template.HTMLEscapeString(string(runes[i])))
+	}
+	if open {
+		buf.WriteString("</mark>")
+	}
+	if end < len(runes) {
+		buf.WriteString("&hellip;")
+	}
+	return template.HTML(buf.String())
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isBoundary(runes []rune, i int) bool {
+	if i < 0 || i >= len(runes) {
+		return true
+	}
+	r := runes[i]
+	return isCJK(r) || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
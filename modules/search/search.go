@@ -0,0 +1,176 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package search
+
+import (
+	"fmt"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/setting"
+)
+
+// the site index, nil when search is disabled
+var index *Index
+
+// Init builds the index from all posts and comments in database
+func Init() {
+	if !setting.SearchEnabled {
+		return
+	}
+
+	idx := NewIndex()
+
+	posts := make(map[int64]*models.Post)
+	err := models.ORM().Iterate(new(models.Post), func(i int, bean interface{}) error {
+		post := bean.(*models.Post)
+		if !post.IsVisible() {
+			return nil
+		}
+		posts[post.Id] = post
+		idx.Add(postDocument(post))
+		return nil
+	})
+	if err != nil {
+		log.Error("search: index posts ", err)
+	}
+
+	err = models.ORM().Iterate(new(models.Comment), func(i int, bean interface{}) error {
+		comment := bean.(*models.Comment)
+		if comment.Status != models.COMMENT_STATUS_NORMAL {
+			return nil
+		}
+		if post, ok := posts[comment.PostId]; ok {
+			idx.Add(commentDocument(comment, post))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("search: index comments ", err)
+	}
+
+	index = idx
+	log.Info("search: indexed", idx.Len(), "documents")
+}
+
+// plain text of rendered markdown
+func plainText(markdown string) string {
+	return utils.Htmlunquote(utils.Html2str(utils.RenderMarkdown(markdown)))
+}
+
+func postDocument(post *models.Post) *Document {
+	return &Document{
+		Kind:       KIND_POST,
+		Id:         post.Id,
+		PostId:     post.Id,
+		UserId:     post.UserId,
+		CategoryId: post.CategoryId,
+		TopicId:    post.TopicId,
+		Title:      post.Title,
+		Content:    plainText(post.Content),
+		Created:    post.Created,
+	}
+}
+
+func commentDocument(comment *models.Comment, post *models.Post) *Document {
+	return &Document{
+		Kind:       KIND_COMMENT,
+		Id:         comment.Id,
+		PostId:     post.Id,
+		UserId:     comment.UserId,
+		CategoryId: post.CategoryId,
+		TopicId:    post.TopicId,
+		Floor:      comment.Floor,
+		Title:      post.Title,
+		Content:    plainText(comment.Message),
+		Created:    comment.Created,
+	}
+}
+
+func (d *Document) Link() string {
+	if d.Kind == KIND_COMMENT {
+		return fmt.Sprintf("%spost/%d#reply%d", setting.AppUrl, d.PostId, d.Floor)
+	}
+	return fmt.Sprintf("%spost/%d", setting.AppUrl, d.PostId)
+}
+
+func (d *Document) IsComment() bool {
+	return d.Kind == KIND_COMMENT
+}
+
+func (d *Document) User() *models.User {
+	user, _ := models.GetUserById(d.UserId)
+	return user
+}
+
+// IndexPost adds or refreshes a post in the index, posts held for review or
+// hidden are removed with their comments instead
+func IndexPost(post *models.Post) {
+	if index == nil {
+		return
+	}
+	if !post.IsVisible() {
+		index.RemovePost(post.Id)
+		return
+	}
+	index.Add(postDocument(post))
+}
+
+// IndexComment adds or refreshes a comment in the index, comments which are
+// not visible or on invisible posts are removed instead
+func IndexComment(comment *models.Comment, post *models.Post) {
+	if index == nil {
+		return
+	}
+	if comment.Status != models.COMMENT_STATUS_NORMAL || !post.IsVisible() {
+		index.Remove(KIND_COMMENT, comment.Id)
+		return
+	}
+	index.Add(commentDocument(comment, post))
+}
+
+// RemovePost deletes a post and its comments from the index
+func RemovePost(postId int64) {
+	if index == nil {
+		return
+	}
+	index.RemovePost(postId)
+}
+
+// RemoveComment deletes a comment from the index
+func RemoveComment(commentId int64) {
+	if index == nil {
+		return
+	}
+	index.Remove(KIND_COMMENT, commentId)
+}
+
+// ReassignUser gives the posts and comments of a deleted user to another
+func ReassignUser(from, to int64) {
+	if index == nil {
+		return
+	}
+	index.ReassignUser(from, to)
+}
+
+// Search queries the site index
+func Search(q *Query, limit, start int) (int, []*Result) {
+	if index == nil {
+		return 0, nil
+	}
+	return index.Search(q, limit, start)
+}
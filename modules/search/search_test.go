@@ -0,0 +1,101 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package search
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-tango/wego/models"
+	. "github.com/go-tango/wego/modules/utils"
+)
+
+func TestTokenize(t *testing.T) {
+	ThrowFail(t, AssertIs(strings.Join(Tokenize("Hello, Go_lang 1.4!"), " "), "hello go_lang 1 4"))
+	ThrowFail(t, AssertIs(strings.Join(Tokenize("Go语言"), " "), "go 语 语言 言"))
+	ThrowFail(t, AssertIs(strings.Join(queryTokens("Go语言"), " "), "go 语言"))
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := NewIndex()
+	now := time.Now()
+	idx.Add(&Document{Kind: KIND_POST, Id: 1, PostId: 1, CategoryId: 1, UserId: 1,
+		Title: "Tango web framework", Content: "A micro web framework for Go", Created: now})
+	idx.Add(&Document{Kind: KIND_POST, Id: 2, PostId: 2, CategoryId: 2, UserId: 2,
+		Title: "Go 语言入门", Content: "how to write web server in go", Created: now.Add(-48 * time.Hour)})
+	idx.Add(&Document{Kind: KIND_COMMENT, Id: 1, PostId: 1, CategoryId: 1, UserId: 2,
+		Title: "Tango web framework", Content: "I like tango", Created: now})
+
+	count, results := idx.Search(&Query{Text: "web framework"}, 10, 0)
+	ThrowFail(t, AssertIs(count, 2))
+	ThrowFail(t, AssertIs(results[0].Kind, KIND_POST))
+	ThrowFail(t, AssertIs(results[0].Id, 1))
+
+	count, _ = idx.Search(&Query{Text: "语言"}, 10, 0)
+	ThrowFail(t, AssertIs(count, 1))
+
+	count, _ = idx.Search(&Query{Text: "web", UserId: 2}, 10, 0)
+	ThrowFail(t, AssertIs(count, 2))
+
+	count, _ = idx.Search(&Query{Text: "web", From: now.Add(-time.Hour)}, 10, 0)
+	ThrowFail(t, AssertIs(count, 2))
+
+	idx.RemovePost(1)
+	count, _ = idx.Search(&Query{Text: "tango"}, 10, 0)
+	ThrowFail(t, AssertIs(count, 0))
+	ThrowFail(t, AssertIs(idx.Len(), 1))
+}
+
+func TestIndexHeldPost(t *testing.T) {
+	index = NewIndex()
+	defer func() { index = nil }()
+
+	post := &models.Post{Id: 1, Title: "Tango web framework", Content: "A micro web framework"}
+	comment := &models.Comment{Id: 1, PostId: 1, Message: "I like tango"}
+	IndexPost(post)
+	IndexComment(comment, post)
+	count, _ := Search(&Query{Text: "tango"}, 10, 0)
+	ThrowFail(t, AssertIs(count, 2))
+
+	// the author edits a post held for review
+	post.Status = models.POST_STATUS_PENDING
+	post.Title = "Tango spam framework"
+	IndexPost(post)
+	count, _ = Search(&Query{Text: "spam"}, 10, 0)
+	ThrowFail(t, AssertIs(count, 0))
+	count, _ = Search(&Query{Text: "tango"}, 10, 0)
+	ThrowFail(t, AssertIs(count, 0))
+
+	// comments on the held post are not indexed either
+	comment.Message = "I like spam"
+	IndexComment(comment, post)
+	count, _ = Search(&Query{Text: "spam"}, 10, 0)
+	ThrowFail(t, AssertIs(count, 0))
+
+	post.Status = models.POST_STATUS_NORMAL
+	comment.Status = models.COMMENT_STATUS_HIDDEN
+	IndexPost(post)
+	IndexComment(comment, post)
+	count, _ = Search(&Query{Text: "spam"}, 10, 0)
+	ThrowFail(t, AssertIs(count, 1))
+}
+
+func TestHighlight(t *testing.T) {
+	ThrowFail(t, AssertIs(string(Highlight("Go <b>is</b> good, gopher", []string{"go"}, 0)),
+		"<mark>Go</mark> &lt;b&gt;is&lt;/b&gt; good, gopher"))
+	ThrowFail(t, AssertIs(string(Highlight("学习Go语言", []string{"go", "语言"}, 0)),
+		"学习<mark>Go语言</mark>"))
+}
@@ -0,0 +1,154 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package sitemap
+
+import (
+	"strings"
+	"time"
+
+	"github.com/go-xorm/xorm"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
+)
+
+// the site sitemap
+var Default = New(postSection, categorySection, topicSection, pageSection, userSection)
+
+func maxId(bean interface{}) func() (int64, error) {
+	return func() (int64, error) {
+		var ids []int64
+		err := models.ORM().Table(bean).Select("COALESCE(MAX(id), 0)").Find(&ids)
+		if err != nil || len(ids) == 0 {
+			return 0, err
+		}
+		return ids[0], nil
+	}
+}
+
+// changedIds finds ids of beans with any of columns updated after since
+func changedIds(bean interface{}, columns ...string) func(time.Time) ([]int64, error) {
+	return func(since time.Time) ([]int64, error) {
+		var ids []int64
+		wheres := make([]string, 0, len(columns))
+		args := make([]interface{}, 0, len(columns))
+		for _, col := range columns {
+			wheres = append(wheres, col+" > ?")
+			args = append(args, since)
+		}
+		err := models.ORM().Table(bean).Cols("id").Where(strings.Join(wheres, " OR "), args...).Find(&ids)
+		return ids, err
+	}
+}
+
+// inRange selects beans with id in [from, to]
+func inRange(from, to int64) *xorm.Session {
+	return models.ORM().Where("id BETWEEN ? AND ?", from, to).Asc("id")
+}
+
+var postSection = &Section{
+	Name:       "posts",
+	MaxId:      maxId(new(models.Post)),
+	ChangedIds: changedIds(new(models.Post), "updated", "last_replied"),
+	URLs: func(from, to int64) ([]URL, error) {
+		var posts []models.Post
+		err := inRange(from, to).And("status = ?", models.POST_STATUS_NORMAL).
+			Cols("id", "updated", "last_replied").Find(&posts)
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]URL, 0, len(posts))
+		for i := range posts {
+			lastmod := posts[i].Updated
+			if posts[i].LastReplied.After(lastmod) {
+				lastmod = posts[i].LastReplied
+			}
+			urls = append(urls, URL{Loc: posts[i].Link(), LastMod: lastmod})
+		}
+		return urls, nil
+	},
+}
+
+// categories have no update time, they change with the daily rebuild
+var categorySection = &Section{
+	Name:  "categories",
+	MaxId: maxId(new(models.Category)),
+	URLs: func(from, to int64) ([]URL, error) {
+		var cats []models.Category
+		if err := inRange(from, to).Find(&cats); err != nil {
+			return nil, err
+		}
+		urls := make([]URL, 0, len(cats))
+		for i := range cats {
+			urls = append(urls, URL{Loc: cats[i].Link()})
+		}
+		return urls, nil
+	},
+}
+
+var topicSection = &Section{
+	Name:       "topics",
+	MaxId:      maxId(new(models.Topic)),
+	ChangedIds: changedIds(new(models.Topic), "updated"),
+	URLs: func(from, to int64) ([]URL, error) {
+		var topics []models.Topic
+		if err := inRange(from, to).Cols("id", "slug", "updated").Find(&topics); err != nil {
+			return nil, err
+		}
+		urls := make([]URL, 0, len(topics))
+		for i := range topics {
+			urls = append(urls, URL{Loc: topics[i].Link(), LastMod: topics[i].Updated})
+		}
+		return urls, nil
+	},
+}
+
+var pageSection = &Section{
+	Name:       "pages",
+	MaxId:      maxId(new(models.Page)),
+	ChangedIds: changedIds(new(models.Page), "updated"),
+	URLs: func(from, to int64) ([]URL, error) {
+		var pages []models.Page
+		err := inRange(from, to).And("is_publish = ?", true).Cols("id", "uri", "updated").Find(&pages)
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]URL, 0, len(pages))
+		for i := range pages {
+			loc := setting.AppUrl + strings.TrimPrefix(pages[i].Uri, "/")
+			urls = append(urls, URL{Loc: loc, LastMod: pages[i].Updated})
+		}
+		return urls, nil
+	},
+}
+
+var userSection = &Section{
+	Name:       "users",
+	MaxId:      maxId(new(models.User)),
+	ChangedIds: changedIds(new(models.User), "updated"),
+	URLs: func(from, to int64) ([]URL, error) {
+		var users []models.User
+		err := inRange(from, to).And("is_active = ? AND is_forbid = ?", true, false).
+			Cols("id", "user_name", "updated").Find(&users)
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]URL, 0, len(users))
+		for i := range users {
+			urls = append(urls, URL{Loc: users[i].Link(), LastMod: users[i].Updated})
+		}
+		return urls, nil
+	},
+}
@@ -0,0 +1,252 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package sitemap builds a sitemap index and chunked sitemaps of posts,
+// categories, topics, pages and users.
+package sitemap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/setting"
+)
+
+const (
+	// objects of consecutive ids in one sitemap, the protocol allows 50000
+	chunkSize = 5000
+	// changed objects are looked up this often
+	refreshInterval = 10 * time.Minute
+	// all sitemaps are built again this often to drop deleted objects
+	rebuildInterval = 24 * time.Hour
+
+	xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+// Section lists urls of one kind of objects, chunks hold id ranges
+type Section struct {
+	Name string
+	// MaxId returns the largest id
+	MaxId func() (int64, error)
+	// ChangedIds returns ids updated after since
+	ChangedIds func(since time.Time) ([]int64, error)
+	// URLs returns urls of objects with id in [from, to]
+	URLs func(from, to int64) ([]URL, error)
+}
+
+type chunk struct {
+	data    []byte
+	lastmod time.Time
+	empty   bool
+}
+
+// Sitemap keeps generated sitemaps in memory, changed chunks are
+// generated again when requested after refreshInterval
+type Sitemap struct {
+	lock     sync.Mutex
+	sections []*Section
+	chunks   map[string]*chunk
+	index    []byte
+	checked  time.Time
+	built    time.Time
+}
+
+func New(sections ...*Section) *Sitemap {
+	return &Sitemap{
+		sections: sections,
+		chunks:   make(map[string]*chunk),
+	}
+}
+
+// Index returns the sitemap index
+func (s *Sitemap) Index() ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.refresh(time.Now()); err != nil {
+		return nil, err
+	}
+	return s.index, nil
+}
+
+// Chunk returns the sitemap of name like "posts-1.xml"
+func (s *Sitemap) Chunk(name string) ([]byte, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.refresh(time.Now()); err != nil {
+		return nil, false, err
+	}
+	c, ok := s.chunks[name]
+	if !ok || c.empty {
+		return nil, false, nil
+	}
+	return c.data, true, nil
+}
+
+func chunkName(section string, n int64) string {
+	return fmt.Sprintf("%s-%d.xml", section, n+1)
+}
+
+func (s *Sitemap) refresh(now time.Time) error {
+	full := s.built.IsZero() || now.Sub(s.built) > rebuildInterval
+	if !full && now.Sub(s.checked) < refreshInterval {
+		return nil
+	}
+
+	since := s.checked
+	changed := false
+	chunks := s.chunks
+	if full {
+		chunks = make(map[string]*chunk)
+	}
+
+	for _, sec := range s.sections {
+		maxId, err := sec.MaxId()
+		if err != nil {
+			return err
+		}
+
+		dirty := make(map[int64]bool)
+		for n := int64(0); n <= (maxId-1)/chunkSize; n++ {
+			if _, ok := chunks[chunkName(sec.Name, n)]; !ok {
+				dirty[n] = true
+			}
+		}
+		if !full && sec.ChangedIds != nil {
+			ids, err := sec.ChangedIds(since)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				dirty[(id-1)/chunkSize] = true
+			}
+		}
+
+		for n := range dirty {
+			c, err := buildChunk(sec, n)
+			if err != nil {
+				return err
+			}
+			chunks[chunkName(sec.Name, n)] = c
+			changed = true
+		}
+	}
+
+	s.chunks = chunks
+	s.checked = now
+	if full {
+		s.built = now
+	}
+
+	if changed || s.index == nil {
+		var buf bytes.Buffer
+		if err := writeIndex(&buf, s.indexEntries()); err != nil {
+			return err
+		}
+		s.index = buf.Bytes()
+		log.Info("sitemap: built", len(s.chunks), "sitemaps")
+	}
+	return nil
+}
+
+func buildChunk(sec *Section, n int64) (*chunk, error) {
+	urls, err := sec.URLs(n*chunkSize+1, (n+1)*chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &chunk{empty: len(urls) == 0}
+	for _, u := range urls {
+		if u.LastMod.After(c.lastmod) {
+			c.lastmod = u.LastMod
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeURLSet(&buf, urls); err != nil {
+		return nil, err
+	}
+	c.data = buf.Bytes()
+	return c, nil
+}
+
+func (s *Sitemap) indexEntries() []URL {
+	names := make([]string, 0, len(s.chunks))
+	for name, c := range s.chunks {
+		if !c.empty {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	entries := make([]URL, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, URL{
+			Loc:     setting.AppUrl + "sitemap/" + name,
+			LastMod: s.chunks[name].lastmod,
+		})
+	}
+	return entries
+}
+
+type xmlURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+func toXMLURLs(urls []URL) []xmlURL {
+	items := make([]xmlURL, 0, len(urls))
+	for _, u := range urls {
+		item := xmlURL{Loc: u.Loc}
+		if !u.LastMod.IsZero() {
+			item.LastMod = u.LastMod.UTC().Format(time.RFC3339)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func writeIndex(w io.Writer, sitemaps []URL) error {
+	return writeXML(w, struct {
+		XMLName  xml.Name `xml:"sitemapindex"`
+		Xmlns    string   `xml:"xmlns,attr"`
+		Sitemaps []xmlURL `xml:"sitemap"`
+	}{Xmlns: xmlns, Sitemaps: toXMLURLs(sitemaps)})
+}
+
+func writeURLSet(w io.Writer, urls []URL) error {
+	return writeXML(w, struct {
+		XMLName xml.Name `xml:"urlset"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		URLs    []xmlURL `xml:"url"`
+	}{Xmlns: xmlns, URLs: toXMLURLs(urls)})
+}
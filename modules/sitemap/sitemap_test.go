@@ -0,0 +1,94 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package sitemap
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// memorySection serves ids 1..max, updated[id] is the update time
+type memorySection struct {
+	max     int64
+	updated map[int64]time.Time
+	built   []int64
+}
+
+func (m *memorySection) section() *Section {
+	return &Section{
+		Name:  "items",
+		MaxId: func() (int64, error) { return m.max, nil },
+		ChangedIds: func(since time.Time) ([]int64, error) {
+			var ids []int64
+			for id, t := range m.updated {
+				if t.After(since) {
+					ids = append(ids, id)
+				}
+			}
+			return ids, nil
+		},
+		URLs: func(from, to int64) ([]URL, error) {
+			m.built = append(m.built, from)
+			var urls []URL
+			for id := from; id <= to && id <= m.max; id++ {
+				urls = append(urls, URL{Loc: fmt.Sprintf("http://example.com/item/%d", id), LastMod: m.updated[id]})
+			}
+			return urls, nil
+		},
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	start := time.Date(2014, 10, 1, 0, 0, 0, 0, time.UTC)
+	m := &memorySection{max: chunkSize + 1, updated: map[int64]time.Time{1: start, chunkSize + 1: start}}
+	s := New(m.section())
+
+	if err := s.refresh(start); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.built) != 2 || !bytes.Contains(s.index, []byte("items-2.xml")) {
+		t.Fatalf("built %v, index %s", m.built, s.index)
+	}
+	if c := s.chunks["items-2.xml"]; !bytes.Contains(c.data, []byte("<loc>http://example.com/item/5001</loc>")) {
+		t.Errorf("unexpected chunk %s", c.data)
+	}
+
+	// nothing is checked before refreshInterval
+	m.built = nil
+	m.updated[2] = start.Add(time.Minute)
+	s.refresh(start.Add(time.Minute))
+	if len(m.built) != 0 {
+		t.Fatalf("refreshed too early, built %v", m.built)
+	}
+
+	// only the changed chunk is built
+	s.refresh(start.Add(refreshInterval + time.Minute))
+	if len(m.built) != 1 || m.built[0] != 1 {
+		t.Fatalf("built %v, want the first chunk", m.built)
+	}
+	if !bytes.Contains(s.index, []byte("<lastmod>2014-10-01T00:01:00Z</lastmod>")) {
+		t.Errorf("lastmod not updated in index %s", s.index)
+	}
+
+	// new ids get a new chunk
+	m.built = nil
+	m.max = 2*chunkSize + 1
+	s.refresh(start.Add(2*refreshInterval + time.Minute))
+	if len(m.built) != 1 || m.built[0] != 2*chunkSize+1 {
+		t.Fatalf("built %v, want the third chunk", m.built)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+)
+
+// names of settings keeping blocklists saved by admin
+const (
+	rejectSettingName = "spam.reject"
+	holdSettingName   = "spam.hold"
+)
+
+// Blocklist is a list of rules, one rule a line. A rule is a word matched
+// anywhere in content ignoring case, or a regexp between slashes like
+// /cheap\s+pills/. Empty lines and lines starting with # are skipped.
+type Blocklist struct {
+	text     string
+	words    []string
+	patterns []*regexp.Regexp
+}
+
+// ParseBlocklist parses rules, a bad regexp fails with its line number
+func ParseBlocklist(text string) (*Blocklist, error) {
+	list := &Blocklist{text: text}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if len(line) > 2 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/") {
+			re, err := regexp.Compile("(?i)" + line[1:len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			list.patterns = append(list.patterns, re)
+			continue
+		}
+		list.words = append(list.words, strings.ToLower(line))
+	}
+	return list, nil
+}
+
+// Match returns the first rule matching text, or empty string
+func (l *Blocklist) Match(text string) string {
+	if l == nil {
+		return ""
+	}
+	lower := strings.ToLower(text)
+	for _, word := range l.words {
+		if strings.Contains(lower, word) {
+			return word
+		}
+	}
+	for _, re := range l.patterns {
+		if re.MatchString(text) {
+			return "/" + strings.TrimPrefix(re.String(), "(?i)") + "/"
+		}
+	}
+	return ""
+}
+
+func (l *Blocklist) String() string {
+	if l == nil {
+		return ""
+	}
+	return l.text
+}
+
+var (
+	listLock   sync.RWMutex
+	rejectList *Blocklist
+	holdList   *Blocklist
+	listLoaded bool
+)
+
+func loadBlocklist(name string) *Blocklist {
+	text, err := models.GetSettingValue(name)
+	if err != nil {
+		if err != models.ErrNotExist {
+			log.Error("spam: load ", name, " ", err)
+		}
+		return nil
+	}
+	list, err := ParseBlocklist(text)
+	if err != nil {
+		log.Error("spam: saved ", name, " ", err)
+		return nil
+	}
+	return list
+}
+
+// ReloadBlocklists reads blocklists saved by admin
+func ReloadBlocklists() {
+	reject := loadBlocklist(rejectSettingName)
+	hold := loadBlocklist(holdSettingName)
+
+	listLock.Lock()
+	defer listLock.Unlock()
+	rejectList, holdList, listLoaded = reject, hold, true
+}
+
+// Blocklists returns the lists of rejected and held content
+func Blocklists() (reject, hold *Blocklist) {
+	listLock.RLock()
+	loaded := listLoaded
+	listLock.RUnlock()
+	if !loaded {
+		ReloadBlocklists()
+	}
+
+	listLock.RLock()
+	defer listLock.RUnlock()
+	return rejectList, holdList
+}
+
+// SaveBlocklists validates and saves blocklists edited by admin
+func SaveBlocklists(reject, hold string) error {
+	if _, err := ParseBlocklist(reject); err != nil {
+		return err
+	}
+	if _, err := ParseBlocklist(hold); err != nil {
+		return err
+	}
+	if err := models.SaveSetting(rejectSettingName, reject); err != nil {
+		return err
+	}
+	if err := models.SaveSetting(holdSettingName, hold); err != nil {
+		return err
+	}
+	ReloadBlocklists()
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
+)
+
+func allow() Decision {
+	return Decision{Action: ALLOW}
+}
+
+// rateCheck rejects content over the per member limits in rate_minutes
+type rateCheck struct{}
+
+func (rateCheck) Name() string {
+	return "rate"
+}
+
+func (rateCheck) Check(c *Content) Decision {
+	// edits add no post or comment to the rate
+	if c.Id > 0 {
+		return allow()
+	}
+
+	limit := setting.SpamPostRate
+	if c.Kind == KIND_COMMENT {
+		limit = setting.SpamCommentRate
+	}
+	if limit <= 0 || setting.SpamRateMinutes <= 0 {
+		return allow()
+	}
+
+	since := time.Now().Add(-time.Duration(setting.SpamRateMinutes) * time.Minute)
+	var cnt int64
+	var err error
+	if c.Kind == KIND_COMMENT {
+		cnt, err = models.CountCommentsByUserSince(c.User.Id, since)
+	} else {
+		cnt, err = models.CountPostsByUserSince(c.User.Id, since)
+	}
+	if err != nil {
+		log.Error("spam: rate ", err)
+		return allow()
+	}
+
+	if cnt >= int64(limit) {
+		return Decision{Action: REJECT,
+			Reason: fmt.Sprintf("%d %ss in %d minutes", cnt, c.Kind, setting.SpamRateMinutes)}
+	}
+	return allow()
+}
+
+var linkPattern = regexp.MustCompile(`(?i)https?://`)
+
+// countLinks counts urls in text, markdown links are urls too
+func countLinks(text string) int {
+	return len(linkPattern.FindAllStringIndex(text, -1))
+}
+
+// linkCheck holds content of new accounts with too many links
+type linkCheck struct{}
+
+func (linkCheck) Name() string {
+	return "links"
+}
+
+func (linkCheck) Check(c *Content) Decision {
+	if setting.SpamNewUserDays <= 0 {
+		return allow()
+	}
+	age := time.Since(c.User.Created)
+	if age >= time.Duration(setting.SpamNewUserDays)*24*time.Hour {
+		return allow()
+	}
+
+	if n := countLinks(c.Title + "\n" + c.Text); n > setting.SpamMaxLinks {
+		return Decision{Action: HOLD,
+			Reason: fmt.Sprintf("%d links from an account of %d days", n, int(age.Hours()/24))}
+	}
+	return allow()
+}
+
+// duplicateCheck rejects content the member already posted in
+// duplicate_minutes
+type duplicateCheck struct{}
+
+func (duplicateCheck) Name() string {
+	return "duplicate"
+}
+
+func (duplicateCheck) Check(c *Content) Decision {
+	if setting.SpamDuplicateMinutes <= 0 || strings.TrimSpace(c.Text) == "" {
+		return allow()
+	}
+
+	since := time.Now().Add(-time.Duration(setting.SpamDuplicateMinutes) * time.Minute)
+	var has bool
+	var err error
+	if c.Kind == KIND_COMMENT {
+		has, err = models.HasCommentMessageSince(c.User.Id, c.Id, c.Text, since)
+	} else {
+		has, err = models.HasPostContentSince(c.User.Id, c.Id, c.Text, since)
+	}
+	if err != nil {
+		log.Error("spam: duplicate ", err)
+		return allow()
+	}
+
+	if has {
+		return Decision{Action: REJECT, Reason: "same " + c.Kind + " posted again"}
+	}
+	return allow()
+}
+
+// blocklistCheck rejects or holds content matching blocked words and
+// patterns saved by admin
+type blocklistCheck struct{}
+
+func (blocklistCheck) Name() string {
+	return "blocklist"
+}
+
+func (blocklistCheck) Check(c *Content) Decision {
+	reject, hold := Blocklists()
+	text := c.Title + "\n" + c.Text
+	if rule := reject.Match(text); rule != "" {
+		return Decision{Action: REJECT, Reason: "matches " + rule}
+	}
+	if rule := hold.Match(text); rule != "" {
+		return Decision{Action: HOLD, Reason: "matches " + rule}
+	}
+	return allow()
+}
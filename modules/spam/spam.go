@@ -0,0 +1,140 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package spam checks new posts and comments before they are saved.
+package spam
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
+)
+
+// Action tells what to do with checked content, a stronger action wins
+// when checks disagree
+type Action int
+
+const (
+	ALLOW Action = iota
+	HOLD
+	REJECT
+)
+
+func (a Action) String() string {
+	switch a {
+	case HOLD:
+		return "hold"
+	case REJECT:
+		return "reject"
+	}
+	return "allow"
+}
+
+// kinds of checked content
+const (
+	KIND_POST    = "post"
+	KIND_COMMENT = "comment"
+)
+
+// ErrRejected is returned when saving content rejected by a check
+var ErrRejected = errors.New("spam: content rejected")
+
+// Content is a new or edited post or comment going to be saved
+// Id: the edited post or comment, 0 for new content
+type Content struct {
+	Kind  string
+	Id    int64
+	User  *models.User
+	Title string
+	Text  string
+}
+
+// Decision is the result of a check, Reason explains actions other than
+// ALLOW and goes to logs and held reports
+type Decision struct {
+	Action Action
+	Check  string
+	Reason string
+}
+
+func (d Decision) String() string {
+	if d.Check == "" {
+		return d.Action.String()
+	}
+	return d.Action.String() + " by " + d.Check + ": " + d.Reason
+}
+
+// Checker is a spam check, a failing check should log its error and
+// allow the content
+type Checker interface {
+	Name() string
+	Check(c *Content) Decision
+}
+
+var (
+	lock     sync.RWMutex
+	checkers []Checker
+)
+
+// Register adds a check run by Check after the registered ones
+func Register(checker Checker) {
+	lock.Lock()
+	defer lock.Unlock()
+	checkers = append(checkers, checker)
+}
+
+// Check runs all checks on content of members below the trusted role,
+// it stops at the first rejection, otherwise holds if any check holds
+func Check(c *Content) Decision {
+	result := Decision{Action: ALLOW}
+	if !setting.SpamEnabled || c.User.IsTrusted() {
+		return result
+	}
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	var reasons []string
+	for _, checker := range checkers {
+		d := checker.Check(c)
+		if d.Action == ALLOW {
+			continue
+		}
+		d.Check = checker.Name()
+		log.Infof("spam: %s of %s %s", c.Kind, c.User.UserName, d)
+
+		if d.Action > result.Action {
+			result.Action = d.Action
+			result.Check = d.Check
+		}
+		reasons = append(reasons, d.Check+": "+d.Reason)
+		if d.Action == REJECT {
+			break
+		}
+	}
+	result.Reason = strings.Join(reasons, "; ")
+	return result
+}
+
+func init() {
+	Register(rateCheck{})
+	Register(linkCheck{})
+	Register(duplicateCheck{})
+	Register(blocklistCheck{})
+}
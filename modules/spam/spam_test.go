@@ -0,0 +1,84 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package spam
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/setting"
+)
+
+func TestParseBlocklist(t *testing.T) {
+	list, err := ParseBlocklist("# comments are skipped\n\nCasino\n/cheap\\s+pills/\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		text string
+		rule string
+	}{
+		{"best online CASINO here", "casino"},
+		{"buy Cheap   pills now", `/cheap\s+pills/`},
+		{"a post about goroutines", ""},
+		{"# comments are skipped", ""},
+	}
+	for _, c := range cases {
+		if rule := list.Match(c.text); rule != c.rule {
+			t.Errorf("Match(%q) = %q, want %q", c.text, rule, c.rule)
+		}
+	}
+}
+
+func TestParseBlocklistBadPattern(t *testing.T) {
+	if _, err := ParseBlocklist("ok\n/(unclosed/"); err == nil {
+		t.Error("bad pattern is parsed")
+	}
+}
+
+func TestNilBlocklist(t *testing.T) {
+	var list *Blocklist
+	if rule := list.Match("anything"); rule != "" {
+		t.Errorf("nil list matches %q", rule)
+	}
+}
+
+func TestCountLinks(t *testing.T) {
+	text := "see http://a.com and [b](HTTPS://b.com), not ftp://c.com"
+	if n := countLinks(text); n != 2 {
+		t.Errorf("countLinks = %d, want 2", n)
+	}
+}
+
+func TestCheckEdits(t *testing.T) {
+	setting.SpamNewUserDays, setting.SpamMaxLinks = 3, 1
+	setting.SpamPostRate, setting.SpamRateMinutes = 1, 10
+	defer func() {
+		setting.SpamNewUserDays, setting.SpamMaxLinks = 0, 0
+		setting.SpamPostRate, setting.SpamRateMinutes = 0, 0
+	}()
+
+	// a new account edits links into its post
+	c := &Content{Kind: KIND_POST, Id: 1, User: &models.User{Created: time.Now()},
+		Text: "see http://a.com and http://b.com"}
+	if d := (linkCheck{}).Check(c); d.Action != HOLD {
+		t.Errorf("links of edit = %s, want hold", d.Action)
+	}
+	if d := (rateCheck{}).Check(c); d.Action != ALLOW {
+		t.Errorf("rate of edit = %s, want allow", d.Action)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// "12345678901234567890" of RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		code, err := Code(rfcSecret, time.Unix(c.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != c.code {
+			t.Errorf("Code at %d = %s, want %s", c.unix, code, c.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	if step, ok := Validate(rfcSecret, "081 804", now); !ok || step != Counter(now) {
+		t.Errorf("Validate = %d, %v", step, ok)
+	}
+	if _, ok := Validate(rfcSecret, "081804", now.Add(Period*time.Second)); !ok {
+		t.Error("previous step is refused")
+	}
+	if _, ok := Validate(rfcSecret, "081804", now.Add(3*Period*time.Second)); ok {
+		t.Error("old code is accepted")
+	}
+	if _, ok := Validate(rfcSecret, "", now); ok {
+		t.Error("empty code is accepted")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret %q", secret)
+	}
+	if _, err := Code(strings.ToLower(secret), time.Now()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("wego", "a b", rfcSecret)
+	if !strings.HasPrefix(uri, "otpauth://totp/wego:a%20b?") || !strings.Contains(uri, "secret="+rfcSecret) {
+		t.Errorf("uri %s", uri)
+	}
+}
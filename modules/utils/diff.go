@@ -0,0 +1,135 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"strings"
+)
+
+const (
+	DIFF_EQUAL = iota
+	DIFF_INSERT
+	DIFF_DELETE
+)
+
+// DiffLine is one line of a line based diff
+type DiffLine struct {
+	Type int
+	Text string
+}
+
+func (d DiffLine) IsInsert() bool {
+	return d.Type == DIFF_INSERT
+}
+
+func (d DiffLine) IsDelete() bool {
+	return d.Type == DIFF_DELETE
+}
+
+// DiffLines compares old and new text line by line with the longest
+// common subsequence, deleted lines are put before inserted lines.
+func DiffLines(old, new string) []DiffLine {
+	a := splitLines(old)
+	b := splitLines(new)
+
+	// lcs[i][j] is the lcs length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diffs := make([]DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diffs = append(diffs, DiffLine{DIFF_EQUAL, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diffs = append(diffs, DiffLine{DIFF_DELETE, a[i]})
+			i++
+		default:
+			diffs = append(diffs, DiffLine{DIFF_INSERT, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diffs = append(diffs, DiffLine{DIFF_DELETE, a[i]})
+	}
+	for ; j < len(b); j++ {
+		diffs = append(diffs, DiffLine{DIFF_INSERT, b[j]})
+	}
+	return diffs
+}
+
+func splitLines(s string) []string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// DiffRow is a row of side by side diff, Old or New is nil when the line
+// only exists on one side
+type DiffRow struct {
+	Old *DiffLine
+	New *DiffLine
+}
+
+// DiffRows pairs up deleted and inserted lines of diffs for a side by side view
+func DiffRows(diffs []DiffLine) []DiffRow {
+	rows := make([]DiffRow, 0, len(diffs))
+	for i := 0; i < len(diffs); {
+		if diffs[i].Type == DIFF_EQUAL {
+			rows = append(rows, DiffRow{&diffs[i], &diffs[i]})
+			i++
+			continue
+		}
+
+		// a run of changed lines
+		var dels, ins []*DiffLine
+		for ; i < len(diffs) && diffs[i].Type != DIFF_EQUAL; i++ {
+			if diffs[i].Type == DIFF_DELETE {
+				dels = append(dels, &diffs[i])
+			} else {
+				ins = append(ins, &diffs[i])
+			}
+		}
+		for j := 0; j < len(dels) || j < len(ins); j++ {
+			var row DiffRow
+			if j < len(dels) {
+				row.Old = dels[j]
+			}
+			if j < len(ins) {
+				row.New = ins[j]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
@@ -112,6 +112,23 @@ func (this *FormSets) SetError(fieldName, errMsg string) {
 	}
 }
 
+// Remove drops fields not used by the current settings
+func (this *FormSets) Remove(fieldNames ...string) {
+	for _, name := range fieldNames {
+		fSet, ok := this.Fields[name]
+		if !ok {
+			continue
+		}
+		delete(this.Fields, name)
+		for i, f := range this.FieldList {
+			if f == fSet {
+				this.FieldList = append(this.FieldList[:i], this.FieldList[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
 // create formSets for generate label/field html code
 func NewFormSets(form interface{}, errs map[string]*validation.ValidationError, locale FormLocaler) *FormSets {
 	fSets := new(FormSets)
@@ -0,0 +1,146 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package admin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+)
+
+type AuditAdminRouter struct {
+	BaseAdminRouter
+}
+
+func (this *AuditAdminRouter) Before() {
+	this.BaseAdminRouter.Before()
+	this.Data["auditAdmin"] = true
+}
+
+// filter reads ?actor=&action=&model=&target= of the request, ok is false
+// when the actor doesn't exist
+func (this *AuditAdminRouter) filter() (filter models.AuditLogFilter, ok bool) {
+	actor := strings.TrimSpace(this.GetString("actor"))
+	filter.Action = strings.TrimSpace(this.GetString("action"))
+	filter.TargetModel = strings.TrimSpace(this.GetString("model"))
+	filter.TargetId, _ = this.GetInt("target")
+
+	this.Data["Actor"] = actor
+	this.Data["Action"] = filter.Action
+	this.Data["Model"] = filter.TargetModel
+	if filter.TargetId > 0 {
+		this.Data["Target"] = filter.TargetId
+	}
+	this.Data["Query"] = this.Req().URL.RawQuery
+
+	if actor != "" {
+		user, err := models.GetUserByName(actor)
+		if err != nil {
+			return filter, false
+		}
+		filter.ActorId = user.Id
+	}
+	return filter, true
+}
+
+type AuditAdminList struct {
+	AuditAdminRouter
+}
+
+// list audit logs, newest first
+func (this *AuditAdminList) Get() {
+	this.TplNames = "admin/audit/list.html"
+
+	filter, ok := this.filter()
+	if !ok {
+		this.Data["ActorNotFound"] = true
+		return
+	}
+
+	cnt, err := models.CountAuditLogs(filter)
+	if err != nil {
+		this.Data["Error"] = err
+		log.Error(err)
+		return
+	}
+
+	p := this.SetPaginator(20, cnt)
+	logs, err := models.FindAuditLogs(filter, p.PerPageNums, p.Offset())
+	if err != nil {
+		this.Data["Error"] = err
+		log.Error(err)
+		return
+	}
+	this.Data["Objects"] = logs
+	this.Data["ObjectsCnt"] = cnt
+}
+
+type AuditAdminExport struct {
+	AuditAdminRouter
+}
+
+// export all logs matching the filter as csv
+func (this *AuditAdminExport) Get() {
+	// Before has redirected users who are not admin
+	if this.Written() {
+		return
+	}
+
+	filter, ok := this.filter()
+	if !ok {
+		this.NotFound()
+		return
+	}
+
+	logs, err := models.FindAuditLogs(filter, 0, 0)
+	if err != nil {
+		log.Error("AuditAdminExport: ", err)
+		this.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// actors are looked up once for all their logs
+	actors := make(map[int64]string)
+
+	this.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	this.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=audit-%s.csv", time.Now().Format("20060102150405")))
+
+	w := csv.NewWriter(this.ResponseWriter)
+	w.Write([]string{"id", "created", "actor", "action", "model", "target", "field", "before", "after", "ip"})
+	for _, m := range logs {
+		name, ok := actors[m.ActorId]
+		if !ok {
+			if user := m.Actor(); user != nil {
+				name = user.UserName
+			}
+			actors[m.ActorId] = name
+		}
+
+		for _, row := range m.CSVRows(name) {
+			w.Write(row)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Error("AuditAdminExport: ", err)
+	}
+}
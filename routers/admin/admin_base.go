@@ -38,12 +38,17 @@ func (this *BaseAdminRouter) Before() {
 		return
 	}
 
-	// if user isn't admin, then logout user
 	if !this.User.IsAdmin {
-		auth.LogoutUser(this.Context, &this.Session)
-		// write flash message, use .flash.NotPermit
-		this.FlashWrite("NotPermit", "true")
-		this.Redirect("/login", 302)
+		// moderators can only handle reports
+		if this.User.CanModerate() {
+			this.FlashRedirect("/admin/reports", 302, "NotPermit")
+			return
+		}
+		this.notPermit()
+		return
+	}
+
+	if this.checkTwoFactor() {
 		return
 	}
 
@@ -51,6 +56,47 @@ func (this *BaseAdminRouter) Before() {
 	this.Data["IsAdminPage"] = true
 }
 
+// admins may be required to enable two-factor authentication first
+func (this *BaseAdminRouter) checkTwoFactor() bool {
+	if auth.TwoFactorRequired(&this.User) && !auth.HasTwoFactor(&this.User) {
+		this.FlashRedirect("/settings/security", 302, "TwoFactorRequired")
+		return true
+	}
+	return false
+}
+
+// if user isn't admin or moderator, then logout user
+func (this *BaseAdminRouter) notPermit() {
+	auth.LogoutUser(this.Context, &this.Session)
+	// write flash message, use .flash.NotPermit
+	this.FlashWrite("NotPermit", "true")
+	this.Redirect("/login", 302)
+}
+
+// BaseModerateRouter is for admin pages open to moderators of any category
+type BaseModerateRouter struct {
+	BaseAdminRouter
+}
+
+func (this *BaseModerateRouter) Before() {
+	this.BaseRouter.Before()
+
+	if this.CheckActiveRedirect() {
+		return
+	}
+
+	if !this.User.CanModerate() {
+		this.notPermit()
+		return
+	}
+
+	if this.checkTwoFactor() {
+		return
+	}
+
+	this.Data["IsAdminPage"] = true
+}
+
 type ModelFinder interface {
 	Object() interface{}
 }
@@ -54,6 +54,7 @@ func (this *BulletinAdminNew) Post() {
 	var bulletin models.Bulletin
 	form.SetToBulletin(&bulletin)
 	if err := models.Insert(&bulletin); err == nil {
+		this.Audit(models.AUDIT_CREATE, "bulletin", bulletin.Id, nil)
 		this.FlashRedirect(fmt.Sprintf("/admin/bulletin/%d", bulletin.Id), 302, "CreateSuccess")
 		return
 	} else {
@@ -85,8 +86,10 @@ func (this *BulletinAdminEdit) Post() {
 
 	// update changed fields only
 	if len(changes) > 0 {
+		old := this.object
 		form.SetToBulletin(&this.object)
 		if err := models.UpdateById(this.object.Id, this.object, models.Obj2Table(changes)...); err == nil {
+			this.Audit(models.AUDIT_UPDATE, "bulletin", this.object.Id, models.AuditDiff(&old, &this.object, changes))
 			this.FlashRedirect(url, 302, "UpdateSuccess")
 			return
 		} else {
@@ -110,6 +113,7 @@ func (this *BulletinAdminDelete) Post() {
 	if cnt > 0 {
 		// delete object
 		if err := models.DeleteById(this.object.Id, new(models.Bulletin)); err == nil {
+			this.Audit(models.AUDIT_DELETE, "bulletin", this.object.Id, nil)
 			this.FlashRedirect("/admin/bulletin", 302, "DeleteSuccess")
 			return
 		} else {
@@ -16,6 +16,7 @@ package admin
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/lunny/log"
 
@@ -73,6 +74,7 @@ func (this *CategoryAdminNew) Post() {
 	var cat models.Category
 	form.SetToCategory(&cat)
 	if err := models.Insert(&cat); err == nil {
+		this.Audit(models.AUDIT_CREATE, "category", cat.Id, nil)
 		this.FlashRedirect(fmt.Sprintf("/admin/category/%d", cat.Id), 302, "CreateSuccess")
 		return
 	} else {
@@ -90,12 +92,23 @@ func (this *CategoryAdminEdit) Get() {
 	form := post.CategoryAdminForm{}
 	form.SetFromCategory(&this.object)
 	this.SetFormSets(&form)
+	this.setModerators()
+}
+
+// set moderators assigned to the category
+func (this *CategoryAdminRouter) setModerators() {
+	mods, err := models.FindCategoryModerators(this.object.Id)
+	if err != nil {
+		log.Error(err)
+	}
+	this.Data["Moderators"] = mods
 }
 
 // view for update object
 func (this *CategoryAdminEdit) Post() {
 	form := post.CategoryAdminForm{Id: int(this.object.Id)}
 	if this.ValidFormSets(&form) == false {
+		this.setModerators()
 		return
 	}
 
@@ -106,8 +119,10 @@ func (this *CategoryAdminEdit) Post() {
 
 	// update changed fields only
 	if len(changes) > 0 {
+		old := this.object
 		form.SetToCategory(&this.object)
 		if err := models.UpdateById(this.object.Id, this.object, models.Obj2Table(changes)...); err == nil {
+			this.Audit(models.AUDIT_UPDATE, "category", this.object.Id, models.AuditDiff(&old, &this.object, changes))
 			this.FlashRedirect(url, 302, "UpdateSuccess")
 			return
 		} else {
@@ -119,6 +134,38 @@ func (this *CategoryAdminEdit) Post() {
 	}
 }
 
+type CategoryModeratorAdmin struct {
+	CategoryAdminRouter
+}
+
+// assign a user to moderate the category, or remove one with action=remove
+func (this *CategoryModeratorAdmin) Post() {
+	url := fmt.Sprintf("/admin/category/%d", this.object.Id)
+
+	user, err := models.GetUserByName(strings.TrimSpace(this.GetString("user")))
+	if err != nil {
+		this.FlashRedirect(url, 302, "ModeratorNotFound")
+		return
+	}
+
+	action := "moderator.add"
+	change := models.AuditChange{Field: "Moderator", After: user.UserName}
+	if this.GetString("action") == "remove" {
+		action = "moderator.remove"
+		change = models.AuditChange{Field: "Moderator", Before: user.UserName}
+		err = models.RemoveCategoryModerator(user.Id, this.object.Id)
+	} else {
+		err = models.AddCategoryModerator(user.Id, this.object.Id)
+	}
+	if err != nil {
+		log.Error(err)
+		this.Data["Error"] = err
+		return
+	}
+	this.Audit(action, "category", this.object.Id, []models.AuditChange{change})
+	this.FlashRedirect(url, 302, "UpdateSuccess")
+}
+
 type CategoryAdminDelete struct {
 	CategoryAdminRouter
 }
@@ -136,6 +183,7 @@ func (this *CategoryAdminDelete) Post() {
 	} else {
 		// delete object
 		if err := models.DeleteById(this.object.Id, this.object); err == nil {
+			this.Audit(models.AUDIT_DELETE, "category", this.object.Id, nil)
 			this.FlashRedirect("/admin/category", 302, "DeleteSuccess")
 			return
 		} else {
@@ -16,11 +16,13 @@ package admin
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lunny/log"
 
 	"github.com/go-tango/wego/models"
 	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/search"
 	"github.com/go-tango/wego/modules/utils"
 )
 
@@ -73,6 +75,10 @@ func (this *CommentAdminNew) Post() {
 	var comment models.Comment
 	form.SetToComment(&comment)
 	if err := models.Insert(&comment); err == nil {
+		if p := comment.Post(); p != nil {
+			search.IndexComment(&comment, p)
+		}
+		this.Audit(models.AUDIT_CREATE, "comment", comment.Id, nil)
 		this.FlashRedirect(fmt.Sprintf("/admin/comment/%d", comment.Id), 302, "CreateSuccess")
 		return
 	} else {
@@ -106,8 +112,29 @@ func (this *CommentAdminEdit) Post() {
 
 	// update changed fields only
 	if len(changes) > 0 {
+		old := this.object
+		cols := models.Obj2Table(changes)
+		// keep the old message in comment history
+		if form.Message != this.object.Message {
+			history := models.CommentHistory{
+				CommentId: this.object.Id,
+				UserId:    this.User.Id,
+				Message:   this.object.Message,
+				Action:    models.COMMENT_HISTORY_EDIT,
+			}
+			if err := models.InsertCommentHistory(&history); err != nil {
+				log.Error(err)
+			}
+			this.object.Edited = time.Now()
+			cols = append(cols, "message_cache", "edited")
+		}
+
 		form.SetToComment(&this.object)
-		if err := models.UpdateById(this.object.Id, this.object, models.Obj2Table(changes)...); err == nil {
+		if err := models.UpdateById(this.object.Id, this.object, cols...); err == nil {
+			if p := this.object.Post(); p != nil {
+				search.IndexComment(&this.object, p)
+			}
+			this.Audit(models.AUDIT_UPDATE, "comment", this.object.Id, models.AuditDiff(&old, &this.object, changes))
 			this.FlashRedirect(url, 302, "UpdateSuccess")
 			return
 		} else {
@@ -123,14 +150,21 @@ type CommentAdminDelete struct {
 	CommentAdminRouter
 }
 
-// view for delete object
+// view for delete object, the comment is soft deleted like users do so
+// replies and its history are kept
 func (this *CommentAdminDelete) Post() {
 	if this.FormOnceNotMatch() {
 		return
 	}
 
+	if this.object.IsDeleted() {
+		this.FlashRedirect("/admin/comment", 302, "DeleteSuccess")
+		return
+	}
+
 	// delete object
-	if err := models.DeleteById(this.object.Id, this.object); err == nil {
+	if err := post.DeleteComment(&this.object, &this.User); err == nil {
+		this.Audit(models.AUDIT_DELETE, "comment", this.object.Id, nil)
 		this.FlashRedirect("/admin/comment", 302, "DeleteSuccess")
 		return
 	} else {
@@ -0,0 +1,90 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package admin
+
+import (
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/mailer"
+	"github.com/go-tango/wego/modules/utils"
+)
+
+// status filters of the mail queue list
+var mailStatusFilters = map[string]int{
+	"pending": models.MAIL_QUEUE_PENDING,
+	"sending": models.MAIL_QUEUE_SENDING,
+	"sent":    models.MAIL_QUEUE_SENT,
+	"failed":  models.MAIL_QUEUE_FAILED,
+}
+
+type MailAdminRouter struct {
+	BaseAdminRouter
+}
+
+func (this *MailAdminRouter) Before() {
+	this.BaseAdminRouter.Before()
+	this.Data["mailAdmin"] = true
+}
+
+type MailAdminList struct {
+	MailAdminRouter
+}
+
+// list queued, sent and failed mails
+func (this *MailAdminList) Get() {
+	this.TplNames = "admin/mail/list.html"
+
+	filter := this.GetString("status")
+	status, ok := mailStatusFilters[filter]
+	if !ok {
+		filter = ""
+		status = -1
+	}
+	this.Data["Status"] = filter
+
+	cnt, err := models.CountMailQueue(status)
+	if err != nil {
+		this.Data["Error"] = err
+		log.Error(err)
+		return
+	}
+
+	p := this.SetPaginator(20, cnt)
+	mails, err := models.FindMailQueue(status, p.PerPageNums, p.Offset())
+	if err != nil {
+		this.Data["Error"] = err
+		log.Error(err)
+		return
+	}
+	this.Data["Objects"] = mails
+	this.Data["ObjectsCnt"] = cnt
+}
+
+type MailAdminResend struct {
+	MailAdminRouter
+}
+
+// put a mail back to queue
+func (this *MailAdminResend) Post() {
+	id, _ := utils.StrTo(this.Params().Get(":id")).Int64()
+	if err := mailer.Resend(id); err != nil {
+		log.Error("MailAdminResend: ", err)
+		this.FlashRedirect("/admin/mail", 302, "ResendFailed")
+		return
+	}
+	this.Audit("resend", "mail", id, nil)
+	this.FlashRedirect("/admin/mail", 302, "ResendSuccess")
+}
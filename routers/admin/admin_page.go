@@ -73,6 +73,7 @@ func (this *PageAdminNew) Post() {
 	var a models.Page
 	form.SetToPage(&a)
 	if err := models.Insert(&a); err == nil {
+		this.Audit(models.AUDIT_CREATE, "page", a.Id, nil)
 		this.FlashRedirect(fmt.Sprintf("/admin/page/%d", a.Id), 302, "CreateSuccess")
 		return
 	} else {
@@ -106,8 +107,10 @@ func (this *PageAdminEdit) Post() {
 
 	// update changed fields only
 	if len(changes) > 0 {
+		old := this.object
 		form.SetToPage(&this.object)
 		if err := models.UpdateById(this.object.Id, this.object, models.Obj2Table(changes)...); err == nil {
+			this.Audit(models.AUDIT_UPDATE, "page", this.object.Id, models.AuditDiff(&old, &this.object, changes))
 			this.FlashRedirect(url, 302, "UpdateSuccess")
 			return
 		} else {
@@ -131,6 +134,7 @@ func (this *PageAdminDelete) Post() {
 
 	// delete object
 	if err := models.DeleteById(this.object.Id, this.object); err == nil {
+		this.Audit(models.AUDIT_DELETE, "page", this.object.Id, nil)
 		this.FlashRedirect("/admin/page", 302, "DeleteSuccess")
 		return
 	} else {
@@ -21,6 +21,7 @@ import (
 
 	"github.com/go-tango/wego/models"
 	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/search"
 	"github.com/go-tango/wego/modules/utils"
 )
 
@@ -79,6 +80,8 @@ func (this *PostAdminNew) Post() {
 	var post models.Post
 	form.SetToPost(&post)
 	if err := models.Insert(&post); err == nil {
+		search.IndexPost(&post)
+		this.Audit(models.AUDIT_CREATE, "post", post.Id, nil)
 		this.FlashRedirect(fmt.Sprintf("/admin/post/%d", post.Id), 302, "CreateSuccess")
 		return
 	} else {
@@ -114,8 +117,14 @@ func (this *PostAdminEdit) Post() {
 	if len(changes) > 0 {
 		//fix the bug of category not updated
 		changes = append(changes, "Category")
+		old := this.object
 		form.SetToPost(&this.object)
 		if err := models.UpdateById(this.object.Id, this.object, models.Obj2Table(changes)...); err == nil {
+			search.IndexPost(&this.object)
+			if err := post.RecordPostRevision(&old, &this.object, &this.User); err != nil {
+				log.Error(err)
+			}
+			this.Audit(models.AUDIT_UPDATE, "post", this.object.Id, models.AuditDiff(&old, &this.object, changes))
 			this.FlashRedirect(url, 302, "UpdateSuccess")
 			return
 		} else {
@@ -138,7 +147,8 @@ func (this *PostAdminDelete) Get() {
 	}
 
 	// delete object
-	if err := models.DeleteById(this.object.Id, this.object); err == nil {
+	if err := post.DeletePost(&this.object, &this.User); err == nil {
+		this.Audit(models.AUDIT_DELETE, "post", this.object.Id, nil)
 		this.FlashRedirect("/admin/post", 302, "DeleteSuccess")
 		return
 	} else {
@@ -0,0 +1,97 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package admin
+
+import (
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/utils"
+)
+
+type ReportAdminRouter struct {
+	BaseModerateRouter
+}
+
+func (this *ReportAdminRouter) Before() {
+	this.BaseModerateRouter.Before()
+	this.Data["reportAdmin"] = true
+}
+
+type ReportAdminList struct {
+	ReportAdminRouter
+}
+
+// list open reports, or closed reports with ?status=closed
+func (this *ReportAdminList) Get() {
+	this.TplNames = "admin/report/list.html"
+
+	open := this.GetString("status") != "closed"
+	this.Data["Open"] = open
+
+	// category moderators see reports of their categories only
+	var categoryIds []int64
+	if !this.User.IsModerator() {
+		categoryIds = this.User.ModeratedCategoryIds()
+	}
+
+	cnt, err := models.CountReports(open, categoryIds)
+	if err != nil {
+		this.Data["Error"] = err
+		log.Error(err)
+		return
+	}
+
+	p := this.SetPaginator(20, cnt)
+	reports, err := models.FindReports(open, categoryIds, p.PerPageNums, p.Offset())
+	if err != nil {
+		this.Data["Error"] = err
+		log.Error(err)
+		return
+	}
+	this.Data["Objects"] = reports
+	this.Data["ObjectsCnt"] = cnt
+}
+
+type ReportAdminAction struct {
+	ReportAdminRouter
+}
+
+// apply approve, hide, delete or ban to the target of a report
+func (this *ReportAdminAction) Post() {
+	id, _ := utils.StrTo(this.Params().Get(":id")).Int64()
+	report, err := models.GetReport(id)
+	if err != nil {
+		this.NotFound()
+		return
+	}
+
+	action := this.Params().Get(":action")
+	switch err := post.Moderate(report, action, &this.User); err {
+	case nil:
+		this.Audit("moderate."+action, report.TargetName(), report.TargetId, []models.AuditChange{
+			{Field: "Report", After: utils.ToStr(report.Id)},
+		})
+		this.FlashRedirect("/admin/reports", 302, "ModerateSuccess")
+	case post.ErrBanAdmin:
+		this.FlashRedirect("/admin/reports", 302, "ModerateBanAdmin")
+	case post.ErrModerateDenied:
+		this.FlashRedirect("/admin/reports", 302, "ModerateDenied")
+	default:
+		log.Error("ReportAdminAction: ", err)
+		this.FlashRedirect("/admin/reports", 302, "ModerateFailed")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package admin
+
+import (
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/robots"
+)
+
+// RobotsAdmin edits rules of robots.txt
+type RobotsAdmin struct {
+	BaseAdminRouter
+}
+
+func (this *RobotsAdmin) Before() {
+	this.BaseAdminRouter.Before()
+	this.Data["robotsAdmin"] = true
+	this.TplNames = "admin/robots.html"
+}
+
+func (this *RobotsAdmin) Get() {
+	rules, custom := robots.Current()
+	this.Data["Rules"] = rules.String()
+	this.Data["IsCustom"] = custom
+}
+
+// save edited rules, or go back to rules in config
+func (this *RobotsAdmin) Post() {
+	if this.GetString("action") == "reset" {
+		if err := robots.Reset(); err != nil {
+			log.Error("RobotsAdmin: ", err)
+			this.Data["Error"] = err
+			this.Get()
+			return
+		}
+		this.Audit("robots.reset", "robots", 0, nil)
+		this.FlashRedirect("/admin/robots", 302, "ResetSuccess")
+		return
+	}
+
+	text := this.GetString("rules")
+	before, _ := robots.Current()
+	if err := robots.Save(text); err != nil {
+		_, custom := robots.Current()
+		this.Data["Rules"] = text
+		this.Data["IsCustom"] = custom
+		this.Data["Error"] = err
+		return
+	}
+	this.Audit(models.AUDIT_UPDATE, "robots", 0, []models.AuditChange{
+		{Field: "Rules", Before: before.String(), After: text},
+	})
+	this.FlashRedirect("/admin/robots", 302, "UpdateSuccess")
+}
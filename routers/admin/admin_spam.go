@@ -0,0 +1,62 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package admin
+
+import (
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/spam"
+)
+
+// SpamAdmin edits words and patterns blocked by spam checks
+type SpamAdmin struct {
+	BaseAdminRouter
+}
+
+func (this *SpamAdmin) Before() {
+	this.BaseAdminRouter.Before()
+	this.Data["spamAdmin"] = true
+	this.TplNames = "admin/spam.html"
+}
+
+func (this *SpamAdmin) Get() {
+	reject, hold := spam.Blocklists()
+	this.Data["Reject"] = reject.String()
+	this.Data["Hold"] = hold.String()
+}
+
+func (this *SpamAdmin) Post() {
+	reject := this.GetString("reject")
+	hold := this.GetString("hold")
+
+	oldReject, oldHold := spam.Blocklists()
+	if err := spam.SaveBlocklists(reject, hold); err != nil {
+		this.Data["Reject"] = reject
+		this.Data["Hold"] = hold
+		this.Data["Error"] = err
+		return
+	}
+
+	var changes []models.AuditChange
+	if reject != oldReject.String() {
+		changes = append(changes, models.AuditChange{Field: "Reject", Before: oldReject.String(), After: reject})
+	}
+	if hold != oldHold.String() {
+		changes = append(changes, models.AuditChange{Field: "Hold", Before: oldHold.String(), After: hold})
+	}
+	if len(changes) > 0 {
+		this.Audit(models.AUDIT_UPDATE, "spam", 0, changes)
+	}
+	this.FlashRedirect("/admin/spam", 302, "UpdateSuccess")
+}
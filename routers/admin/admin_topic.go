@@ -72,6 +72,7 @@ func (this *TopicAdminNew) Post() {
 	var topic models.Topic
 	form.SetToTopic(&topic)
 	if err := models.Insert(&topic); err == nil {
+		this.Audit(models.AUDIT_CREATE, "topic", topic.Id, nil)
 		this.FlashRedirect(fmt.Sprintf("/admin/topic/%d", topic.Id), 302, "CreateSuccess")
 		return
 	} else {
@@ -106,8 +107,10 @@ func (this *TopicAdminEdit) Post() {
 
 	// update changed fields only
 	if len(changes) > 0 {
+		old := this.object
 		form.SetToTopic(&this.object)
 		if err := models.UpdateById(this.object.Id, this.object, models.Obj2Table(changes)...); err == nil {
+			this.Audit(models.AUDIT_UPDATE, "topic", this.object.Id, models.AuditDiff(&old, &this.object, changes))
 			this.FlashRedirect(url, 302, "UpdateSuccess")
 			return
 		} else {
@@ -136,6 +139,7 @@ func (this *TopicAdminDelete) Post() {
 	} else {
 		// delete object
 		if err := models.DeleteById(this.object.Id, this.object); err == nil {
+			this.Audit(models.AUDIT_DELETE, "topic", this.object.Id, nil)
 			this.FlashRedirect("/admin/topic", 302, "DeleteSuccess")
 			return
 		} else {
@@ -79,6 +79,7 @@ func (this *UserAdminNew) Post() {
 	var user models.User
 	form.SetToUser(&user)
 	if err := models.Insert(&user); err == nil {
+		this.Audit(models.AUDIT_CREATE, "user", user.Id, nil)
 		this.FlashRedirect(fmt.Sprintf("/admin/user/%d", user.Id), 302, "CreateSuccess")
 		return
 	} else {
@@ -107,13 +108,19 @@ func (this *UserAdminEdit) Post() {
 
 	// get changed field names
 	changes := utils.FormChanges(&this.object, &form)
+	// IsAdmin follows the role
+	if form.Role != this.object.GetRole() {
+		changes = append(changes, "IsAdmin")
+	}
 
 	url := fmt.Sprintf("/admin/user/%d", this.object.Id)
 
 	// update changed fields only
 	if len(changes) > 0 {
+		old := this.object
 		form.SetToUser(&this.object)
 		if err := models.UpdateById(this.object.Id, this.object, models.Obj2Table(changes)...); err == nil {
+			this.Audit(models.AUDIT_UPDATE, "user", this.object.Id, models.AuditDiff(&old, &this.object, changes))
 			this.FlashRedirect(url, 302, "UpdateSuccess")
 			return
 		} else {
@@ -135,8 +142,9 @@ func (this *UserAdminDelete) Post() {
 		return
 	}
 
-	// delete object
-	if err := models.DeleteById(this.object.Id, this.object); err == nil {
+	// delete object, the content goes to the ghost user
+	if err := auth.DeleteAccount(&this.object); err == nil {
+		this.Audit(models.AUDIT_DELETE, "user", this.object.Id, nil)
 		this.FlashRedirect("/admin/user", 302, "DeleteSuccess")
 		return
 	} else {
@@ -2,14 +2,18 @@ package api
 
 import (
 	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/routers/base"
-
-	"github.com/tango-contrib/xsrf"
 )
 
 type Post struct {
 	base.BaseRouter
-	xsrf.NoCheck
+}
+
+// CheckXsrf overrides BaseRouter, ajax actions need no xsrf check
+func (this *Post) CheckXsrf() bool {
+	return false
 }
 
 func (this *Post) Post() {
@@ -27,26 +31,29 @@ func (this *Post) Post() {
 	action := this.GetString("action")
 	switch action {
 	case "toggle-best":
-		if this.User.IsAdmin {
-			if postId, err := this.GetInt("post"); err == nil {
-				//set post best
-				var post models.Post
-				if err := models.GetById(postId, &post); err == nil {
-					post.IsBest = !post.IsBest
-					if models.UpdateById(post.Id, post, "is_best") == nil {
-						result["success"] = true
-					}
+		if postId, err := this.GetInt("post"); err == nil {
+			//set post best
+			var post models.Post
+			if err := models.GetById(postId, &post); err == nil && post.CanMarkBestBy(&this.User) {
+				post.IsBest = !post.IsBest
+				if models.UpdateById(post.Id, post, "is_best") == nil {
+					this.Audit(action, "post", post.Id, []models.AuditChange{{
+						Field:  "IsBest",
+						Before: utils.ToStr(!post.IsBest),
+						After:  utils.ToStr(post.IsBest),
+					}})
+					result["success"] = true
 				}
-			} else {
-				this.Logger.Error("post value is not int:", this.GetString("post"))
 			}
+		} else {
+			this.Logger.Error("post value is not int:", this.GetString("post"))
 		}
 	case "toggle-fav":
 		if postId, err := this.GetInt("post"); err == nil {
-			var post models.Post
-			if err := models.GetById(postId, &post); err == nil {
+			var postMd models.Post
+			if err := models.GetById(postId, &postMd); err == nil {
 				var favoritePost = models.FavoritePost{
-					PostId: post.Id,
+					PostId: postMd.Id,
 					UserId: this.User.Id,
 				}
 
@@ -68,10 +75,12 @@ func (this *Post) Post() {
 				} else if err == models.ErrNotExist {
 					favoritePost = models.FavoritePost{
 						UserId: this.User.Id,
-						PostId: post.Id,
+						PostId: postMd.Id,
 						IsFav:  true,
 					}
 					if models.Insert(favoritePost) == nil {
+						post.NotifyFavorite(&this.User, &postMd)
+
 						//update user fav post count
 						this.User.FavPosts += 1
 						if models.UpdateById(this.User.Id, this.User, "fav_posts") == nil {
@@ -0,0 +1,347 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package v1 implemented the versioned JSON REST API mounted at /api/v1.
+package v1
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Unknwon/i18n"
+	"github.com/go-xweb/xweb/validation"
+	"github.com/tango-contrib/renders"
+	"github.com/tango-contrib/xsrf"
+
+	"github.com/go-tango/wego/modules/auth"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/routers/base"
+)
+
+// Error is the error object of a failed API response
+type Error struct {
+	Status  int               `json:"-"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	ErrBadRequest   = &Error{Status: http.StatusBadRequest, Code: "bad_request", Message: "request is malformed"}
+	ErrUnauthorized = &Error{Status: http.StatusUnauthorized, Code: "unauthorized", Message: "authentication required"}
+	ErrInactive     = &Error{Status: http.StatusForbidden, Code: "inactive", Message: "account email is not verified"}
+	ErrForbidden    = &Error{Status: http.StatusForbidden, Code: "forbidden", Message: "permission denied"}
+	ErrInvalidToken = &Error{Status: http.StatusUnauthorized, Code: "invalid_token", Message: "access token is invalid"}
+	ErrTwoFactor    = &Error{Status: http.StatusForbidden, Code: "two_factor_required", Message: "two-factor authentication must be enabled"}
+	ErrTokenScope   = &Error{Status: http.StatusForbidden, Code: "insufficient_scope", Message: "access token scope not allowed"}
+	ErrXsrf         = &Error{Status: http.StatusForbidden, Code: "invalid_xsrf", Message: "xsrf token is missing or invalid"}
+	ErrNotFound     = &Error{Status: http.StatusNotFound, Code: "not_found", Message: "resource not found"}
+	ErrConflict     = &Error{Status: http.StatusConflict, Code: "conflict", Message: "resource is still in use"}
+	ErrValidation   = &Error{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Message: "some fields are invalid"}
+	ErrSpam         = &Error{Status: http.StatusUnprocessableEntity, Code: "spam_rejected", Message: "content is rejected as spam"}
+	ErrInternal     = &Error{Status: http.StatusInternalServerError, Code: "internal_error", Message: "internal server error"}
+)
+
+// envelope wraps every API response
+type envelope struct {
+	Success    bool        `json:"success"`
+	Data       interface{} `json:"data,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Error      *Error      `json:"error,omitempty"`
+}
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Router is the base of all API routers. Requests are authenticated by an
+// access token or the browser session, mutating requests of session must
+// send the xsrf cookie value back in the X-Xsrftoken header or the _xsrf
+// parameter.
+type Router struct {
+	base.BaseRouter
+
+	input map[string]string
+}
+
+// CheckXsrf overrides BaseRouter, the xsrf token is checked by RequireLogin
+func (this *Router) CheckXsrf() bool {
+	return false
+}
+
+func (this *Router) Before() {
+	this.Data = make(renders.T)
+
+	switch raw := auth.BearerToken(this.Req()); {
+	case raw != "":
+		switch this.LoginByToken(raw) {
+		case http.StatusUnauthorized:
+			this.Fail(ErrInvalidToken)
+			return
+		case http.StatusForbidden:
+			this.Fail(ErrTokenScope)
+			return
+		}
+	case auth.LoginUserFromSession(&this.User, this.Context, &this.Session):
+		this.IsLogin = true
+	case auth.LoginUserFromRememberCookie(&this.User, this.Ctx.Context, &this.Session):
+		this.IsLogin = true
+	}
+
+	if this.IsLogin && this.User.IsForbid {
+		auth.LogoutUser(this.Context, &this.Session)
+		this.IsLogin = false
+	}
+
+	this.Lang = "en-US"
+	if this.IsLogin {
+		this.Lang = i18n.GetLangByIndex(this.User.Lang)
+	} else if al := this.Header().Get("Accept-Language"); len(al) > 4 && i18n.IsExist(al[:5]) {
+		this.Lang = al[:5]
+	}
+}
+
+// Serve writes a success envelope with data
+func (this *Router) Serve(data interface{}, status ...int) {
+	this.write(&envelope{Success: true, Data: data}, status...)
+}
+
+// ServeList writes a success envelope with a page of data and the next cursor
+func (this *Router) ServeList(data interface{}, next int64) {
+	env := &envelope{Success: true, Data: data}
+	if next > 0 {
+		env.NextCursor = encodeCursor(next)
+	}
+	this.write(env)
+}
+
+// Fail writes an error envelope
+func (this *Router) Fail(err *Error) {
+	this.write(&envelope{Error: err}, err.Status)
+}
+
+// FailValidation writes the errors of a form validation
+func (this *Router) FailValidation(valid *validation.Validation) {
+	e := *ErrValidation
+	e.Fields = make(map[string]string)
+	for name, verr := range valid.ErrorMap() {
+		e.Fields[snakeName(name)] = this.Tr(verr.Message)
+	}
+	this.Fail(&e)
+}
+
+func (this *Router) write(env *envelope, status ...int) {
+	if this.Written() {
+		return
+	}
+	code := http.StatusOK
+	if len(status) > 0 {
+		code = status[0]
+	}
+	this.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	this.WriteHeader(code)
+	if err := json.NewEncoder(this).Encode(env); err != nil {
+		this.Logger.Error("api: encode response", err)
+	}
+}
+
+// RequireLogin writes an error and returns true if the request can not act as a user
+func (this *Router) RequireLogin() bool {
+	if this.Written() {
+		return true
+	}
+	if !this.IsLogin {
+		this.Fail(ErrUnauthorized)
+		return true
+	}
+	if !this.checkXsrf() {
+		this.Fail(ErrXsrf)
+		return true
+	}
+	return false
+}
+
+// RequireActive likes RequireLogin and also need the user email verified
+func (this *Router) RequireActive() bool {
+	if this.RequireLogin() {
+		return true
+	}
+	if !this.User.IsActive {
+		this.Fail(ErrInactive)
+		return true
+	}
+	return false
+}
+
+// RequireAdmin likes RequireLogin and also need the user is admin
+func (this *Router) RequireAdmin() bool {
+	if this.RequireLogin() {
+		return true
+	}
+	if !this.User.IsAdmin {
+		this.Fail(ErrForbidden)
+		return true
+	}
+	if auth.TwoFactorRequired(&this.User) && !auth.HasTwoFactor(&this.User) {
+		this.Fail(ErrTwoFactor)
+		return true
+	}
+	return false
+}
+
+func (this *Router) checkXsrf() bool {
+	if this.Token != nil {
+		return true
+	}
+	switch this.Req().Method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	cookie, err := this.Req().Cookie(xsrf.XSRF_TAG)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	value := this.Req().Header.Get("X-Xsrftoken")
+	if value == "" {
+		value = this.Input()["_xsrf"]
+	}
+	return value == cookie.Value
+}
+
+// Input returns the request parameters from a JSON object body or the form,
+// keys are in snake case as in responses.
+func (this *Router) Input() map[string]string {
+	if this.input != nil {
+		return this.input
+	}
+	this.input = make(map[string]string)
+
+	if strings.HasPrefix(this.Req().Header.Get("Content-Type"), "application/json") {
+		var body map[string]interface{}
+		if err := this.DecodeJSON(&body); err == nil {
+			for k, v := range body {
+				switch v := v.(type) {
+				case nil:
+				case string:
+					this.input[k] = v
+				case float64:
+					this.input[k] = strconv.FormatFloat(v, 'f', -1, 64)
+				default:
+					this.input[k] = fmt.Sprint(v)
+				}
+			}
+		}
+		return this.input
+	}
+
+	this.Req().ParseForm()
+	for k, vs := range this.Req().Form {
+		if len(vs) > 0 {
+			this.input[k] = vs[0]
+		}
+	}
+	return this.input
+}
+
+// ParseForm fills form fields from the request input. When partial is true
+// fields absent from input keep their current values.
+func (this *Router) ParseForm(form interface{}, partial bool) {
+	input := this.Input()
+	values := make(map[string][]string)
+
+	elm := reflect.ValueOf(form).Elem()
+	for i := 0; i < elm.NumField(); i++ {
+		fT := elm.Type().Field(i)
+		if fT.Tag.Get("form") == "-" {
+			continue
+		}
+		if v, ok := input[snakeName(fT.Name)]; ok {
+			values[fT.Name] = []string{v}
+		} else if partial {
+			values[fT.Name] = []string{utils.ToStr(elm.Field(i).Interface())}
+		}
+	}
+	utils.ParseForm(form, values)
+}
+
+// Validate runs form validation and writes errors if failed
+func (this *Router) Validate(form interface{}) bool {
+	valid := validation.Validation{}
+	if ok, _ := valid.Valid(form); !ok {
+		this.FailValidation(&valid)
+		return false
+	}
+	return true
+}
+
+// Limit returns the page size from limit param
+func (this *Router) Limit() int {
+	limit, err := strconv.Atoi(this.Req().FormValue("limit"))
+	if err != nil || limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+// Cursor returns the id decoded from cursor param, 0 means the first page
+func (this *Router) Cursor() int64 {
+	return decodeCursor(this.Req().FormValue("cursor"))
+}
+
+// IdParam returns the int64 value of a route param
+func (this *Router) IdParam(name string) int64 {
+	id, _ := strconv.ParseInt(this.Params().Get(name), 10, 64)
+	return id
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) int64 {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
+
+// snakeName converts field name NickName to nick_name
+func snakeName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,156 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/spam"
+)
+
+// PostComments serves /api/v1/posts/:id/comments
+type PostComments struct {
+	PostRouter
+}
+
+// Get lists comments of the post oldest first
+func (this *PostComments) Get() {
+	if this.Written() {
+		return
+	}
+
+	limit := this.Limit()
+	comments, err := models.FindCommentsAfterId(this.post.Id, this.Cursor(), limit+1)
+	if err != nil {
+		this.Logger.Error("api: find comments", err)
+		this.Fail(ErrInternal)
+		return
+	}
+
+	var next int64
+	if len(comments) > limit {
+		comments = comments[:limit]
+		next = comments[limit-1].Id
+	}
+
+	views := make([]*CommentView, 0, len(comments))
+	for i := range comments {
+		views = append(views, commentView(&comments[i]))
+	}
+	this.ServeList(views, next)
+}
+
+// Post replies to the post
+func (this *PostComments) Post() {
+	if this.RequireActive() {
+		return
+	}
+
+	form := post.CommentForm{}
+	this.ParseForm(&form, false)
+	if !this.Validate(&form) {
+		return
+	}
+
+	comment := models.Comment{}
+	if err := form.SaveComment(&comment, &this.User, &this.post); err == spam.ErrRejected {
+		this.Fail(ErrSpam)
+		return
+	} else if err != nil {
+		this.Logger.Error("api: save comment", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	if !comment.IsPending() {
+		post.FilterCommentMentions(&this.User, &this.post, &comment)
+		post.PostReplysCount(&this.post)
+	}
+
+	this.Serve(commentView(&comment), http.StatusCreated)
+}
+
+// CommentRouter serves /api/v1/comments/:id
+type CommentRouter struct {
+	Router
+	comment models.Comment
+}
+
+func (this *CommentRouter) Before() {
+	this.Router.Before()
+	if this.Written() {
+		return
+	}
+
+	err := models.GetById(this.IdParam(":id"), &this.comment)
+	if err != nil || !this.comment.CanViewBy(&this.User) {
+		this.Fail(ErrNotFound)
+	}
+}
+
+func (this *CommentRouter) Get() {
+	if this.Written() {
+		return
+	}
+	this.Serve(commentView(&this.comment))
+}
+
+// Put updates the comment message, the author can edit within the edit
+// window and admins at any time
+func (this *CommentRouter) Put() {
+	if this.RequireActive() {
+		return
+	}
+	if !this.comment.CanEditBy(&this.User) {
+		this.Fail(ErrForbidden)
+		return
+	}
+
+	form := post.CommentForm{}
+	form.SetFromComment(&this.comment)
+	this.ParseForm(&form, true)
+	if !this.Validate(&form) {
+		return
+	}
+
+	if err := form.UpdateComment(&this.comment, &this.User); err == spam.ErrRejected {
+		this.Fail(ErrSpam)
+		return
+	} else if err != nil {
+		this.Logger.Error("api: update comment", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Serve(commentView(&this.comment))
+}
+
+// Delete soft deletes the comment, by the author or admins
+func (this *CommentRouter) Delete() {
+	if this.RequireActive() {
+		return
+	}
+	if !this.comment.CanDeleteBy(&this.User) {
+		this.Fail(ErrForbidden)
+		return
+	}
+
+	if err := post.DeleteComment(&this.comment, &this.User); err != nil {
+		this.Logger.Error("api: delete comment", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Serve(nil)
+}
@@ -0,0 +1,183 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/spam"
+)
+
+// Posts serves /api/v1/posts
+type Posts struct {
+	Router
+}
+
+// Get lists posts newest first, filter by category or topic slug
+func (this *Posts) Get() {
+	var example models.Post
+	if slug := this.GetString("category"); len(slug) > 0 {
+		cat, err := models.GetCategoryBySlug(slug)
+		if err != nil {
+			this.Fail(ErrNotFound)
+			return
+		}
+		example.CategoryId = cat.Id
+	}
+	if slug := this.GetString("topic"); len(slug) > 0 {
+		topic, err := models.GetTopicBySlug(slug)
+		if err != nil {
+			this.Fail(ErrNotFound)
+			return
+		}
+		example.TopicId = topic.Id
+	}
+	this.listPosts(&example)
+}
+
+// Post creates a new post
+func (this *Posts) Post() {
+	if this.RequireActive() {
+		return
+	}
+
+	form := post.PostForm{Locale: this.Locale}
+	this.ParseForm(&form, false)
+	if err := models.FindTopics(&form.Topics); err != nil {
+		this.Fail(ErrInternal)
+		return
+	}
+	if topic, err := models.GetTopicById(form.Topic); err == nil {
+		form.Category = topic.CategoryId
+	}
+	if !this.Validate(&form) {
+		return
+	}
+
+	var postMd models.Post
+	if err := form.SavePost(&postMd, &this.User); err == spam.ErrRejected {
+		this.Fail(ErrSpam)
+		return
+	} else if err != nil {
+		this.Logger.Error("api: save post", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Serve(postView(&postMd), http.StatusCreated)
+}
+
+// listPosts writes a page of posts matching example
+func (this *Router) listPosts(example *models.Post) {
+	limit := this.Limit()
+	posts, err := models.FindPostsBeforeId(example, this.Cursor(), limit+1)
+	if err != nil {
+		this.Logger.Error("api: find posts", err)
+		this.Fail(ErrInternal)
+		return
+	}
+
+	var next int64
+	if len(posts) > limit {
+		posts = posts[:limit]
+		next = posts[limit-1].Id
+	}
+	this.ServeList(postViews(posts), next)
+}
+
+// PostRouter serves /api/v1/posts/:id
+type PostRouter struct {
+	Router
+	post models.Post
+}
+
+func (this *PostRouter) Before() {
+	this.Router.Before()
+	if this.Written() {
+		return
+	}
+
+	err := models.GetById(this.IdParam(":id"), &this.post)
+	if err != nil || !this.post.CanViewBy(&this.User) {
+		this.Fail(ErrNotFound)
+	}
+}
+
+func (this *PostRouter) Get() {
+	if this.Written() {
+		return
+	}
+	this.Serve(postView(&this.post))
+}
+
+// Put updates a post, the author can edit it before any reply, moderators
+// of its category always can
+func (this *PostRouter) Put() {
+	if this.RequireLogin() {
+		return
+	}
+	if !this.post.CanEditBy(&this.User) {
+		this.Fail(ErrForbidden)
+		return
+	}
+
+	form := post.PostForm{Locale: this.Locale}
+	form.SetFromPost(&this.post)
+	this.ParseForm(&form, true)
+	if err := models.FindTopics(&form.Topics); err != nil {
+		this.Fail(ErrInternal)
+		return
+	}
+	if topic, err := models.GetTopicById(form.Topic); err == nil {
+		form.Category = topic.CategoryId
+		if topic.Id != this.post.TopicId && !this.post.CanMoveTo(&this.User, topic) {
+			this.Fail(ErrForbidden)
+			return
+		}
+	}
+	if !this.Validate(&form) {
+		return
+	}
+
+	if err := form.UpdatePost(&this.post, &this.User); err == spam.ErrRejected {
+		this.Fail(ErrSpam)
+		return
+	} else if err != nil {
+		this.Logger.Error("api: update post", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Serve(postView(&this.post))
+}
+
+// Delete removes a post, only for the author or moderators
+func (this *PostRouter) Delete() {
+	if this.RequireLogin() {
+		return
+	}
+	if !this.post.CanDeleteBy(&this.User) {
+		this.Fail(ErrForbidden)
+		return
+	}
+
+	if err := post.DeletePost(&this.post, &this.User); err != nil {
+		this.Logger.Error("api: delete post", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Audit(models.AUDIT_DELETE, "post", this.post.Id, nil)
+	this.Serve(nil)
+}
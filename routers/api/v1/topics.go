@@ -0,0 +1,269 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/utils"
+)
+
+// Topics serves /api/v1/topics
+type Topics struct {
+	Router
+}
+
+// Get lists all topics, filter by category slug
+func (this *Topics) Get() {
+	var topics []models.Topic
+	var err error
+	if slug := this.GetString("category"); len(slug) > 0 {
+		cat, cerr := models.GetCategoryBySlug(slug)
+		if cerr != nil {
+			this.Fail(ErrNotFound)
+			return
+		}
+		err = models.FindTopicsByCategoryId(&topics, cat.Id)
+	} else {
+		err = models.FindTopics(&topics)
+	}
+	if err != nil {
+		this.Logger.Error("api: find topics", err)
+		this.Fail(ErrInternal)
+		return
+	}
+
+	views := make([]*TopicView, 0, len(topics))
+	for i := range topics {
+		views = append(views, topicView(&topics[i]))
+	}
+	this.Serve(views)
+}
+
+// Post creates a topic, admin only
+func (this *Topics) Post() {
+	if this.RequireAdmin() {
+		return
+	}
+
+	form := post.TopicAdminForm{Create: true}
+	this.ParseForm(&form, false)
+	if !this.Validate(&form) {
+		return
+	}
+
+	var topic models.Topic
+	form.SetToTopic(&topic)
+	if err := models.Insert(&topic); err != nil {
+		this.Logger.Error("api: insert topic", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Audit(models.AUDIT_CREATE, "topic", topic.Id, nil)
+	this.Serve(topicView(&topic), http.StatusCreated)
+}
+
+// TopicRouter serves /api/v1/topics/:slug
+type TopicRouter struct {
+	Router
+	topic *models.Topic
+}
+
+func (this *TopicRouter) Before() {
+	this.Router.Before()
+	if this.Written() {
+		return
+	}
+
+	topic, err := models.GetTopicBySlug(this.Params().Get(":slug"))
+	if err != nil {
+		this.Fail(ErrNotFound)
+		return
+	}
+	this.topic = topic
+}
+
+func (this *TopicRouter) Get() {
+	if this.Written() {
+		return
+	}
+	this.Serve(topicView(this.topic))
+}
+
+// Put updates a topic, admin only
+func (this *TopicRouter) Put() {
+	if this.RequireAdmin() {
+		return
+	}
+
+	form := post.TopicAdminForm{}
+	form.SetFromTopic(this.topic)
+	this.ParseForm(&form, true)
+	form.Id = int(this.topic.Id)
+	if !this.Validate(&form) {
+		return
+	}
+
+	changes := utils.FormChanges(this.topic, &form)
+	if len(changes) > 0 {
+		old := *this.topic
+		form.SetToTopic(this.topic)
+		if err := models.UpdateById(this.topic.Id, this.topic, models.Obj2Table(changes)...); err != nil {
+			this.Logger.Error("api: update topic", err)
+			this.Fail(ErrInternal)
+			return
+		}
+		this.Audit(models.AUDIT_UPDATE, "topic", this.topic.Id, models.AuditDiff(&old, this.topic, changes))
+	}
+	this.Serve(topicView(this.topic))
+}
+
+// Delete removes a topic without posts, admin only
+func (this *TopicRouter) Delete() {
+	if this.RequireAdmin() {
+		return
+	}
+
+	if cnt, _ := models.Count(&models.Post{TopicId: this.topic.Id}); cnt > 0 {
+		this.Fail(ErrConflict)
+		return
+	}
+	if err := models.DeleteById(this.topic.Id, this.topic); err != nil {
+		this.Logger.Error("api: delete topic", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Audit(models.AUDIT_DELETE, "topic", this.topic.Id, nil)
+	this.Serve(nil)
+}
+
+// Categories serves /api/v1/categories
+type Categories struct {
+	Router
+}
+
+func (this *Categories) Get() {
+	var cats []models.Category
+	if _, err := models.FindCategories(&cats); err != nil {
+		this.Logger.Error("api: find categories", err)
+		this.Fail(ErrInternal)
+		return
+	}
+
+	views := make([]*CategoryView, 0, len(cats))
+	for i := range cats {
+		views = append(views, categoryView(&cats[i]))
+	}
+	this.Serve(views)
+}
+
+// Post creates a category, admin only
+func (this *Categories) Post() {
+	if this.RequireAdmin() {
+		return
+	}
+
+	form := post.CategoryAdminForm{Create: true}
+	this.ParseForm(&form, false)
+	if !this.Validate(&form) {
+		return
+	}
+
+	var cat models.Category
+	form.SetToCategory(&cat)
+	if err := models.Insert(&cat); err != nil {
+		this.Logger.Error("api: insert category", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Audit(models.AUDIT_CREATE, "category", cat.Id, nil)
+	this.Serve(categoryView(&cat), http.StatusCreated)
+}
+
+// CategoryRouter serves /api/v1/categories/:slug
+type CategoryRouter struct {
+	Router
+	category *models.Category
+}
+
+func (this *CategoryRouter) Before() {
+	this.Router.Before()
+	if this.Written() {
+		return
+	}
+
+	cat, err := models.GetCategoryBySlug(this.Params().Get(":slug"))
+	if err != nil {
+		this.Fail(ErrNotFound)
+		return
+	}
+	this.category = cat
+}
+
+func (this *CategoryRouter) Get() {
+	if this.Written() {
+		return
+	}
+	this.Serve(categoryView(this.category))
+}
+
+// Put updates a category, admin only
+func (this *CategoryRouter) Put() {
+	if this.RequireAdmin() {
+		return
+	}
+
+	form := post.CategoryAdminForm{}
+	form.SetFromCategory(this.category)
+	this.ParseForm(&form, true)
+	form.Id = int(this.category.Id)
+	if !this.Validate(&form) {
+		return
+	}
+
+	changes := utils.FormChanges(this.category, &form)
+	if len(changes) > 0 {
+		old := *this.category
+		form.SetToCategory(this.category)
+		if err := models.UpdateById(this.category.Id, this.category, models.Obj2Table(changes)...); err != nil {
+			this.Logger.Error("api: update category", err)
+			this.Fail(ErrInternal)
+			return
+		}
+		this.Audit(models.AUDIT_UPDATE, "category", this.category.Id, models.AuditDiff(&old, this.category, changes))
+	}
+	this.Serve(categoryView(this.category))
+}
+
+// Delete removes a category without topics, admin only
+func (this *CategoryRouter) Delete() {
+	if this.RequireAdmin() {
+		return
+	}
+
+	if cnt, _ := models.CountTopicsByCategoryId(this.category.Id); cnt > 0 {
+		this.Fail(ErrConflict)
+		return
+	}
+	if err := models.DeleteById(this.category.Id, this.category); err != nil {
+		this.Logger.Error("api: delete category", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Audit(models.AUDIT_DELETE, "category", this.category.Id, nil)
+	this.Serve(nil)
+}
@@ -0,0 +1,130 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/auth"
+)
+
+// CurrentUser serves /api/v1/user
+type CurrentUser struct {
+	Router
+}
+
+func (this *CurrentUser) Get() {
+	if this.RequireLogin() {
+		return
+	}
+	this.Serve(userView(&this.User, true))
+}
+
+// Put updates the profile of current user
+func (this *CurrentUser) Put() {
+	if this.RequireLogin() {
+		return
+	}
+
+	form := auth.ProfileForm{Locale: this.Locale}
+	form.SetFromUser(&this.User)
+	this.ParseForm(&form, true)
+
+	// tokens can not change the email, it receives password reset links
+	if this.Token != nil && form.Email != this.User.Email {
+		this.Fail(ErrForbidden)
+		return
+	}
+
+	if !this.Validate(&form) {
+		return
+	}
+
+	if err := form.SaveUserProfile(&this.User); err != nil {
+		this.Logger.Error("api: save profile", err)
+		this.Fail(ErrInternal)
+		return
+	}
+	this.Serve(userView(&this.User, true))
+}
+
+// UserRouter serves /api/v1/users/:username
+type UserRouter struct {
+	Router
+	theUser *models.User
+}
+
+func (this *UserRouter) Before() {
+	this.Router.Before()
+	if this.Written() {
+		return
+	}
+
+	user, err := models.GetUserByName(this.Params().Get(":username"))
+	if err != nil {
+		this.Fail(ErrNotFound)
+		return
+	}
+	this.theUser = user
+}
+
+func (this *UserRouter) Get() {
+	if this.Written() {
+		return
+	}
+	this.Serve(userView(this.theUser, this.IsLogin && this.User.Id == this.theUser.Id))
+}
+
+// UserPosts serves /api/v1/users/:username/posts
+type UserPosts struct {
+	UserRouter
+}
+
+func (this *UserPosts) Get() {
+	if this.Written() {
+		return
+	}
+	this.listPosts(&models.Post{UserId: this.theUser.Id})
+}
+
+// UserFollow serves /api/v1/users/:username/follow
+type UserFollow struct {
+	UserRouter
+}
+
+// Post follows the user
+func (this *UserFollow) Post() {
+	if this.RequireLogin() {
+		return
+	}
+	if this.theUser.Id == this.User.Id {
+		this.Fail(ErrBadRequest)
+		return
+	}
+
+	if !models.IsExist(&models.Follow{UserId: this.User.Id, FollowUserId: this.theUser.Id}) {
+		auth.UserFollow(&this.User, this.theUser)
+	}
+	this.Serve(userView(this.theUser, false))
+}
+
+// Delete unfollows the user
+func (this *UserFollow) Delete() {
+	if this.RequireLogin() {
+		return
+	}
+
+	auth.UserUnFollow(&this.User, this.theUser)
+	this.Serve(userView(this.theUser, false))
+}
@@ -0,0 +1,234 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package v1
+
+import (
+	"time"
+
+	"github.com/go-tango/wego/models"
+)
+
+// views are the public JSON representation of models, they never expose
+// password, rands or private email.
+
+type UserView struct {
+	Id        int64     `json:"id"`
+	UserName  string    `json:"user_name"`
+	NickName  string    `json:"nick_name"`
+	Email     string    `json:"email,omitempty"`
+	Avatar    string    `json:"avatar"`
+	Url       string    `json:"url"`
+	Company   string    `json:"company"`
+	Location  string    `json:"location"`
+	Info      string    `json:"info"`
+	Github    string    `json:"github"`
+	Twitter   string    `json:"twitter"`
+	Google    string    `json:"google"`
+	Weibo     string    `json:"weibo"`
+	Linkedin  string    `json:"linkedin"`
+	Facebook  string    `json:"facebook"`
+	Followers int       `json:"followers"`
+	Following int       `json:"following"`
+	IsAdmin   bool      `json:"is_admin"`
+	Role      string    `json:"role"`
+	Link      string    `json:"link"`
+	Created   time.Time `json:"created"`
+}
+
+func userView(user *models.User, self bool) *UserView {
+	if user == nil {
+		return nil
+	}
+	v := &UserView{
+		Id:        user.Id,
+		UserName:  user.UserName,
+		NickName:  user.NickName,
+		Avatar:    user.AvatarLink100(),
+		Url:       user.Url,
+		Company:   user.Company,
+		Location:  user.Location,
+		Info:      user.Info,
+		Github:    user.Github,
+		Twitter:   user.Twitter,
+		Google:    user.Google,
+		Weibo:     user.Weibo,
+		Linkedin:  user.Linkedin,
+		Facebook:  user.Facebook,
+		Followers: user.Followers,
+		Following: user.Following,
+		IsAdmin:   user.IsAdmin,
+		Role:      user.RoleName(),
+		Link:      user.Link(),
+		Created:   user.Created,
+	}
+	if self || user.PublicEmail {
+		v.Email = user.Email
+	}
+	return v
+}
+
+// AuthorView is the short user info embedded in posts and comments
+type AuthorView struct {
+	Id       int64  `json:"id"`
+	UserName string `json:"user_name"`
+	NickName string `json:"nick_name"`
+	Avatar   string `json:"avatar"`
+}
+
+func authorView(user *models.User) *AuthorView {
+	if user == nil {
+		return nil
+	}
+	return &AuthorView{
+		Id:       user.Id,
+		UserName: user.UserName,
+		NickName: user.NickName,
+		Avatar:   user.AvatarLink48(),
+	}
+}
+
+type PostView struct {
+	Id          int64       `json:"id"`
+	Title       string      `json:"title"`
+	Content     string      `json:"content"`
+	ContentHtml string      `json:"content_html"`
+	Author      *AuthorView `json:"author"`
+	TopicId     int64       `json:"topic_id"`
+	CategoryId  int64       `json:"category_id"`
+	Lang        int         `json:"lang"`
+	Browsers    int         `json:"browsers"`
+	Replys      int         `json:"replys"`
+	Favorites   int         `json:"favorites"`
+	IsBest      bool        `json:"is_best"`
+	CanEdit     bool        `json:"can_edit"`
+	Pending     bool        `json:"pending"`
+	Link        string      `json:"link"`
+	Created     time.Time   `json:"created"`
+	Updated     time.Time   `json:"updated"`
+	LastReplied time.Time   `json:"last_replied"`
+}
+
+func postView(post *models.Post) *PostView {
+	return &PostView{
+		Id:          post.Id,
+		Title:       post.Title,
+		Content:     post.Content,
+		ContentHtml: post.GetContentCache(),
+		Author:      authorView(post.User()),
+		TopicId:     post.TopicId,
+		CategoryId:  post.CategoryId,
+		Lang:        post.Lang,
+		Browsers:    post.Browsers,
+		Replys:      post.Replys,
+		Favorites:   post.Favorites,
+		IsBest:      post.IsBest,
+		CanEdit:     post.CanEdit,
+		Pending:     post.IsPending(),
+		Link:        post.Link(),
+		Created:     post.Created,
+		Updated:     post.Updated,
+		LastReplied: post.LastReplied,
+	}
+}
+
+func postViews(posts []models.Post) []*PostView {
+	views := make([]*PostView, 0, len(posts))
+	for i := range posts {
+		views = append(views, postView(&posts[i]))
+	}
+	return views
+}
+
+type CommentView struct {
+	Id          int64       `json:"id"`
+	PostId      int64       `json:"post_id"`
+	ParentId    int64       `json:"parent_id"`
+	Floor       int         `json:"floor"`
+	Message     string      `json:"message"`
+	MessageHtml string      `json:"message_html"`
+	Author      *AuthorView `json:"author"`
+	Deleted     bool        `json:"deleted"`
+	Pending     bool        `json:"pending"`
+	Edited      *time.Time  `json:"edited"`
+	Created     time.Time   `json:"created"`
+}
+
+// message and author of deleted comments are hidden
+func commentView(comment *models.Comment) *CommentView {
+	v := &CommentView{
+		Id:       comment.Id,
+		PostId:   comment.PostId,
+		ParentId: comment.ParentId,
+		Floor:    comment.Floor,
+		Deleted:  comment.IsDeleted(),
+		Pending:  comment.IsPending(),
+		Created:  comment.Created,
+	}
+	if comment.IsEdited() {
+		v.Edited = &comment.Edited
+	}
+	if !v.Deleted {
+		v.Message = comment.Message
+		v.MessageHtml = comment.GetMessageCache()
+		v.Author = authorView(comment.User())
+	}
+	return v
+}
+
+type TopicView struct {
+	Id         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Slug       string    `json:"slug"`
+	Intro      string    `json:"intro"`
+	ImageLink  string    `json:"image_link"`
+	Followers  int       `json:"followers"`
+	Order      int       `json:"order"`
+	CategoryId int64     `json:"category_id"`
+	Link       string    `json:"link"`
+	Created    time.Time `json:"created"`
+}
+
+func topicView(topic *models.Topic) *TopicView {
+	return &TopicView{
+		Id:         topic.Id,
+		Name:       topic.Name,
+		Slug:       topic.Slug,
+		Intro:      topic.Intro,
+		ImageLink:  topic.ImageLink,
+		Followers:  topic.Followers,
+		Order:      topic.Order,
+		CategoryId: topic.CategoryId,
+		Link:       topic.Link(),
+		Created:    topic.Created,
+	}
+}
+
+type CategoryView struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Order int    `json:"order"`
+	Link  string `json:"link"`
+}
+
+func categoryView(cat *models.Category) *CategoryView {
+	return &CategoryView{
+		Id:    cat.Id,
+		Name:  cat.Name,
+		Slug:  cat.Slug,
+		Order: cat.Order,
+		Link:  cat.Link(),
+	}
+}
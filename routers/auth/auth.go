@@ -32,9 +32,18 @@ type Login struct {
 	base.BaseRouter
 }
 
+// setLoginOptions shows the OpenID Connect login and local password help
+func (this *Login) setLoginOptions() {
+	if setting.OIDCAuth != nil {
+		this.Data["OIDC"] = setting.OIDCAuth
+	}
+	this.Data["LocalLoginDisabled"] = setting.LocalLoginDisabled
+}
+
 // Get implemented login page.
 func (this *Login) Get() error {
 	this.Data["IsLoginPage"] = true
+	this.setLoginOptions()
 
 	loginRedirect := strings.TrimSpace(this.GetString("to"))
 	if loginRedirect == "" {
@@ -62,6 +71,7 @@ func (this *Login) Get() error {
 // Login implemented user login.
 func (this *Login) Post() {
 	this.Data["IsLoginPage"] = true
+	this.setLoginOptions()
 
 	// no need login
 	if this.CheckLoginRedirect(false) {
@@ -89,7 +99,7 @@ func (this *Login) Post() {
 		}
 		this.Data["ErrorReached"] = true
 
-	} else if auth.VerifyUser(&user, form.UserName, form.Password) {
+	} else if auth.Authenticate(&user, form.UserName, form.Password) {
 		loginRedirect := this.LoginUser(&user, form.Remember)
 
 		if this.IsAjax() {
@@ -123,6 +133,83 @@ ajaxError:
 	this.ServeJson(this.Data)
 }
 
+// TwoFactorLogin serves the second step of login for users with
+// two-factor authentication.
+type TwoFactorLogin struct {
+	base.BaseRouter
+}
+
+// pendingUser gets the user of which password is verified, or redirects
+// to login page when the first step is not done or expired
+func (this *TwoFactorLogin) pendingUser(user *models.User) bool {
+	if id, _ := auth.GetPendingLogin(&this.Session); id > 0 {
+		if err := models.GetById(id, user); err == nil {
+			return true
+		}
+	}
+	auth.ClearPendingLogin(&this.Session)
+	this.Redirect("/login", 302)
+	return false
+}
+
+// Get implemented two-factor code page.
+func (this *TwoFactorLogin) Get() error {
+	this.Data["IsLoginPage"] = true
+
+	// no need login
+	if this.CheckLoginRedirect(false) {
+		return nil
+	}
+
+	var user models.User
+	if !this.pendingUser(&user) {
+		return nil
+	}
+
+	form := auth.TwoFactorForm{}
+	this.SetFormSets(&form)
+
+	return this.Render("auth/two_factor.html", this.Data)
+}
+
+// Post implemented two-factor code check.
+func (this *TwoFactorLogin) Post() {
+	this.Data["IsLoginPage"] = true
+
+	// no need login
+	if this.CheckLoginRedirect(false) {
+		return
+	}
+
+	var user models.User
+	if !this.pendingUser(&user) {
+		return
+	}
+
+	form := auth.TwoFactorForm{}
+	if this.ValidFormSets(&form) == false {
+		this.Render("auth/two_factor.html", this.Data)
+		return
+	}
+
+	// retries are counted by user, the password is known already
+	key := "auth.two_factor." + utils.ToStr(user.Id)
+	if times, ok := utils.TimesReachedTest(key, setting.LoginMaxRetries); ok {
+		this.Data["ErrorReached"] = true
+
+	} else if auth.VerifyTwoFactor(&user, form.Code) {
+		_, remember := auth.GetPendingLogin(&this.Session)
+		auth.ClearPendingLogin(&this.Session)
+
+		this.Redirect(this.FinishLogin(&user, remember), 302)
+		return
+	} else {
+		utils.TimesReachedSet(key, times, setting.LoginFailedBlocks)
+		this.Data["Error"] = true
+	}
+	this.Render("auth/two_factor.html", this.Data)
+}
+
 type Logout struct {
 	base.BaseRouter
 }
@@ -142,6 +229,15 @@ type Register struct {
 	base.BaseRouter
 }
 
+// setRegisterMode shows the registration mode and hides the invite code
+// field of the form sets name unless the mode needs it
+func setRegisterMode(this *base.BaseRouter, name string) {
+	this.Data["RegisterClosed"] = auth.RegisterClosed()
+	if sets, ok := this.Data[name].(*utils.FormSets); ok && !auth.NeedInvite() {
+		sets.Remove("InviteCode")
+	}
+}
+
 // Get implemented Get method for RegisterRouter.
 func (this *Register) Get() error {
 	// no need login
@@ -152,7 +248,9 @@ func (this *Register) Get() error {
 	this.Data["IsRegisterPage"] = true
 
 	form := auth.RegisterForm{Locale: this.Locale}
+	form.InviteCode = this.GetString("invite")
 	this.SetFormSets(&form)
+	setRegisterMode(&this.BaseRouter, "RegisterFormSets")
 	return this.Render("auth/register.html", this.Data)
 }
 
@@ -168,13 +266,19 @@ func (this *Register) Post() error {
 	form := auth.RegisterForm{Locale: this.Locale}
 	// valid form and put errors to template context
 	if this.ValidFormSets(&form) == false {
+		setRegisterMode(&this.BaseRouter, "RegisterFormSets")
 		return this.Render("auth/register.html", this.Data)
 	}
 
 	// Create new user.
 	user := new(models.User)
 
-	if err := auth.RegisterUser(user, form.UserName, form.Email, form.Password, this.Locale); err != nil {
+	if err := auth.RegisterInvitedUser(user, form.UserName, form.Email, form.Password, form.InviteCode, this.Locale); err == models.ErrNotExist {
+		// the invite was used up since the form was checked
+		this.SetFormError(&form, "InviteCode", "auth.invite_code_invalid")
+		setRegisterMode(&this.BaseRouter, "RegisterFormSets")
+		return this.Render("auth/register.html", this.Data)
+	} else if err != nil {
 		return err
 	}
 
@@ -15,6 +15,10 @@
 package auth
 
 import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/go-tango/social-auth"
 	"github.com/lunny/log"
 	"github.com/lunny/tango"
@@ -23,6 +27,7 @@ import (
 	"github.com/go-tango/wego/middlewares"
 	"github.com/go-tango/wego/models"
 	"github.com/go-tango/wego/modules/auth"
+	"github.com/go-tango/wego/modules/oidc"
 	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/routers/base"
 	"github.com/go-tango/wego/setting"
@@ -41,6 +46,11 @@ func (p *socialAuther) IsUserLogin(ctx *tango.Context, session *session.Session)
 func (p *socialAuther) LoginUser(ctx *tango.Context, session *session.Session, uid int) (string, error) {
 	user := models.User{}
 	if err := models.GetById(int64(uid), &user); err == nil {
+		// the oauth provider replaces the password, not the second step
+		if auth.HasTwoFactor(&user) {
+			auth.SetPendingLogin(session, &user, true)
+			return "/login/two_factor", nil
+		}
 		auth.LoginUser(&user, ctx, session, true)
 	}
 	return auth.GetLoginRedirect(ctx), nil
@@ -93,6 +103,72 @@ func (this *SocialAuthRouter) canConnect(socialType *social.SocialType) bool {
 	return true
 }
 
+// oidcProfile returns the OpenID Connect user waiting to be connected,
+// nil for other providers
+func (this *SocialAuthRouter) oidcProfile(socialType social.SocialType) *oidc.Profile {
+	if socialType != oidc.SocialOIDC || setting.OIDCAuth == nil {
+		return nil
+	}
+
+	data, ok := this.Session.Get(fmt.Sprintf("social_%d_token", socialType)).([]byte)
+	if !ok {
+		return nil
+	}
+	tk := social.SocialTokenField{}
+	if err := tk.FromDB(data); err != nil || tk.Token == nil {
+		return nil
+	}
+
+	profile, err := setting.OIDCAuth.Profile(tk.Token)
+	if err != nil {
+		log.Error("oidc: profile", err)
+		return nil
+	}
+	return profile
+}
+
+// oidcAutoLink connects the account with the verified email of profile.
+// The local account must have verified the email as well, otherwise anyone
+// registering the address first would get the login of its owner. Admin
+// accounts are never linked without asking.
+func (this *SocialAuthRouter) oidcAutoLink(profile *oidc.Profile) bool {
+	if !setting.OIDCAutoLink || !profile.EmailVerified || profile.Email == "" {
+		return false
+	}
+
+	var user models.User
+	if !auth.HasUser(&user, strings.ToLower(profile.Email)) || user.IsForbid {
+		return false
+	}
+	if !user.IsActive || user.IsAdmin {
+		return false
+	}
+
+	loginRedirect, _, err := setting.SocialAuth.ConnectAndLogin(this.Context, &this.Session, oidc.SocialOIDC, int(user.Id))
+	if err != nil {
+		log.Error("oidc: auto link", err)
+		return false
+	}
+	log.Info("oidc: linked", profile.Subject, "to", user.UserName)
+	this.Redirect(loginRedirect, 302)
+	return true
+}
+
+// oidcNickName uses the nickname of profile when nobody has it
+func oidcNickName(user *models.User, profile *oidc.Profile) {
+	nick := profile.NickName
+	if nick == "" || utf8.RuneCountInString(nick) > 30 {
+		return
+	}
+	if has, err := models.ORM().Where("id <> ?", user.Id).Get(&models.User{NickName: nick}); err != nil || has {
+		return
+	}
+	user.NickName = nick
+	if err := models.UpdateById(user.Id, user, "nick_name"); err != nil {
+		log.Error("oidc: nickname", err)
+	}
+}
+
 func (this *SocialAuthRouter) Get() {
 	this.TplNames = "auth/connect.html"
 
@@ -110,7 +186,15 @@ func (this *SocialAuthRouter) Get() {
 	this.SetFormSets(&formL)
 
 	formR := auth.OAuthRegisterForm{Locale: this.Locale}
+	if profile := this.oidcProfile(socialType); profile != nil {
+		if this.oidcAutoLink(profile) {
+			return
+		}
+		formR.UserName = profile.UserName
+		formR.Email = profile.Email
+	}
 	this.SetFormSets(&formR)
+	setRegisterMode(&this.BaseRouter, "OAuthRegisterFormSets")
 
 	this.Data["Action"] = this.GetString("action")
 	this.Data["Social"] = socialType
@@ -154,7 +238,9 @@ func (this *SocialAuthRouter) Post() {
 	this.Data["Social"] = socialType
 
 	// valid form and put errors to template context
-	if this.ValidFormSets(form) == false {
+	valid := this.ValidFormSets(form)
+	setRegisterMode(&this.BaseRouter, "OAuthRegisterFormSets")
+	if !valid {
 		return
 	}
 
@@ -165,17 +251,21 @@ func (this *SocialAuthRouter) Post() {
 		key := "auth.login." + formL.UserName + utils.IP(this.Req())
 		if times, ok := utils.TimesReachedTest(key, setting.LoginMaxRetries); ok {
 			this.Data["ErrorReached"] = true
-		} else if auth.VerifyUser(&user, formL.UserName, formL.Password) {
+		} else if auth.Authenticate(&user, formL.UserName, formL.Password) {
 			goto connect
 		} else {
 			utils.TimesReachedSet(key, times, setting.LoginFailedBlocks)
 		}
 
 	default:
-		if err := auth.RegisterUser(&user, formR.UserName, formR.Email, formR.Password, this.Locale); err == nil {
+		if err := auth.RegisterInvitedUser(&user, formR.UserName, formR.Email, formR.Password, formR.InviteCode, this.Locale); err == nil {
 
 			auth.SendRegisterMail(middlewares.Renders, this.Locale, &user)
 
+			if profile := this.oidcProfile(socialType); profile != nil {
+				oidcNickName(&user, profile)
+			}
+
 			goto connect
 
 		} else {
@@ -15,9 +15,18 @@
 package auth
 
 import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+
 	"github.com/lunny/log"
 
+	"github.com/go-tango/wego/models"
 	"github.com/go-tango/wego/modules/auth"
+	"github.com/go-tango/wego/modules/export"
+	"github.com/go-tango/wego/modules/totp"
+	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/routers/base"
 	"github.com/go-tango/wego/setting"
 )
@@ -30,6 +39,7 @@ type ProfileRouter struct {
 // Profile implemented user profile settings page.
 func (this *ProfileRouter) Get() error {
 	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "profile"
 
 	// need login
 	if this.CheckLoginRedirect() {
@@ -46,10 +56,15 @@ func (this *ProfileRouter) Get() error {
 // ProfileSave implemented save user profile.
 func (this *ProfileRouter) Post() {
 	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "profile"
 	if this.CheckLoginRedirect() {
 		return
 	}
 
+	if this.RequireBrowser() {
+		return
+	}
+
 	action := this.GetString("action")
 	if this.IsAjax() {
 		switch action {
@@ -90,6 +105,7 @@ type PasswordRouter struct {
 
 func (this *PasswordRouter) Get() error {
 	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "password"
 
 	//need login
 	if this.CheckLoginRedirect() {
@@ -103,10 +119,17 @@ func (this *PasswordRouter) Get() error {
 
 func (this *PasswordRouter) Post() {
 	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "password"
 	if this.CheckLoginRedirect() {
 		return
 	}
 
+	// the source of the user keeps its password
+	if this.User.LoginSource != "" {
+		this.Redirect("/settings/change/password", 302)
+		return
+	}
+
 	pwdForm := auth.PasswordForm{User: &this.User}
 
 	this.Data["Form"] = pwdForm
@@ -131,6 +154,7 @@ type AvatarRouter struct {
 
 func (this *AvatarRouter) Get() error {
 	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "avatar"
 	//need login
 	if this.CheckLoginRedirect() {
 		return nil
@@ -144,6 +168,7 @@ func (this *AvatarRouter) Get() error {
 
 func (this *AvatarRouter) Post() {
 	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "avatar"
 	//need login
 	if this.CheckLoginRedirect() {
 		return
@@ -169,6 +194,7 @@ type AvatarUploadRouter struct {
 
 func (this *AvatarUploadRouter) Post() {
 	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "avatar"
 	//need login and active
 	if this.CheckLoginRedirect() {
 		return
@@ -191,3 +217,501 @@ func (this *AvatarUploadRouter) Post() {
 	this.FlashRedirect("/settings/avatar", 302, "AvatarUploadSuccess")
 	this.Render("settings/user_avatar.html", this.Data)
 }
+
+// TokensRouter serves personal access tokens settings.
+type TokensRouter struct {
+	base.BaseRouter
+}
+
+func (this *TokensRouter) setTokens() {
+	tokens, err := models.FindAccessTokensByUserId(this.User.Id)
+	if err != nil {
+		log.Error("TokensRouter: find tokens", err)
+	}
+	this.Data["Tokens"] = tokens
+}
+
+func (this *TokensRouter) Get() error {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "tokens"
+	//need login
+	if this.CheckLoginRedirect() {
+		return nil
+	}
+
+	form := auth.AccessTokenForm{Read: true}
+	this.SetFormSets(&form)
+	this.setTokens()
+	return this.Render("settings/tokens.html", this.Data)
+}
+
+func (this *TokensRouter) Post() {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "tokens"
+	if this.CheckLoginRedirect() {
+		return
+	}
+
+	if this.RequireBrowser() {
+		return
+	}
+
+	if this.GetString("action") == "revoke" {
+		id, _ := this.GetInt("id")
+		if id <= 0 {
+			this.Redirect("/settings/tokens", 302)
+			return
+		}
+		if err := models.DeleteAccessToken(this.User.Id, id); err != nil {
+			log.Error("TokensRouter: revoke token", err)
+		}
+		this.FlashRedirect("/settings/tokens", 302, "TokenRevoked")
+		return
+	}
+
+	form := auth.AccessTokenForm{User: &this.User}
+	if this.ValidFormSets(&form) {
+		token, err := auth.CreateAccessToken(&this.User, form.Name, form.Scopes())
+		if err == nil {
+			// the raw token only shows once
+			this.Data["NewToken"] = token
+			form = auth.AccessTokenForm{Read: true}
+			this.SetFormSets(&form)
+		} else {
+			log.Error("TokensRouter: create token", err)
+		}
+	}
+
+	this.setTokens()
+	this.Render("settings/tokens.html", this.Data)
+}
+
+// SecurityRouter serves two-factor authentication settings.
+type SecurityRouter struct {
+	base.BaseRouter
+}
+
+// secret being enrolled is kept in session until a code of it is verified
+func (this *SecurityRouter) pendingSecret() string {
+	if secret, ok := this.Session.Get("auth_2fa_secret").(string); ok && secret != "" {
+		return secret
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Error("SecurityRouter: generate secret", err)
+		return ""
+	}
+	this.Session.Set("auth_2fa_secret", secret)
+	return secret
+}
+
+func (this *SecurityRouter) setSecurity() {
+	enabled := auth.HasTwoFactor(&this.User)
+	this.Data["TwoFactorEnabled"] = enabled
+	this.Data["TwoFactorRequired"] = auth.TwoFactorRequired(&this.User)
+
+	if enabled {
+		left, err := models.CountRecoveryCodes(this.User.Id)
+		if err != nil {
+			log.Error("SecurityRouter: count recovery codes", err)
+		}
+		this.Data["RecoveryCodesLeft"] = left
+		if _, ok := this.Data["TwoFactorConfirmFormSets"]; !ok {
+			this.SetFormSets(&auth.TwoFactorConfirmForm{})
+		}
+		return
+	}
+
+	secret := this.pendingSecret()
+	this.Data["TwoFactorSecret"] = secret
+	// otpauth is not a safe url scheme of html/template
+	this.Data["ProvisioningURI"] = template.URL(totp.ProvisioningURI(setting.AppName, this.User.UserName, secret))
+	if _, ok := this.Data["TwoFactorFormSets"]; !ok {
+		this.SetFormSets(&auth.TwoFactorForm{})
+	}
+}
+
+func (this *SecurityRouter) Get() error {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "security"
+	if this.CheckLoginRedirect() {
+		return nil
+	}
+
+	this.setSecurity()
+	return this.Render("settings/security.html", this.Data)
+}
+
+func (this *SecurityRouter) Post() {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "security"
+	if this.CheckLoginRedirect() {
+		return
+	}
+
+	if this.RequireBrowser() {
+		return
+	}
+
+	enabled := auth.HasTwoFactor(&this.User)
+	switch action := this.GetString("action"); {
+	case action == "enable" && !enabled:
+		form := auth.TwoFactorForm{}
+		if !this.ValidFormSets(&form) {
+			break
+		}
+		codes, ok, err := auth.EnableTwoFactor(&this.User, this.pendingSecret(), form.Code)
+		if !ok {
+			this.SetFormError(&form, "Code", "auth.two_factor_code_wrong")
+			break
+		}
+		if err != nil {
+			log.Error("SecurityRouter: enable two factor", err)
+			break
+		}
+		this.Session.Del("auth_2fa_secret")
+		log.Info("SecurityRouter: user", this.User.Id, "enabled two-factor authentication")
+		// the raw recovery codes only show once
+		this.Data["RecoveryCodes"] = codes
+
+	case action == "recovery" && enabled:
+		form := auth.TwoFactorConfirmForm{User: &this.User}
+		if !this.ValidFormSets(&form) {
+			break
+		}
+		codes, err := auth.RegenerateRecoveryCodes(&this.User)
+		if err != nil {
+			log.Error("SecurityRouter: regenerate recovery codes", err)
+			break
+		}
+		this.Data["RecoveryCodes"] = codes
+		this.SetFormSets(&auth.TwoFactorConfirmForm{})
+
+	case action == "disable" && enabled:
+		if auth.TwoFactorRequired(&this.User) {
+			this.FlashRedirect("/settings/security", 302, "TwoFactorRequired")
+			return
+		}
+		form := auth.TwoFactorConfirmForm{User: &this.User}
+		if !this.ValidFormSets(&form) {
+			break
+		}
+		if err := auth.DisableTwoFactor(&this.User); err != nil {
+			log.Error("SecurityRouter: disable two factor", err)
+			break
+		}
+		log.Info("SecurityRouter: user", this.User.Id, "disabled two-factor authentication")
+		this.FlashRedirect("/settings/security", 302, "TwoFactorDisabled")
+		return
+
+	default:
+		this.Redirect("/settings/security", 302)
+		return
+	}
+
+	this.setSecurity()
+	this.Render("settings/security.html", this.Data)
+}
+
+// SessionsRouter lists the logins of user on browsers.
+type SessionsRouter struct {
+	base.BaseRouter
+}
+
+func (this *SessionsRouter) Get() error {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "sessions"
+	if this.CheckLoginRedirect() {
+		return nil
+	}
+
+	sessions, err := models.FindUserSessions(this.User.Id)
+	if err != nil {
+		log.Error("SessionsRouter: find sessions", err)
+	}
+	this.Data["Sessions"] = sessions
+	this.Data["CurrentSession"] = auth.CurrentSessionHash(&this.Session)
+	return this.Render("settings/sessions.html", this.Data)
+}
+
+func (this *SessionsRouter) Post() {
+	if this.CheckLoginRedirect() {
+		return
+	}
+
+	if this.RequireBrowser() {
+		return
+	}
+
+	switch this.GetString("action") {
+	case "revoke":
+		id, _ := this.GetInt("id")
+		if id <= 0 {
+			this.Redirect("/settings/sessions", 302)
+			return
+		}
+		if err := models.DeleteUserSession(this.User.Id, id); err != nil {
+			log.Error("SessionsRouter: revoke session", err)
+		}
+		this.FlashRedirect("/settings/sessions", 302, "SessionRevoked")
+	case "all":
+		if err := auth.SignOutEverywhere(&this.User, this.Context, &this.Session); err != nil {
+			log.Error("SessionsRouter: sign out everywhere", err)
+			this.FlashRedirect("/settings/sessions", 302, "SignOutFailed")
+			return
+		}
+		this.FlashRedirect("/login", 302, "SignedOutEverywhere")
+	default:
+		this.Redirect("/settings/sessions", 302)
+	}
+}
+
+// InvitesRouter lets admins and trusted members create invite codes.
+type InvitesRouter struct {
+	base.BaseRouter
+}
+
+func (this *InvitesRouter) Get() error {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "invites"
+	if this.CheckLoginRedirect() {
+		return nil
+	}
+	if !this.User.Can(models.PERM_INVITE, 0) {
+		this.NotFound()
+		return nil
+	}
+
+	invites, err := models.FindInvites(this.User.Id)
+	if err != nil {
+		log.Error("InvitesRouter: find invites", err)
+	}
+	invited, err := models.FindInvitedUsers(this.User.Id)
+	if err != nil {
+		log.Error("InvitesRouter: find invited users", err)
+	}
+	this.Data["Invites"] = invites
+	this.Data["Invited"] = invited
+	this.Data["NeedInvite"] = auth.NeedInvite()
+	this.Data["InviteMaxUses"] = setting.InviteMaxUses
+	this.Data["InviteLives"] = setting.InviteLives
+	return this.Render("settings/invites.html", this.Data)
+}
+
+func (this *InvitesRouter) Post() {
+	if this.CheckLoginRedirect() {
+		return
+	}
+	if !this.User.Can(models.PERM_INVITE, 0) {
+		this.NotFound()
+		return
+	}
+
+	if this.RequireBrowser() {
+		return
+	}
+
+	switch this.GetString("action") {
+	case "create":
+		uses, _ := this.GetInt("uses")
+		days, _ := this.GetInt("days")
+		invite, err := auth.CreateInvite(&this.User, int(uses), int(days))
+		if err != nil {
+			log.Error("InvitesRouter: create invite", err)
+			this.FlashRedirect("/settings/invites", 302, "InviteFailed")
+			return
+		}
+		this.Audit(models.AUDIT_CREATE, "invite", invite.Id, nil)
+		this.FlashRedirect("/settings/invites", 302, "InviteCreated")
+	case "delete":
+		id, _ := this.GetInt("id")
+		if err := models.DeleteInvite(this.User.Id, id); err != nil {
+			log.Error("InvitesRouter: delete invite", err)
+		} else {
+			this.Audit(models.AUDIT_DELETE, "invite", id, nil)
+		}
+		this.FlashRedirect("/settings/invites", 302, "InviteDeleted")
+	default:
+		this.Redirect("/settings/invites", 302)
+	}
+}
+
+type NoticeMailRouter struct {
+	base.BaseRouter
+}
+
+func (this *NoticeMailRouter) Get() error {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "notifications"
+	if this.CheckLoginRedirect() {
+		return nil
+	}
+
+	form := auth.NoticeMailForm{}
+	if err := form.SetFromUser(&this.User); err != nil {
+		log.Error("NoticeMailRouter: get mail modes", err)
+	}
+	this.SetFormSets(&form)
+	return this.Render("settings/notifications.html", this.Data)
+}
+
+func (this *NoticeMailRouter) Post() {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "notifications"
+	if this.CheckLoginRedirect() {
+		return
+	}
+
+	form := auth.NoticeMailForm{}
+	if this.ValidFormSets(&form) {
+		if err := form.SaveUserModes(&this.User); err == nil {
+			this.FlashRedirect("/settings/notifications", 302, "NoticeMailSaved")
+			return
+		} else {
+			log.Error("NoticeMailRouter: save mail modes", err)
+		}
+	}
+	this.Render("settings/notifications.html", this.Data)
+}
+
+// AccountRouter exports the data of user and deletes the account.
+type AccountRouter struct {
+	base.BaseRouter
+}
+
+func (this *AccountRouter) render() error {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "account"
+
+	exports, err := models.FindDataExports(this.User.Id)
+	if err != nil {
+		log.Error("AccountRouter: find exports", err)
+	}
+	this.Data["Exports"] = exports
+	this.Data["ExportLives"] = setting.ExportLives
+	return this.Render("settings/account.html", this.Data)
+}
+
+func (this *AccountRouter) Get() error {
+	if this.CheckLoginRedirect() {
+		return nil
+	}
+
+	form := auth.DeleteAccountForm{}
+	this.SetFormSets(&form)
+	return this.render()
+}
+
+func (this *AccountRouter) Post() {
+	if this.CheckLoginRedirect() {
+		return
+	}
+
+	if this.RequireBrowser() {
+		return
+	}
+
+	switch this.GetString("action") {
+	case "export":
+		if _, err := export.Start(&this.User); err == export.ErrPending {
+			this.FlashRedirect("/settings/account", 302, "ExportPending")
+		} else if err != nil {
+			log.Error("AccountRouter: start export", err)
+			this.FlashRedirect("/settings/account", 302, "ExportFailed")
+		} else {
+			this.FlashRedirect("/settings/account", 302, "ExportStarted")
+		}
+	case "mail":
+		auth.SendDeleteAccountMail(this.Locale, &this.User)
+		this.FlashRedirect("/settings/account", 302, "DeleteMailSent")
+	case "delete":
+		form := auth.DeleteAccountForm{User: &this.User}
+		if !this.ValidFormSets(&form) {
+			this.render()
+			return
+		}
+		deleteAccount(&this.BaseRouter)
+	default:
+		this.Redirect("/settings/account", 302)
+	}
+}
+
+// deleteAccount deletes the signed in user and signs it out.
+func deleteAccount(this *base.BaseRouter) {
+	user := this.User
+	if err := auth.DeleteAccount(&user); err != nil {
+		log.Error("AccountRouter: delete account", err)
+		this.FlashRedirect("/settings/account", 302, "DeleteFailed")
+		return
+	}
+	this.Audit(models.AUDIT_DELETE, "user", user.Id, nil)
+	auth.LogoutUser(this.Context, &this.Session)
+	this.FlashRedirect("/login", 302, "AccountDeleted")
+}
+
+// AccountExportRouter downloads a finished export.
+type AccountExportRouter struct {
+	base.BaseRouter
+}
+
+func (this *AccountExportRouter) Get() {
+	if this.CheckLoginRedirect() {
+		return
+	}
+
+	id, _ := utils.StrTo(this.Params().Get(":id")).Int64()
+	exp, err := models.GetDataExport(this.User.Id, id)
+	if err != nil || !exp.IsDone() {
+		this.NotFound()
+		return
+	}
+
+	f, err := os.Open(export.FilePath(exp))
+	if err != nil {
+		log.Error("AccountExportRouter: open", err)
+		this.NotFound()
+		return
+	}
+	defer f.Close()
+
+	name := fmt.Sprintf("%s-%s.zip", this.User.UserName, exp.Created.Format("20060102"))
+	this.Header().Set("Content-Type", "application/zip")
+	this.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(this.ResponseWriter, this.Req(), name, exp.Finished, f)
+}
+
+// AccountDeleteRouter deletes the account with the code of the mail.
+type AccountDeleteRouter struct {
+	base.BaseRouter
+}
+
+func (this *AccountDeleteRouter) Get() error {
+	this.Data["IsUserSettingPage"] = true
+	this.Data["SettingsNav"] = "account"
+	if this.CheckLoginRedirect() {
+		return nil
+	}
+
+	code := this.Params().Get(":code")
+	if !auth.VerifyUserDeleteCode(&this.User, code) {
+		this.FlashRedirect("/settings/account", 302, "DeleteCodeInvalid")
+		return nil
+	}
+	this.Data["Code"] = code
+	return this.Render("settings/account_delete.html", this.Data)
+}
+
+func (this *AccountDeleteRouter) Post() {
+	if this.CheckLoginRedirect() {
+		return
+	}
+	if this.RequireBrowser() {
+		return
+	}
+
+	if !auth.VerifyUserDeleteCode(&this.User, this.Params().Get(":code")) {
+		this.FlashRedirect("/settings/account", 302, "DeleteCodeInvalid")
+		return
+	}
+	deleteAccount(&this.BaseRouter)
+}
@@ -49,6 +49,8 @@ func (this *UserRouter) getUser(user *models.User) bool {
 
 	this.Data["TheUser"] = &user
 	this.Data["IsFollowed"] = IsFollowed
+	this.Data["FeedLink"] = user.FeedLink()
+	this.Data["FeedTitle"] = user.NickName
 
 	return false
 }
@@ -68,7 +70,7 @@ func (this *Home) Get() error {
 	//recent posts and comments
 	limit := 5
 
-	posts, _ := models.RecentPosts("recent", limit, 0)
+	posts, _ := models.FindPostsByUserId(user.Id, limit, 0)
 	comments, _ := models.RecentCommentsByUserId(user.Id, limit)
 
 	this.Data["TheUserPosts"] = posts
@@ -89,12 +91,12 @@ func (this *Home) Get() error {
 	//favorite posts
 	var favPostIds = make([]int64, 0)
 	var favPosts []models.Post
-	models.ORM().Limit(8).Desc("created").Iterate(new(models.FavoritePost), func(idx int, bean interface{}) error {
+	models.ORM().Limit(8).Desc("created").Iterate(&models.FavoritePost{UserId: user.Id}, func(idx int, bean interface{}) error {
 		favPostIds = append(favPostIds, bean.(*models.FavoritePost).PostId)
 		return nil
 	})
 	if len(favPostIds) > 0 {
-		models.ORM().In("id", favPostIds).Desc("created").Find(&favPosts)
+		models.ORM().In("id", favPostIds).And("status = ?", models.POST_STATUS_NORMAL).Desc("created").Find(&favPosts)
 	}
 	this.Data["TheUserFavoritePosts"] = favPosts
 	this.Data["TheUserFavoritePostsMore"] = len(favPostIds) >= 8
@@ -113,11 +115,10 @@ func (this *Posts) Get() error {
 	}
 
 	limit := 20
-	nums, _ := models.Count(&models.Post{UserId: user.Id})
+	nums, _ := models.CountPostsByUserId(user.Id)
 	pager := this.SetPaginator(limit, nums)
 
-	var posts = make([]*models.Post, 0)
-	models.Find(limit, pager.Offset(), &posts)
+	posts, _ := models.FindPostsByUserId(user.Id, limit, pager.Offset())
 
 	this.Data["TheUserPosts"] = posts
 	return this.Render("user/posts.html", this.Data)
@@ -137,8 +138,7 @@ func (this *Comments) Get() error {
 	nums, _ := models.CountCommentsByUserId(int64(user.Id))
 	pager := this.SetPaginator(limit, nums)
 
-	var comments = make([]*models.Comment, 0)
-	models.Find(limit, pager.Offset(), &comments)
+	comments, _ := models.FindCommentsByUserId(user.Id, limit, pager.Offset())
 
 	this.Data["TheUserComments"] = comments
 
@@ -19,6 +19,7 @@ package routers
 import (
 	"github.com/go-tango/wego/routers/admin"
 	"github.com/go-tango/wego/routers/api"
+	"github.com/go-tango/wego/routers/api/v1"
 	"github.com/go-tango/wego/routers/attachment"
 	"github.com/go-tango/wego/routers/auth"
 	"github.com/go-tango/wego/routers/base"
@@ -44,15 +45,25 @@ func Init(t *tango.Tango) {
 	/* Common Routers */
 	t.Get("/", new(post.Home))
 	t.Any("/topic/:slug", new(post.Topic))
+	t.Get("/topic/:slug/feed", new(post.TopicFeed))
 
+	t.Get("/feed", new(post.SiteFeed))
 	t.Get("/category/:slug", new(post.Category))
+	t.Get("/category/:slug/feed", new(post.CategoryFeed))
 	t.Get("/category/:catSlug/:sortSlug", new(post.CateNavs))
 
 	t.Any("/new", new(post.NewPost))
 	t.Any("/post/:post", new(post.SinglePost))
 	t.Any("/post/:post/edit", new(post.EditPost))
+	t.Get("/post/:post/revisions", new(post.PostRevisions))
+	t.Post("/post/:post/revisions/:rev/rollback", new(post.RollbackPost))
+	t.Any("/comment/:comment/edit", new(post.EditComment))
+	t.Post("/comment/:comment/delete", new(post.DeleteComment))
+	t.Get("/comment/:comment/history", new(post.CommentHistory))
+	t.Any("/report/:type/:id", new(post.ReportRouter))
 
 	t.Get("/notification", new(post.NoticeRouter))
+	t.Get("/notification/stream", new(post.NoticeStream))
 
 	if setting.SearchEnabled {
 		t.Get("/search", new(post.SearchRouter))
@@ -61,6 +72,7 @@ func Init(t *tango.Tango) {
 	t.Group("/user/:username", func(g *tango.Group) {
 		g.Get("/comments", new(auth.Comments))
 		g.Get("/posts", new(auth.Posts))
+		g.Get("/feed", new(post.UserFeed))
 		g.Get("/following", new(auth.Following))
 		g.Get("/followers", new(auth.Followers))
 		g.Get("/follow/topics", new(auth.FollowTopics))
@@ -69,6 +81,7 @@ func Init(t *tango.Tango) {
 	})
 
 	t.Any("/login", new(auth.Login))
+	t.Any("/login/two_factor", new(auth.TwoFactorLogin))
 	t.Get("/logout", new(auth.Logout))
 
 	t.Any("/register/connect", new(auth.SocialAuthRouter))
@@ -81,6 +94,14 @@ func Init(t *tango.Tango) {
 		g.Any("/change/password", new(auth.PasswordRouter))
 		g.Any("/avatar", new(auth.AvatarRouter))
 		g.Post("/avatar/upload", new(auth.AvatarUploadRouter))
+		g.Any("/tokens", new(auth.TokensRouter))
+		g.Any("/security", new(auth.SecurityRouter))
+		g.Any("/sessions", new(auth.SessionsRouter))
+		g.Any("/notifications", new(auth.NoticeMailRouter))
+		g.Any("/invites", new(auth.InvitesRouter))
+		g.Any("/account", new(auth.AccountRouter))
+		g.Get("/account/export/:id", new(auth.AccountExportRouter))
+		g.Any("/account/delete/:code", new(auth.AccountDeleteRouter))
 	})
 
 	t.Any("/forgot", new(auth.ForgotRouter))
@@ -97,6 +118,21 @@ func Init(t *tango.Tango) {
 		g.Post("/user", new(api.Users))
 		g.Post("/md", new(api.Markdown))
 		g.Post("/post", new(api.Post))
+
+		g.Group("/v1", func(vg *tango.Group) {
+			vg.Any("/posts", new(v1.Posts))
+			vg.Any("/posts/:id", new(v1.PostRouter))
+			vg.Any("/posts/:id/comments", new(v1.PostComments))
+			vg.Any("/comments/:id", new(v1.CommentRouter))
+			vg.Any("/topics", new(v1.Topics))
+			vg.Any("/topics/:slug", new(v1.TopicRouter))
+			vg.Any("/categories", new(v1.Categories))
+			vg.Any("/categories/:slug", new(v1.CategoryRouter))
+			vg.Any("/user", new(v1.CurrentUser))
+			vg.Get("/users/:username", new(v1.UserRouter))
+			vg.Get("/users/:username/posts", new(v1.UserPosts))
+			vg.Route([]string{"POST", "DELETE"}, "/users/:username/follow", new(v1.UserFollow))
+		})
 	})
 
 	// /* Admin Routers */
@@ -139,6 +175,7 @@ func Init(t *tango.Tango) {
 			cg.Get("", new(admin.CategoryAdminList))
 			cg.Any("/new", new(admin.CategoryAdminNew))
 			cg.Any("/:id", new(admin.CategoryAdminEdit))
+			cg.Post("/:id/moderators", new(admin.CategoryModeratorAdmin))
 			cg.Post("/:id/:action", new(admin.CategoryAdminDelete))
 		})
 
@@ -155,11 +192,34 @@ func Init(t *tango.Tango) {
 			cg.Any("/:id", new(admin.BulletinAdminEdit))
 			cg.Post("/:id/:action", new(admin.BulletinAdminDelete))
 		})
+
+		g.Group("/reports", func(cg *tango.Group) {
+			cg.Get("", new(admin.ReportAdminList))
+			cg.Post("/:id/:action", new(admin.ReportAdminAction))
+		})
+
+		g.Any("/robots", new(admin.RobotsAdmin))
+
+		g.Any("/spam", new(admin.SpamAdmin))
+
+		g.Group("/mail", func(cg *tango.Group) {
+			cg.Get("", new(admin.MailAdminList))
+			cg.Post("/:id/resend", new(admin.MailAdminResend))
+		})
+
+		g.Group("/audit", func(cg *tango.Group) {
+			cg.Get("", new(admin.AuditAdminList))
+			cg.Get("/export", new(admin.AuditAdminExport))
+		})
 	})
 
 	t.Get("/:sortSlug", new(post.Navs))
 	t.Get("/page/:slug", new(page.Show))
 
-	// /* Robot routers for "robot.txt" */
-	t.Get("/robot.txt", new(base.RobotRouter))
+	// /* Robot routers for "robots.txt" */
+	t.Get("/robots.txt", new(base.RobotRouter))
+	t.Get("/robot.txt", new(base.LegacyRobotRouter))
+
+	t.Get("/sitemap.xml", new(base.SitemapRouter))
+	t.Get("/sitemap/:name", new(base.SitemapChunkRouter))
 }
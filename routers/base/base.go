@@ -17,6 +17,7 @@ package base
 import (
 	"fmt"
 	"html/template"
+	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -33,6 +34,7 @@ import (
 
 	"github.com/go-tango/wego/models"
 	"github.com/go-tango/wego/modules/auth"
+	"github.com/go-tango/wego/modules/robots"
 	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/setting"
 )
@@ -49,10 +51,48 @@ type BaseRouter struct {
 
 	User     models.User
 	IsLogin  bool
+	Token    *models.AccessToken
 	Data     renders.T
 	TplNames string
 }
 
+// CheckXsrf overrides xsrf.Checker, requests authenticated by access token
+// are not sent by browsers and need no xsrf check.
+func (this *BaseRouter) CheckXsrf() bool {
+	return auth.BearerToken(this.Req()) == ""
+}
+
+// RequireBrowser aborts requests authenticated by access token, the account
+// settings like email, tokens and logins are only managed in browsers
+func (this *BaseRouter) RequireBrowser() bool {
+	if this.Token == nil {
+		return false
+	}
+	this.Abort(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	return true
+}
+
+// LoginByToken authenticates the request by the access token, returns the
+// http status to abort with when the token is invalid or out of scope.
+func (this *BaseRouter) LoginByToken(raw string) int {
+	token, ok := auth.LoginUserFromAccessToken(&this.User, raw)
+	if !ok {
+		return http.StatusUnauthorized
+	}
+	if !auth.CheckTokenScope(token, this.Req().Method) {
+		return http.StatusForbidden
+	}
+
+	// admin and moderation rights need the admin scope
+	if !token.HasScope(models.SCOPE_ADMIN) {
+		this.User.RestrictRole(models.ROLE_TRUSTED)
+	}
+
+	this.Token = token
+	this.IsLogin = true
+	return 0
+}
+
 // Before implemented Before method for baseRouter.
 func (this *BaseRouter) Before() {
 	this.Data = make(renders.T)
@@ -68,6 +108,12 @@ func (this *BaseRouter) Before() {
 	// page start time
 	this.Data["PageStartTime"] = time.Now()
 
+	// keep private pages out of search engines
+	if robots.NoIndex(this.Req().URL.Path) {
+		this.Header().Set("X-Robots-Tag", "noindex, nofollow")
+		this.Data["NoIndex"] = true
+	}
+
 	// check flash redirect, if match url then end, else for redirect return
 	if match, redir := this.CheckFlashRedirect(this.Ctx.Req().RequestURI); redir {
 		return
@@ -75,9 +121,15 @@ func (this *BaseRouter) Before() {
 		this.EndFlashRedirect()
 	}
 
-	switch {
+	switch raw := auth.BearerToken(this.Req()); {
+	// save user of the access token in authorization header
+	case raw != "":
+		if status := this.LoginByToken(raw); status != 0 {
+			this.Abort(status, http.StatusText(status))
+			return
+		}
 	// save logined user if exist in session
-	case auth.GetUserFromSession(&this.User, &this.Session):
+	case auth.LoginUserFromSession(&this.User, this.Context, &this.Session):
 		this.IsLogin = true
 	// save logined user if exist in remember cookie
 	case auth.LoginUserFromRememberCookie(&this.User, this.Ctx.Context, &this.Session):
@@ -132,7 +184,18 @@ func (this *BaseRouter) After() {
 	}
 }
 
+// LoginUser logs user in and returns the page to redirect, users with
+// two-factor authentication are redirected to the second step instead
 func (this *BaseRouter) LoginUser(user *models.User, remember bool) string {
+	if auth.HasTwoFactor(user) {
+		auth.SetPendingLogin(&this.Session, user, remember)
+		return "/login/two_factor"
+	}
+	return this.FinishLogin(user, remember)
+}
+
+// FinishLogin logs user in without the two-factor step
+func (this *BaseRouter) FinishLogin(user *models.User, remember bool) string {
 	ck := this.Cookies().Get("login_to")
 	var loginRedirect string
 	if ck != nil {
@@ -449,6 +512,21 @@ func (this *BaseRouter) SetPaginator(per int, nums int64) *utils.Paginator {
 	return p
 }
 
+// Audit records an administrative action of current user on an object,
+// failing to write the log doesn't stop the action
+func (this *BaseRouter) Audit(action, model string, id int64, changes []models.AuditChange) {
+	m := models.AuditLog{
+		ActorId:     this.User.Id,
+		Action:      action,
+		TargetModel: model,
+		TargetId:    id,
+		Ip:          utils.IP(this.Req()),
+	}
+	if err := models.InsertAuditLog(&m, changes); err != nil {
+		this.Logger.Error("Audit:", err)
+	}
+}
+
 func (this *BaseRouter) JsStorage(action, key string, values ...string) {
 	value := action + ":::" + key
 	if len(values) > 0 {
@@ -15,41 +15,30 @@
 package base
 
 import (
-	"bytes"
-	"html/template"
-	"strings"
+	"net/http"
 
-	"github.com/go-tango/wego/setting"
-)
+	"github.com/lunny/tango"
 
-var robotTxt string
+	"github.com/go-tango/wego/modules/robots"
+)
 
-const robotTpl = `{{$disallow := .Disallow}}{{range .Uas}}User-Agent: {{.}}
-Disallow: {{$disallow}}
+// RobotRouter serves robots.txt from rules in config or saved by admin.
+type RobotRouter struct {
+	tango.Ctx
+}
 
-{{end}}User-Agent: *
-Disallow: /
-`
+// Get writes the current rules.
+func (this *RobotRouter) Get() {
+	rules, _ := robots.Current()
+	this.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	this.Write([]byte(rules.String()))
+}
 
-// RobotRouter implemented global settings for all other routers.
-type RobotRouter struct {
+// LegacyRobotRouter redirects the old "robot.txt" to robots.txt.
+type LegacyRobotRouter struct {
+	tango.Ctx
 }
 
-// Get implemented Prepare method for RobotRouter.
-func (this *RobotRouter) Get() string {
-	if len(robotTxt) > 0 {
-		return robotTxt
-	}
-
-	// Generate "robot.txt".
-	t := template.New("robotTpl")
-	t.Parse(robotTpl)
-	uas := strings.Split(setting.Cfg.MustValue("robot", "uas"), "|")
-
-	buf := new(bytes.Buffer)
-	t.Execute(buf, map[string]interface{}{
-		"Uas": uas,
-		"Disallow": setting.Cfg.MustValue("robot", "disallow"),
-	})
-	return buf.String()
+func (this *LegacyRobotRouter) Get() {
+	this.Redirect("/robots.txt", http.StatusMovedPermanently)
 }
@@ -0,0 +1,63 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package base
+
+import (
+	"net/http"
+
+	"github.com/lunny/log"
+	"github.com/lunny/tango"
+
+	"github.com/go-tango/wego/modules/sitemap"
+)
+
+// SitemapRouter serves the sitemap index at /sitemap.xml
+type SitemapRouter struct {
+	tango.Ctx
+}
+
+func (this *SitemapRouter) Get() {
+	data, err := sitemap.Default.Index()
+	if err != nil {
+		log.Error("SitemapRouter: ", err)
+		this.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	this.writeXML(data)
+}
+
+func (this *SitemapRouter) writeXML(data []byte) {
+	this.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	this.Write(data)
+}
+
+// SitemapChunkRouter serves sitemaps listed in the index
+type SitemapChunkRouter struct {
+	SitemapRouter
+}
+
+func (this *SitemapChunkRouter) Get() {
+	data, ok, err := sitemap.Default.Chunk(this.Params().Get(":name"))
+	if err != nil {
+		log.Error("SitemapChunkRouter: ", err)
+		this.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		this.NotFound()
+		return
+	}
+	this.writeXML(data)
+}
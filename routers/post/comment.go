@@ -0,0 +1,200 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package post
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/spam"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/routers/base"
+)
+
+// Comment Router
+type CommentRouter struct {
+	base.BaseRouter
+}
+
+// loadComment loads the comment of :comment and its post, returns true
+// if response is already written
+func (this *CommentRouter) loadComment(comment *models.Comment, postMd *models.Post) bool {
+	id, _ := strconv.ParseInt(this.Params().Get(":comment"), 10, 64)
+	if id <= 0 || models.GetById(id, comment) != nil {
+		this.NotFound()
+		return true
+	}
+	if models.GetById(comment.PostId, postMd) != nil {
+		this.NotFound()
+		return true
+	}
+	if !postMd.CanViewBy(&this.User) || !comment.CanViewBy(&this.User) {
+		this.NotFound()
+		return true
+	}
+
+	this.Data["Comment"] = comment
+	this.Data["Post"] = postMd
+	return false
+}
+
+func commentLink(postMd *models.Post, comment *models.Comment) string {
+	return fmt.Sprintf("%s#reply%d", postMd.Link(), comment.Floor)
+}
+
+type EditComment struct {
+	CommentRouter
+}
+
+func (this *EditComment) Get() {
+	if this.CheckActiveRedirect() {
+		return
+	}
+
+	var comment models.Comment
+	var postMd models.Post
+	if this.loadComment(&comment, &postMd) {
+		return
+	}
+
+	if !comment.CanEditBy(&this.User) {
+		this.FlashRedirect(postMd.Path(), 302, "CanNotEditComment")
+		return
+	}
+
+	form := post.CommentForm{}
+	form.SetFromComment(&comment)
+	this.SetFormSets(&form)
+	this.Render("post/comment_edit.html", this.Data)
+}
+
+func (this *EditComment) Post() {
+	if this.CheckActiveRedirect() {
+		return
+	}
+
+	var comment models.Comment
+	var postMd models.Post
+	if this.loadComment(&comment, &postMd) {
+		return
+	}
+
+	if !comment.CanEditBy(&this.User) {
+		this.FlashRedirect(postMd.Path(), 302, "CanNotEditComment")
+		return
+	}
+
+	form := post.CommentForm{}
+	form.SetFromComment(&comment)
+	if !this.ValidFormSets(&form) {
+		this.Render("post/comment_edit.html", this.Data)
+		return
+	}
+
+	if err := form.UpdateComment(&comment, &this.User); err == spam.ErrRejected {
+		this.SetFormError(&form, "Message", "post.spam_rejected")
+		this.Render("post/comment_edit.html", this.Data)
+		return
+	} else if err != nil {
+		log.Error("UpdateComment: ", err)
+		this.Render("post/comment_edit.html", this.Data)
+		return
+	}
+	this.JsStorage("deleteKey", "post/comment/edit")
+	this.Redirect(commentLink(&postMd, &comment))
+}
+
+type DeleteComment struct {
+	CommentRouter
+}
+
+func (this *DeleteComment) Post() {
+	if this.CheckActiveRedirect() {
+		return
+	}
+
+	var comment models.Comment
+	var postMd models.Post
+	if this.loadComment(&comment, &postMd) {
+		return
+	}
+
+	if !comment.CanDeleteBy(&this.User) {
+		this.FlashRedirect(postMd.Path(), 302, "CanNotDeleteComment")
+		return
+	}
+
+	if err := post.DeleteComment(&comment, &this.User); err != nil {
+		log.Error("DeleteComment: ", err)
+		this.FlashRedirect(postMd.Path(), 302, "CanNotDeleteComment")
+		return
+	}
+	this.FlashRedirect(postMd.Path(), 302, "CommentDeleted")
+}
+
+// a change of comment with the diff to the message before it
+type CommentChange struct {
+	models.CommentHistory
+	Diffs []utils.DiffLine
+}
+
+type CommentHistory struct {
+	CommentRouter
+}
+
+// Get shows the original message and every change of a comment. History of
+// deleted comments is visible to the author and moderators only.
+func (this *CommentHistory) Get() {
+	var comment models.Comment
+	var postMd models.Post
+	if this.loadComment(&comment, &postMd) {
+		return
+	}
+
+	if comment.IsDeleted() && !(this.IsLogin && (this.User.Id == comment.UserId || comment.CanModerateBy(&this.User))) {
+		this.NotFound()
+		return
+	}
+
+	histories, err := models.FindCommentHistories(comment.Id)
+	if err != nil {
+		log.Error("FindCommentHistories: ", err)
+	}
+
+	original := comment.Message
+	if len(histories) > 0 {
+		original = histories[0].Message
+	}
+
+	changes := make([]CommentChange, 0, len(histories))
+	for i, history := range histories {
+		next := comment.Message
+		if i+1 < len(histories) {
+			next = histories[i+1].Message
+		}
+		changes = append(changes, CommentChange{
+			CommentHistory: history,
+			Diffs:          utils.DiffLines(history.Message, next),
+		})
+	}
+
+	this.Data["Original"] = original
+	this.Data["Changes"] = changes
+	this.Render("post/comment_history.html", this.Data)
+}
@@ -0,0 +1,175 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package post
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/feed"
+	"github.com/go-tango/wego/routers/base"
+	"github.com/go-tango/wego/setting"
+)
+
+// posts in a feed
+const feedSize = 20
+
+// FeedRouter serves feeds as Atom, or RSS with ?format=rss
+type FeedRouter struct {
+	base.BaseRouter
+}
+
+func (this *FeedRouter) format() string {
+	if this.GetString("format") == feed.FORMAT_RSS {
+		return feed.FORMAT_RSS
+	}
+	return feed.FORMAT_ATOM
+}
+
+// serveFeed writes posts matching example as feed, it answers 304 when
+// the client has the same version
+func (this *FeedRouter) serveFeed(title, link, self string, example *models.Post) {
+	posts, err := models.FindPostsBeforeId(example, 0, feedSize)
+	if err != nil {
+		log.Error("FeedRouter: ", err)
+		this.Abort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	format := this.format()
+	if format == feed.FORMAT_RSS {
+		self += "?format=rss"
+	}
+
+	f := &feed.Feed{
+		Title: title,
+		Link:  link,
+		Self:  self,
+	}
+	for i := range posts {
+		f.Items = append(f.Items, postFeedItem(&posts[i]))
+	}
+
+	etag := f.ETag(format)
+	modified := f.LastModified().UTC().Truncate(time.Second)
+	header := this.Header()
+	header.Set("ETag", etag)
+	if !modified.IsZero() {
+		header.Set("Last-Modified", modified.Format(http.TimeFormat))
+	}
+	if this.notModified(etag, modified) {
+		this.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Type", feed.ContentType(format))
+	if err := f.Write(this.ResponseWriter, format); err != nil {
+		log.Error("FeedRouter: ", err)
+	}
+}
+
+// notModified checks If-None-Match, or If-Modified-Since when no etag is sent
+func (this *FeedRouter) notModified(etag string, modified time.Time) bool {
+	req := this.Req()
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since, err := http.ParseTime(req.Header.Get("If-Modified-Since")); err == nil {
+		return !modified.IsZero() && !modified.After(since)
+	}
+	return false
+}
+
+func postFeedItem(post *models.Post) *feed.Item {
+	item := &feed.Item{
+		Id:        post.Link(),
+		Title:     post.Title,
+		Link:      post.Link(),
+		Content:   post.GetContentCache(),
+		Published: post.Created,
+		Updated:   post.Updated,
+	}
+	if user := post.User(); user != nil {
+		item.Author = feed.Author{Name: user.NickName, Link: user.Link()}
+		if item.Author.Name == "" {
+			item.Author.Name = user.UserName
+		}
+	}
+	if topic := post.Topic(); topic != nil {
+		item.Category = topic.Name
+	}
+	return item
+}
+
+type SiteFeed struct {
+	FeedRouter
+}
+
+func (this *SiteFeed) Get() {
+	this.serveFeed(setting.AppName, setting.AppUrl, setting.AppUrl+"feed", &models.Post{})
+}
+
+type CategoryFeed struct {
+	FeedRouter
+}
+
+func (this *CategoryFeed) Get() {
+	cat, err := models.GetCategoryBySlug(this.Params().Get(":slug"))
+	if err != nil {
+		this.NotFound()
+		return
+	}
+	this.serveFeed(cat.Name+" - "+setting.AppName, cat.Link(), cat.FeedLink(),
+		&models.Post{CategoryId: cat.Id})
+}
+
+type TopicFeed struct {
+	FeedRouter
+}
+
+func (this *TopicFeed) Get() {
+	topic, err := models.GetTopicBySlug(this.Params().Get(":slug"))
+	if err != nil {
+		this.NotFound()
+		return
+	}
+	this.serveFeed(topic.Name+" - "+setting.AppName, topic.Link(), topic.FeedLink(),
+		&models.Post{TopicId: topic.Id})
+}
+
+type UserFeed struct {
+	FeedRouter
+}
+
+func (this *UserFeed) Get() {
+	user, err := models.GetUserByName(this.Params().Get(":username"))
+	if err != nil {
+		this.NotFound()
+		return
+	}
+	this.serveFeed(user.NickName+" - "+setting.AppName, user.Link(), user.FeedLink(),
+		&models.Post{UserId: user.Id})
+}
@@ -15,12 +15,14 @@
 package post
 
 import (
+	"sort"
 	"strconv"
 
 	"github.com/lunny/log"
 
 	"github.com/go-tango/wego/models"
 	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/spam"
 	"github.com/go-tango/wego/modules/utils"
 	"github.com/go-tango/wego/routers/base"
 	"github.com/go-tango/wego/setting"
@@ -149,7 +151,7 @@ type Home struct {
 
 func (h *Home) Get() error {
 	//get posts by Created datetime desc order
-	cnt, err := models.CountByExample(&models.Post{})
+	cnt, err := models.CountVisiblePosts(&models.Post{})
 	if err != nil {
 		return err
 	}
@@ -187,7 +189,7 @@ type Navs struct {
 func (this *Navs) Get() error {
 	sortSlug := this.Params().Get(":sortSlug")
 
-	cnt, err := models.CountByExample(&models.Post{})
+	cnt, err := models.CountVisiblePosts(&models.Post{})
 	if err != nil {
 		return err
 	}
@@ -229,7 +231,7 @@ func (this *Category) Get() error {
 	}
 
 	//get posts by category slug, order by Created desc
-	cnt, err := models.CountByExample(&models.Post{CategoryId: cat.Id})
+	cnt, err := models.CountVisiblePosts(&models.Post{CategoryId: cat.Id})
 	if err != nil {
 		return err
 	}
@@ -242,6 +244,8 @@ func (this *Category) Get() error {
 
 	this.Data["Category"] = cat
 	this.Data["Posts"] = posts
+	this.Data["FeedLink"] = cat.FeedLink()
+	this.Data["FeedTitle"] = cat.Name
 
 	//top nav bar data
 	var cats []models.Category
@@ -273,7 +277,7 @@ func (this *CateNavs) Get() error {
 		return err
 	}
 
-	cnt, err := models.CountByExample(&models.Post{CategoryId: cat.Id})
+	cnt, err := models.CountVisiblePosts(&models.Post{CategoryId: cat.Id})
 	if err != nil {
 		return err
 	}
@@ -324,7 +328,7 @@ func (this *Topic) Get() error {
 	}
 
 	//get posts by topic
-	cnt, err := models.CountByExample(&models.Post{TopicId: topic.Id})
+	cnt, err := models.CountVisiblePosts(&models.Post{TopicId: topic.Id})
 	if err != nil {
 		return err
 	}
@@ -338,6 +342,8 @@ func (this *Topic) Get() error {
 	this.Data["Posts"] = posts
 	this.Data["Topic"] = &topic
 	this.Data["Category"] = &category
+	this.Data["FeedLink"] = topic.FeedLink()
+	this.Data["FeedTitle"] = topic.Name
 
 	//check whether added it into favorite list
 	var hasFavorite bool
@@ -508,6 +514,10 @@ func (this *NewPost) Post() error {
 		this.JsStorage("deleteKey", "post/new")
 		this.Redirect(post.Link())
 		return nil
+	} else if err == spam.ErrRejected {
+		this.SetFormError(&form, "Content", "post.spam_rejected")
+	} else {
+		log.Error("SavePost: ", err)
 	}
 	return this.Render("post/new.html", this.Data)
 }
@@ -532,7 +542,8 @@ func (this *PostRouter) loadPost(post *models.Post, user *models.User) bool {
 		}
 	}
 
-	if post.Id == 0 {
+	// pending and hidden posts are shown to their author and admins only
+	if post.Id == 0 || !post.CanViewBy(&this.User) {
 		this.NotFound()
 		return true
 	}
@@ -544,14 +555,28 @@ func (this *PostRouter) loadPost(post *models.Post, user *models.User) bool {
 
 func (this *PostRouter) loadComments(post *models.Post, comments *[]*models.Comment) {
 	err := models.GetCommentsByPostId(comments, post.Id)
+	if err == nil && this.IsLogin {
+		// authors see their comments waiting for review
+		var pending []*models.Comment
+		if pending, err = models.FindPendingComments(post.Id, this.User.Id); err == nil && len(pending) > 0 {
+			*comments = append(*comments, pending...)
+			sort.Sort(commentsById(*comments))
+		}
+	}
 	if err == nil {
-		this.Data["Comments"] = *comments
+		this.Data["Comments"] = models.CommentThreads(*comments)
 		this.Data["CommentsNum"] = len(*comments)
 	} else {
 		log.Error("loadComments error:", err)
 	}
 }
 
+type commentsById []*models.Comment
+
+func (c commentsById) Len() int           { return len(c) }
+func (c commentsById) Less(i, j int) bool { return c[i].Id < c[j].Id }
+func (c commentsById) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
 type SinglePost struct {
 	PostRouter
 }
@@ -576,6 +601,15 @@ func (this *SinglePost) Get() error {
 	this.Data["IsPostFav"] = isPostFav
 
 	form := post.CommentForm{}
+	// quote a comment to reply
+	if id, err := this.GetInt("quote"); err == nil && this.IsLogin {
+		for _, c := range comments {
+			if c.Id == id {
+				form.SetQuote(c)
+				break
+			}
+		}
+	}
 	this.SetFormSets(&form)
 	//increment PageViewCount
 
@@ -610,12 +644,21 @@ func (this *SinglePost) Post() {
 
 	comment := models.Comment{}
 	if err := form.SaveComment(&comment, &this.User, &postMd); err == nil {
-		post.FilterCommentMentions(&this.User, &postMd, &comment)
+		// held comments notify nobody until approved
+		if !comment.IsPending() {
+			post.FilterCommentMentions(&this.User, &postMd, &comment)
+		}
 		this.JsStorage("deleteKey", "post/comment")
 		this.Redirect(postMd.Link(), 302)
 		redir = true
 
-		post.PostReplysCount(&postMd)
+		if !comment.IsPending() {
+			post.PostReplysCount(&postMd)
+		}
+	} else if err == spam.ErrRejected {
+		this.SetFormError(&form, "Message", "post.spam_rejected")
+	} else {
+		log.Error("SaveComment: ", err)
 	}
 	this.Render("post/post.html", this.Data)
 }
@@ -630,12 +673,12 @@ func (this *EditPost) Get() {
 	}
 
 	var postMd models.Post
-	if this.loadPost(&postMd, &this.User) {
+	if this.loadPost(&postMd, nil) {
 		return
 	}
 
-	if !postMd.CanEdit {
-		this.Redirect(postMd.Link())
+	if !postMd.CanEditBy(&this.User) {
+		this.FlashRedirect(postMd.Path(), 302, "CanNotEditPost")
 		return
 	}
 	form := post.PostForm{}
@@ -651,25 +694,40 @@ func (this *PostRouter) Post() {
 	}
 
 	var postMd models.Post
-	if this.loadPost(&postMd, &this.User) {
+	if this.loadPost(&postMd, nil) {
 		return
 	}
 
-	if !postMd.CanEdit {
+	if !postMd.CanEditBy(&this.User) {
 		this.FlashRedirect(postMd.Path(), 302, "CanNotEditPost")
+		return
 	}
 
 	form := post.PostForm{}
 	form.SetFromPost(&postMd)
 	models.FindTopics(&form.Topics)
 	if !this.ValidFormSets(&form) {
+		this.Render("post/edit.html", this.Data)
 		return
 	}
 
+	if form.Topic != postMd.TopicId {
+		topic, err := models.GetTopicById(form.Topic)
+		if err != nil || !postMd.CanMoveTo(&this.User, topic) {
+			this.SetFormError(&form, "Topic", "post.post_move_denied")
+			this.Render("post/edit.html", this.Data)
+			return
+		}
+	}
+
 	if err := form.UpdatePost(&postMd, &this.User); err == nil {
 		this.JsStorage("deleteKey", "post/edit")
 		this.Redirect(postMd.Link())
 		return
+	} else if err == spam.ErrRejected {
+		this.SetFormError(&form, "Content", "post.spam_rejected")
+	} else {
+		log.Error("UpdatePost: ", err)
 	}
 	this.Render("post/edit.html", this.Data)
 }
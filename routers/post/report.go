@@ -0,0 +1,110 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package post
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/routers/base"
+	"github.com/go-tango/wego/setting"
+)
+
+// ReportRouter serves /report/:type/:id, type is post, comment or user
+type ReportRouter struct {
+	base.BaseRouter
+	report models.Report
+}
+
+// loadTarget reads the reported object from url, returns true if response
+// is already written
+func (this *ReportRouter) loadTarget() bool {
+	this.report.TargetType = post.ReportTarget(this.Params().Get(":type"))
+	this.report.TargetId, _ = strconv.ParseInt(this.Params().Get(":id"), 10, 64)
+
+	link := this.report.TargetLink()
+	switch {
+	case this.report.TargetType == 0 || link == "":
+		this.NotFound()
+		return true
+	case this.report.IsPost():
+		if p := this.report.Post(); p == nil || !p.CanViewBy(&this.User) {
+			this.NotFound()
+			return true
+		}
+	case this.report.IsComment():
+		if c := this.report.Comment(); c == nil || !c.CanViewBy(&this.User) {
+			this.NotFound()
+			return true
+		}
+	}
+
+	this.Data["Report"] = &this.report
+	this.Data["ReportLink"] = link
+	this.Data["ReportUrl"] = fmt.Sprintf("%sreport/%s/%d", setting.AppUrl, this.Params().Get(":type"), this.report.TargetId)
+	return false
+}
+
+// back returns the path of the reported object for flash redirects
+func (this *ReportRouter) back() string {
+	return "/" + strings.TrimPrefix(this.report.TargetLink(), setting.AppUrl)
+}
+
+func (this *ReportRouter) Get() {
+	if this.CheckActiveRedirect() {
+		return
+	}
+	if this.loadTarget() {
+		return
+	}
+
+	form := post.ReportForm{}
+	this.SetFormSets(&form)
+	this.Render("post/report.html", this.Data)
+}
+
+func (this *ReportRouter) Post() {
+	if this.CheckActiveRedirect() {
+		return
+	}
+	if this.loadTarget() {
+		return
+	}
+
+	form := post.ReportForm{}
+	if !this.ValidFormSets(&form) {
+		this.Render("post/report.html", this.Data)
+		return
+	}
+
+	switch err := form.SaveReport(&this.report, &this.User); err {
+	case nil:
+		this.FlashRedirect(this.back(), 302, "ReportSaved")
+	case post.ErrReportSelf:
+		this.Data["ReportSelf"] = true
+		this.Render("post/report.html", this.Data)
+	case post.ErrReportDuplicate:
+		this.Data["ReportDuplicate"] = true
+		this.Render("post/report.html", this.Data)
+	default:
+		log.Error("SaveReport: ", err)
+		this.NotFound()
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package post
+
+import (
+	"strconv"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/post"
+	"github.com/go-tango/wego/modules/utils"
+)
+
+// a revision of post with the diff to the revision before it
+type PostRevisionDiff struct {
+	models.PostRevision
+	OldTitle string
+	Rows     []utils.DiffRow
+	IsFirst  bool
+}
+
+func (r *PostRevisionDiff) TitleChanged() bool {
+	return !r.IsFirst && r.OldTitle != r.Title
+}
+
+type PostRevisions struct {
+	PostRouter
+}
+
+// Get lists revisions of the post, newest first
+func (this *PostRevisions) Get() {
+	var postMd models.Post
+	if this.loadPost(&postMd, nil) {
+		return
+	}
+
+	revs, err := models.FindPostRevisions(postMd.Id)
+	if err != nil {
+		log.Error("FindPostRevisions: ", err)
+	}
+
+	diffs := make([]PostRevisionDiff, len(revs))
+	for i, rev := range revs {
+		diff := PostRevisionDiff{PostRevision: rev, IsFirst: i == 0}
+		if i > 0 {
+			diff.OldTitle = revs[i-1].Title
+			diff.Rows = utils.DiffRows(utils.DiffLines(revs[i-1].Content, rev.Content))
+		}
+		diffs[len(revs)-1-i] = diff
+	}
+
+	this.Data["Revisions"] = diffs
+	this.Data["CanRollback"] = this.User.Can(models.PERM_EDIT_POST, postMd.CategoryId)
+	this.Render("post/revisions.html", this.Data)
+}
+
+type RollbackPost struct {
+	PostRouter
+}
+
+// Post restores the post to a revision, for users who may edit posts of
+// others in the category
+func (this *RollbackPost) Post() {
+	if this.CheckLoginRedirect() {
+		return
+	}
+
+	var postMd models.Post
+	if this.loadPost(&postMd, nil) {
+		return
+	}
+	if !this.User.Can(models.PERM_EDIT_POST, postMd.CategoryId) {
+		this.NotFound()
+		return
+	}
+
+	revId, _ := strconv.ParseInt(this.Params().Get(":rev"), 10, 64)
+	rev, err := models.GetPostRevision(postMd.Id, revId)
+	if err != nil {
+		this.NotFound()
+		return
+	}
+
+	old := postMd
+	if err := post.RollbackPost(&postMd, rev, &this.User); err != nil {
+		log.Error("RollbackPost: ", err)
+		this.FlashRedirect(postMd.Path()+"/revisions", 302, "RollbackFailed")
+		return
+	}
+	changes := models.AuditDiff(&old, &postMd, []string{"Title"})
+	changes = append(changes, models.AuditChange{Field: "Revision", After: utils.ToStr(rev.Id)})
+	this.Audit("rollback", "post", postMd.Id, changes)
+	this.FlashRedirect(postMd.Path()+"/revisions", 302, "RollbackSuccess")
+}
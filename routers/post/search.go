@@ -15,16 +15,80 @@
 package post
 
 import (
-	"fmt"
-	"github.com/go-tango/wego/routers/base"
+	"strings"
+	"time"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/search"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/setting"
 )
 
 type SearchRouter struct {
-	base.BaseRouter
+	PostListRouter
 }
 
-func (this *SearchRouter) Get() {
-	site := "site:golanghome.com"
-	q := this.GetString("q")
-	this.Redirect(fmt.Sprintf("http://cn.bing.com/search?q=%s %s", site, q), 302)
+func (this *SearchRouter) Get() error {
+	q := strings.TrimSpace(this.GetString("q"))
+	query := search.Query{Text: q}
+
+	if slug := this.GetString("category"); len(slug) > 0 {
+		if cat, err := models.GetCategoryBySlug(slug); err == nil {
+			query.CategoryId = cat.Id
+		}
+	}
+	if slug := this.GetString("topic"); len(slug) > 0 {
+		if topic, err := models.GetTopicBySlug(slug); err == nil {
+			query.TopicId = topic.Id
+		}
+	}
+	if name := this.GetString("author"); len(name) > 0 {
+		if user, err := models.GetUserByName(name); err == nil {
+			query.UserId = user.Id
+		} else {
+			// unknown author matches nothing
+			query.UserId = -1
+		}
+	}
+	if t, err := utils.DateParse(this.GetString("from"), "Y-m-d"); err == nil {
+		query.From = t
+	}
+	if t, err := utils.DateParse(this.GetString("to"), "Y-m-d"); err == nil {
+		// include the whole end day
+		query.To = t.Add(24 * time.Hour)
+	}
+
+	pers := setting.SearchResultsPerPage
+	start := time.Now()
+	count, results := search.Search(&query, 0, 0)
+	pager := this.SetPaginator(pers, int64(count))
+	// pages past the end show nothing
+	if offset := pager.Offset(); offset < count {
+		end := offset + pers
+		if end > count {
+			end = count
+		}
+		results = results[offset:end]
+	} else {
+		results = nil
+	}
+
+	this.Data["Results"] = results
+	this.Data["SearchCount"] = count
+	this.Data["SearchTime"] = time.Since(start).Seconds()
+	this.Data["Q"] = q
+	this.Data["QCategory"] = this.GetString("category")
+	this.Data["QTopic"] = this.GetString("topic")
+	this.Data["QAuthor"] = this.GetString("author")
+	this.Data["QFrom"] = this.GetString("from")
+	this.Data["QTo"] = this.GetString("to")
+
+	this.Data["CategorySlug"] = "home"
+	var cats []models.Category
+	this.setCategories(&cats)
+	var topics []models.Topic
+	this.setTopics(&topics)
+	this.setSidebarBuilletinInfo()
+
+	return this.Render("search/result.html", this.Data)
 }
@@ -0,0 +1,152 @@
+// Copyright 2014 wego authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package post
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/lunny/log"
+
+	"github.com/go-tango/wego/models"
+	"github.com/go-tango/wego/modules/auth"
+	"github.com/go-tango/wego/modules/notice"
+	"github.com/go-tango/wego/modules/utils"
+	"github.com/go-tango/wego/routers/base"
+)
+
+const (
+	// comment line sent to keep the connection through proxies
+	streamHeartbeat = 25 * time.Second
+	// milliseconds browsers wait before reconnecting
+	streamRetry = 5000
+	// max notifications replayed on reconnect
+	streamReplayLimit = 50
+)
+
+// NoticeStream pushes notifications and unread counts to logged-in
+// browsers with Server-Sent Events
+type NoticeStream struct {
+	base.BaseRouter
+	flusher http.Flusher
+}
+
+func (this *NoticeStream) Get() {
+	if !this.IsLogin {
+		this.Abort(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	flusher, ok := this.ResponseWriter.(http.Flusher)
+	if !ok {
+		this.Abort(http.StatusNotImplemented, "streaming unsupported")
+		return
+	}
+	this.flusher = flusher
+
+	// subscribe before replay so that no notification is missed
+	sub := notice.DefaultHub.Subscribe(this.User.Id)
+	defer notice.DefaultHub.Unsubscribe(sub)
+
+	header := this.Header()
+	header.Set("Content-Type", "text/event-stream; charset=utf-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	this.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(this.ResponseWriter, "retry: %d\n\n", streamRetry); err != nil {
+		return
+	}
+
+	// replay notifications missed since last event
+	lastId := this.lastEventId()
+	if lastId > 0 {
+		notifications, err := models.FindNotificationsAfterId(this.User.Id, lastId, streamReplayLimit)
+		if err != nil {
+			log.Error("NoticeStream: ", err)
+		}
+		for _, n := range notifications {
+			if event := notice.NotificationEvent(n); event != nil {
+				if this.send(event) != nil {
+					return
+				}
+			}
+			lastId = n.Id
+		}
+	}
+	if this.send(notice.UnreadEvent(this.User.Id)) != nil {
+		return
+	}
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	done := this.Req().Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case event := <-sub.C:
+			// skip notifications already replayed
+			if event.Id != "" {
+				id, _ := utils.StrTo(event.Id).Int64()
+				if id <= lastId {
+					continue
+				}
+				lastId = id
+			}
+			if this.send(event) != nil {
+				return
+			}
+		case <-ticker.C:
+			// signed out on the sessions page or everywhere
+			if auth.LoginRevoked(&this.User, this.Token, &this.Session) {
+				return
+			}
+			if _, err := this.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+			this.flusher.Flush()
+		}
+	}
+}
+
+// lastEventId is sent by browsers on reconnect, the query is for clients
+// which can not set headers
+func (this *NoticeStream) lastEventId() int64 {
+	id := this.Req().Header.Get("Last-Event-ID")
+	if id == "" {
+		id = this.GetString("last_event_id")
+	}
+	lastId, _ := utils.StrTo(id).Int64()
+	return lastId
+}
+
+func (this *NoticeStream) send(event *notice.Event) error {
+	var buf bytes.Buffer
+	if event.Id != "" {
+		fmt.Fprintf(&buf, "id: %s\n", event.Id)
+	}
+	fmt.Fprintf(&buf, "event: %s\n", event.Name)
+	fmt.Fprintf(&buf, "data: %s\n\n", event.Data)
+	if _, err := this.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	this.flusher.Flush()
+	return nil
+}
@@ -36,6 +36,9 @@ import (
 	"github.com/tango-contrib/cache"
 	"github.com/tango-contrib/captcha"
 
+	"github.com/go-tango/wego/modules/ldap"
+	"github.com/go-tango/wego/modules/oidc"
+
 	. "github.com/qiniu/api/conf"
 )
 
@@ -70,12 +73,31 @@ var (
 	LoginRememberDays int
 	LoginMaxRetries   int
 	LoginFailedBlocks int
+	TwoFactorAdmin    bool
+
+	// only login sources like ldap check passwords
+	LocalLoginDisabled bool
+
+	// user name of the owner of content of deleted users
+	GhostUserName string
+
+	// one of REGISTER_*, the email domains allowed in domain mode, the
+	// uses and life time in days of invite codes created by members
+	RegisterMode    string
+	RegisterDomains []string
+	InviteMaxUses   int
+	InviteLives     int
+
+	// data export zip files and their life time in hours
+	ExportPath  string
+	ExportLives int
 
 	CookieRememberName string
 	CookieUserName     string
 
 	// search
-	SearchEnabled bool
+	SearchEnabled        bool
+	SearchResultsPerPage int
 
 	// mail setting
 	MailUser     string
@@ -83,6 +105,21 @@ var (
 	MailHost     string
 	MailAuthUser string
 	MailAuthPass string
+	MailTLS      string
+
+	MailTLSSkipVerify bool
+
+	// notification digest mail
+	DigestHour    int
+	DigestWeekday int
+
+	// paths not to be indexed by robots
+	RobotNoIndex []string
+
+	// mail queue
+	MailQueueWorkers     int
+	MailQueueMaxAttempts int
+	MailQueueRetryBase   int
 )
 
 var (
@@ -108,7 +145,40 @@ var (
 )
 
 var (
-	PostCountPerPage int
+	PostCountPerPage   int
+	CommentEditMinutes int
+)
+
+var (
+	ModerationPendingReports int
+)
+
+var (
+	SpamEnabled          bool
+	SpamRateMinutes      int
+	SpamPostRate         int
+	SpamCommentRate      int
+	SpamNewUserDays      int
+	SpamMaxLinks         int
+	SpamDuplicateMinutes int
+)
+
+var (
+	RateLimitEnabled bool
+)
+
+var (
+	PasswordAlgorithm     string
+	PasswordBcryptCost    int
+	PasswordScryptN       int
+	PasswordScryptR       int
+	PasswordScryptP       int
+	PasswordArgon2Time    int
+	PasswordArgon2Memory  int
+	PasswordArgon2Threads int
+	PasswordMinLength     int
+	PasswordMaxLength     int
+	PasswordBreachedFile  string
 )
 
 var (
@@ -120,6 +190,14 @@ const (
 	LangZhCN
 )
 
+// registration modes
+const (
+	REGISTER_OPEN   = "open"
+	REGISTER_INVITE = "invite"
+	REGISTER_CLOSED = "closed"
+	REGISTER_DOMAIN = "domain"
+)
+
 const (
 	BULLETIN_FRIEND_LINK = iota
 	BULLETIN_NEW_COMER
@@ -136,6 +214,9 @@ const (
 const (
 	NOTICE_TYPE_COMMENT   = 1
 	NOTICE_TYPE_FAVOURITE = 2
+	NOTICE_TYPE_REPLY     = 3
+	NOTICE_TYPE_FOLLOW    = 4
+	NOTICE_TYPE_MENTION   = 5
 
 	NOTICE_UNREAD = 1
 	NOTICE_READ   = 2
@@ -146,6 +227,13 @@ var (
 	GithubAuth *apps.Github
 	GoogleAuth *apps.Google
 	SocialAuth *social.SocialAuth
+
+	// OpenID Connect provider, nil when not configured
+	OIDCAuth     *oidc.Provider
+	OIDCAutoLink bool
+
+	// LDAP directory, nil when not enabled
+	LDAP *ldap.Config
 )
 
 var (
@@ -298,6 +386,45 @@ func LoadConfig() *goconfig.ConfigFile {
 		log.Error(err)
 	}
 
+	clientId = Cfg.MustValue("oidc", "client_id")
+	discoveryURL := Cfg.MustValue("oidc", "discovery_url")
+	if clientId != "" && discoveryURL != "" {
+		OIDCAuth = oidc.New(
+			Cfg.MustValue("oidc", "name", "OpenID Connect"),
+			Cfg.MustValue("oidc", "path", "oidc"),
+			discoveryURL, clientId,
+			Cfg.MustValue("oidc", "client_secret"),
+			Cfg.MustValue("oidc", "scopes", "openid profile email"),
+			oidc.Claims{
+				UserName: Cfg.MustValue("oidc", "username_claim", "preferred_username"),
+				NickName: Cfg.MustValue("oidc", "nickname_claim", "name"),
+				Email:    Cfg.MustValue("oidc", "email_claim", "email"),
+			})
+		OIDCAutoLink = Cfg.MustBool("oidc", "auto_link")
+		if err = social.RegisterProvider(OIDCAuth); err != nil {
+			log.Error(err)
+		}
+	}
+
+	if Cfg.MustBool("ldap", "enabled") {
+		LDAP = &ldap.Config{
+			Host:         Cfg.MustValue("ldap", "host"),
+			Port:         Cfg.MustInt("ldap", "port"),
+			Security:     Cfg.MustValue("ldap", "security", ldap.SECURITY_NONE),
+			SkipVerify:   Cfg.MustBool("ldap", "skip_verify"),
+			BindDN:       Cfg.MustValue("ldap", "bind_dn"),
+			BindPassword: Cfg.MustValue("ldap", "bind_password"),
+			BaseDN:       Cfg.MustValue("ldap", "base_dn"),
+			Filter:       Cfg.MustValue("ldap", "filter", "(&(objectClass=person)(uid=%s))"),
+			UserNameAttr: Cfg.MustValue("ldap", "username_attr", "uid"),
+			EmailAttr:    Cfg.MustValue("ldap", "email_attr", "mail"),
+			NickNameAttr: Cfg.MustValue("ldap", "nickname_attr", "cn"),
+			AdminGroup:   Cfg.MustValue("ldap", "admin_group"),
+			GroupAttr:    Cfg.MustValue("ldap", "group_attr", "memberOf"),
+			Timeout:      time.Duration(Cfg.MustInt("ldap", "timeout", 10)) * time.Second,
+		}
+	}
+
 	settingLocales()
 	settingCompress()
 
@@ -335,6 +462,28 @@ func reloadConfig() {
 	LoginRememberDays = Cfg.MustInt("app", "login_remember_days", 7)
 	LoginMaxRetries = Cfg.MustInt("app", "login_max_retries", 5)
 	LoginFailedBlocks = Cfg.MustInt("app", "login_failed_blocks", 10)
+	TwoFactorAdmin = Cfg.MustBool("app", "admin_two_factor")
+	LocalLoginDisabled = Cfg.MustBool("app", "disable_local_login")
+	GhostUserName = Cfg.MustValue("app", "ghost_user", "ghost")
+
+	RegisterMode = strings.ToLower(Cfg.MustValue("register", "mode", REGISTER_OPEN))
+	switch RegisterMode {
+	case REGISTER_OPEN, REGISTER_INVITE, REGISTER_CLOSED, REGISTER_DOMAIN:
+	default:
+		log.Errorf("unknown register mode %s, registration is closed", RegisterMode)
+		RegisterMode = REGISTER_CLOSED
+	}
+	RegisterDomains = nil
+	for _, domain := range strings.Split(Cfg.MustValue("register", "domains"), ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			RegisterDomains = append(RegisterDomains, domain)
+		}
+	}
+	InviteMaxUses = Cfg.MustInt("register", "invite_max_uses", 5)
+	InviteLives = Cfg.MustInt("register", "invite_lives", 14)
+
+	ExportPath = Cfg.MustValue("export", "path", "data/exports")
+	ExportLives = Cfg.MustInt("export", "lives", 168)
 
 	CookieRememberName = Cfg.MustValue("app", "cookie_remember_name", "wetalk_magic")
 	CookieUserName = Cfg.MustValue("app", "cookie_user_name", "wetalk_powerful")
@@ -368,9 +517,29 @@ func reloadConfig() {
 	MailHost = Cfg.MustValue("mailer", "mail_host", "127.0.0.1:25")
 	MailAuthUser = Cfg.MustValue("mailer", "mail_user", "example@example.com")
 	MailAuthPass = Cfg.MustValue("mailer", "mail_pass", "******")
+	MailTLS = Cfg.MustValue("mailer", "mail_tls", "auto")
+	MailTLSSkipVerify = Cfg.MustBool("mailer", "mail_tls_skip_verify", false)
+
+	DigestHour = Cfg.MustInt("mailer", "digest_hour", 8)
+	DigestWeekday = Cfg.MustInt("mailer", "digest_weekday", 1)
+
+	MailQueueWorkers = Cfg.MustInt("mailer", "queue_workers", 2)
+	if MailQueueWorkers < 1 {
+		MailQueueWorkers = 1
+	}
+	MailQueueMaxAttempts = Cfg.MustInt("mailer", "queue_max_attempts", 8)
+	MailQueueRetryBase = Cfg.MustInt("mailer", "queue_retry_base", 60)
+
+	RobotNoIndex = RobotNoIndex[:0]
+	for _, path := range strings.Split(Cfg.MustValue("robot", "noindex", "/settings|/admin"), "|") {
+		if path = strings.TrimSpace(path); path != "" {
+			RobotNoIndex = append(RobotNoIndex, path)
+		}
+	}
 
 	// search setting
 	SearchEnabled = Cfg.MustBool("search", "enabled")
+	SearchResultsPerPage = Cfg.MustInt("search", "results_per_page", 20)
 
 	// OAuth
 	GithubClientId = Cfg.MustValue("oauth", "github_client_id", "your_client_id")
@@ -393,6 +562,35 @@ func reloadConfig() {
 
 	//post
 	PostCountPerPage = Cfg.MustInt("post", "post_count_per_page", 20)
+	CommentEditMinutes = Cfg.MustInt("post", "comment_edit_minutes", 30)
+
+	//moderation
+	ModerationPendingReports = Cfg.MustInt("moderation", "pending_reports", 3)
+
+	//spam
+	SpamEnabled = Cfg.MustBool("spam", "enabled", true)
+	SpamRateMinutes = Cfg.MustInt("spam", "rate_minutes", 10)
+	SpamPostRate = Cfg.MustInt("spam", "post_rate", 3)
+	SpamCommentRate = Cfg.MustInt("spam", "comment_rate", 10)
+	SpamNewUserDays = Cfg.MustInt("spam", "new_user_days", 3)
+	SpamMaxLinks = Cfg.MustInt("spam", "max_links", 2)
+	SpamDuplicateMinutes = Cfg.MustInt("spam", "duplicate_minutes", 60)
+
+	//rate limit
+	RateLimitEnabled = Cfg.MustBool("ratelimit", "enabled", true)
+
+	//password
+	PasswordAlgorithm = Cfg.MustValue("password", "algorithm", "argon2id")
+	PasswordBcryptCost = Cfg.MustInt("password", "bcrypt_cost", 10)
+	PasswordScryptN = Cfg.MustInt("password", "scrypt_n", 32768)
+	PasswordScryptR = Cfg.MustInt("password", "scrypt_r", 8)
+	PasswordScryptP = Cfg.MustInt("password", "scrypt_p", 1)
+	PasswordArgon2Time = Cfg.MustInt("password", "argon2_time", 3)
+	PasswordArgon2Memory = Cfg.MustInt("password", "argon2_memory", 65536)
+	PasswordArgon2Threads = Cfg.MustInt("password", "argon2_threads", 2)
+	PasswordMinLength = Cfg.MustInt("password", "min_length", 8)
+	PasswordMaxLength = Cfg.MustInt("password", "max_length", 72)
+	PasswordBreachedFile = Cfg.MustValue("password", "breached_file")
 }
 
 func settingLocales() {
@@ -230,6 +230,17 @@
 		$('[rel=select2]').select2();
 
 		$('.markdown').mdFilter();
+
+		// live unread notification count
+		var $unread = $('#unread-notice');
+		if($unread.length && window.EventSource){
+			var stream = new EventSource($unread.data('stream'));
+			stream.addEventListener('unread', function(e){
+				var count = JSON.parse(e.data).count;
+				$unread.find('.badge').text(count);
+				$unread.toggle(count > 0);
+			});
+		}
 	});
 
 
@@ -243,10 +254,30 @@
 				floor = $e.data('floor'),
 				sel = api.getSel(),
 				v = '#'+floor+' @'+user+' ';
+				$('#CommentForm-Parent').val($e.data('id'));
 				$('#post-reply').ScrollTo();
 				api.insertText(v, sel.start + v.length);
 			});
 
+			// collapse or expand replies of a comment
+			$(document).on('click', '[rel=comment-toggle]', function(){
+				var $btn = $(this),
+				$children = $btn.parents('.comment:first').children('.comment-children');
+				if(!$btn.data('expanded-text')){
+					$btn.data('expanded-text', $btn.text());
+				}
+				$children.toggle();
+				$btn.text($children.is(':visible') ? $btn.data('expanded-text') : $btn.data('collapsed-text'));
+			});
+
+			// soft delete a comment after confirm
+			$(document).on('click', '[rel=comment-delete]', function(){
+				var $form = $(this).parents('form:first');
+				if(confirm($form.data('confirm'))){
+					$form.submit();
+				}
+			});
+
 			var $comments = $('.post-comments');
 
 			$(window).on('hashchange', function(){
@@ -21,7 +21,6 @@ import (
 
 	"github.com/lunny/tango"
 	"github.com/tango-contrib/debug"
-	"github.com/tango-contrib/events"
 	"github.com/tango-contrib/flash"
 	"github.com/tango-contrib/session"
 	"github.com/tango-contrib/xsrf"
@@ -29,6 +28,11 @@ import (
 	"github.com/go-tango/social-auth"
 	"github.com/go-tango/wego/middlewares"
 	"github.com/go-tango/wego/models"
+	modauth "github.com/go-tango/wego/modules/auth"
+	"github.com/go-tango/wego/modules/export"
+	"github.com/go-tango/wego/modules/mailer"
+	"github.com/go-tango/wego/modules/notice"
+	"github.com/go-tango/wego/modules/search"
 	"github.com/go-tango/wego/routers"
 	"github.com/go-tango/wego/routers/auth"
 	"github.com/go-tango/wego/setting"
@@ -74,7 +78,7 @@ func initTango(isprod bool) *tango.Tango {
 	if setting.EnableXSRF {
 		tg.Use(xsrf.New(time.Duration(setting.SessionCookieLifeTime)))
 	}
-	tg.Use(flash.Flashes(sess), events.Events())
+	tg.Use(flash.Flashes(sess), middlewares.RateLimit(sess), middlewares.Events())
 	return tg
 }
 
@@ -85,6 +89,21 @@ func main() {
 	// init models
 	models.Init(setting.IsProMode)
 
+	// init ldap and other login sources
+	modauth.InitSources()
+
+	// init search index
+	search.Init()
+
+	// start sending queued mails
+	mailer.StartQueue()
+
+	// clean up the data exports
+	export.Init()
+
+	// clean up the expired logins
+	modauth.StartSessionCleanup()
+
 	// init social
 	social.SetORM(models.ORM())
 	setting.SocialAuth = social.NewSocial("/login/", auth.SocialAuther)
@@ -96,6 +115,9 @@ func main() {
 	// init tango
 	t := initTango(setting.IsProMode)
 
+	// init notification push and mails
+	notice.Init(middlewares.Renders)
+
 	// init routers
 	routers.Init(t)
 